@@ -0,0 +1,257 @@
+package diskplayer
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"github.com/spf13/viper"
+	"golang.org/x/crypto/bcrypt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	sessionCookie     = "diskplayer_session"
+	defaultSessionTTL = 12 * time.Hour
+)
+
+// ErrInvalidPassword is returned when logging in to the recorder with a password which does not match the configured
+// password hash.
+var ErrInvalidPassword = errors.New("invalid password")
+
+// Session is a logged in session of the recorder web UI, identified by a cookie. Requests which change anything must
+// include the CSRF token of the session, so that other sites cannot make them on behalf of a logged in user.
+type Session struct {
+	ID        string
+	CSRFToken string
+	Expires   time.Time
+}
+
+// AccessControl restricts access to the recorder to users who have logged in with the password matching the bcrypt
+// PasswordHash, or clients presenting one of the API Tokens as a bearer token. If neither a password hash nor any
+// tokens are given, access is not restricted.
+type AccessControl struct {
+	PasswordHash string
+	Tokens       []string
+	TTL          time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]*Session
+}
+
+// sessionKey is the request context key of the session making the request.
+type sessionKey struct{}
+
+// NewAccessControl returns the access control defined in the diskplayer.yaml configuration file under the
+// recorder.auth fields.
+func NewAccessControl() *AccessControl {
+	ttl := defaultSessionTTL
+	if v := viper.GetString(RECORD_AUTH_SESSION_TTL); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			log.Printf("Invalid session TTL \"%s\", using %s", v, ttl)
+		} else {
+			ttl = d
+		}
+	}
+
+	return &AccessControl{
+		PasswordHash: viper.GetString(RECORD_AUTH_PASSWORD_HASH),
+		Tokens:       ConfigValues(RECORD_AUTH_TOKENS),
+		TTL:          ttl,
+	}
+}
+
+// HashPassword returns the bcrypt hash of the password, for use as the recorder.auth.password_hash configuration
+// value.
+// An error is returned if one is encountered.
+func HashPassword(password string) (string, error) {
+	h, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	return string(h), err
+}
+
+// Enabled returns true if access to the recorder is restricted.
+func (a *AccessControl) Enabled() bool {
+	return a.PasswordHash != "" || len(a.Tokens) > 0
+}
+
+// Login checks the password against the password hash and returns a new session if it matches.
+// ErrInvalidPassword is returned if the password does not match, or any other error encountered.
+func (a *AccessControl) Login(password string) (*Session, error) {
+	if a.PasswordHash == "" {
+		return nil, errors.New("logging in with a password is not configured")
+	}
+	err := bcrypt.CompareHashAndPassword([]byte(a.PasswordHash), []byte(password))
+	if err == bcrypt.ErrMismatchedHashAndPassword {
+		return nil, ErrInvalidPassword
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	t, err := randomToken()
+	if err != nil {
+		return nil, err
+	}
+	s := &Session{ID: id, CSRFToken: t, Expires: time.Now().Add(a.TTL)}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.sessions == nil {
+		a.sessions = map[string]*Session{}
+	}
+	a.sessions[id] = s
+	return s, nil
+}
+
+// Logout ends the session identified by the ID.
+func (a *AccessControl) Logout(id string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.sessions, id)
+}
+
+// Session returns the unexpired session identified by the cookie of the request, or nil if there is none.
+func (a *AccessControl) Session(r *http.Request) *Session {
+	c, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return nil
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	s, ok := a.sessions[c.Value]
+	if !ok {
+		return nil
+	}
+	if time.Now().After(s.Expires) {
+		delete(a.sessions, c.Value)
+		return nil
+	}
+	return s
+}
+
+// Protect returns a handler which only passes requests on to the handler if access to the recorder is not restricted,
+// or the request is made by a logged in session or with a valid API token. Requests from a session which change
+// anything, i.e. any request other than GET or HEAD, must include the CSRF token of the session either in the
+// csrf_token form value or the X-CSRF-Token header.
+// The login page and static files are always passed on. Other unauthenticated page requests are redirected to the
+// login page, while API requests are refused with a 401 error.
+func (a *AccessControl) Protect(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !a.Enabled() || r.URL.Path == "/login" || strings.HasPrefix(r.URL.Path, "/static/") {
+			h.ServeHTTP(w, r)
+			return
+		}
+
+		api := strings.HasPrefix(r.URL.Path, "/api/")
+		if t := bearerToken(r); t != "" {
+			if a.validToken(t) {
+				h.ServeHTTP(w, r)
+				return
+			}
+			auditLog("rejected API token for %s from %s", r.URL.Path, r.RemoteAddr)
+			accessError(w, api, http.StatusUnauthorized, errors.New("invalid API token"))
+			return
+		}
+
+		s := a.Session(r)
+		if s == nil {
+			if !api && (r.Method == http.MethodGet || r.Method == http.MethodHead) {
+				http.Redirect(w, r, "/login?next="+url.QueryEscape(r.URL.RequestURI()), http.StatusFound)
+				return
+			}
+			accessError(w, api, http.StatusUnauthorized, errors.New("authentication required"))
+			return
+		}
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			t := r.Header.Get("X-CSRF-Token")
+			if t == "" {
+				t = r.PostFormValue("csrf_token")
+			}
+			if subtle.ConstantTimeCompare([]byte(t), []byte(s.CSRFToken)) != 1 {
+				auditLog("rejected %s %s from %s: invalid CSRF token", r.Method, r.URL.Path, r.RemoteAddr)
+				accessError(w, api, http.StatusForbidden, errors.New("invalid or missing CSRF token"))
+				return
+			}
+		}
+
+		h.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), sessionKey{}, s)))
+	})
+}
+
+// validToken returns true if the token is one of the API tokens.
+func (a *AccessControl) validToken(t string) bool {
+	ok := false
+	for _, v := range a.Tokens {
+		if subtle.ConstantTimeCompare([]byte(t), []byte(v)) == 1 {
+			ok = true
+		}
+	}
+	return ok
+}
+
+// requestSession returns the session making the request, or nil if access is not restricted or the request was made
+// with an API token.
+func requestSession(r *http.Request) *Session {
+	s, _ := r.Context().Value(sessionKey{}).(*Session)
+	return s
+}
+
+// csrfToken returns the CSRF token to be included in forms on pages returned for the request, or an empty string if
+// the request was not made by a logged in session.
+func csrfToken(r *http.Request) string {
+	if s := requestSession(r); s != nil {
+		return s.CSRFToken
+	}
+	return ""
+}
+
+// bearerToken returns the bearer token given in the Authorization header of the request, if any.
+func bearerToken(r *http.Request) string {
+	h := r.Header.Get("Authorization")
+	if !strings.HasPrefix(h, "Bearer ") {
+		return ""
+	}
+	return strings.TrimSpace(strings.TrimPrefix(h, "Bearer "))
+}
+
+// accessError returns an API error or an error page with the status code, depending on the request.
+func accessError(w http.ResponseWriter, api bool, status int, err error) {
+	if api {
+		apiError(w, status, err.Error())
+		return
+	}
+	w.WriteHeader(status)
+	errorPage(w, err)
+}
+
+// randomToken returns a random token suitable for identifying a session.
+// An error is returned if one is encountered.
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// safeRedirect returns the path to redirect to after logging in, which must be a path on the recorder itself.
+func safeRedirect(next string) string {
+	if !strings.HasPrefix(next, "/") || strings.HasPrefix(next, "//") || strings.HasPrefix(next, "/\\") {
+		return "/"
+	}
+	return next
+}
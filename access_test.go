@@ -0,0 +1,187 @@
+package diskplayer
+
+import (
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testAccessControl returns an access control with a password of "hunter2" and an API token of "t0k3n".
+func testAccessControl(t *testing.T) *AccessControl {
+	h, err := bcrypt.GenerateFromPassword([]byte("hunter2"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &AccessControl{PasswordHash: string(h), Tokens: []string{"t0k3n"}, TTL: time.Hour}
+}
+
+// protected returns a handler protected by the access control which records the CSRF token of the request.
+func protected(a *AccessControl, token *string) http.Handler {
+	return a.Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*token = csrfToken(r)
+		w.WriteHeader(http.StatusTeapot)
+	}))
+}
+
+func TestNewAccessControl(t *testing.T) {
+	viper.Set("recorder.auth.password_hash", "$2a$10$hash")
+	viper.Set("recorder.auth.api_tokens", []string{"a", "b"})
+	viper.Set("recorder.auth.session_ttl", "30m")
+	defer func() {
+		viper.Set("recorder.auth.password_hash", "")
+		viper.Set("recorder.auth.api_tokens", []string{})
+		viper.Set("recorder.auth.session_ttl", "")
+	}()
+
+	a := NewAccessControl()
+	assert.Equal(t, "$2a$10$hash", a.PasswordHash)
+	assert.Equal(t, []string{"a", "b"}, a.Tokens)
+	assert.Equal(t, 30*time.Minute, a.TTL)
+	assert.True(t, a.Enabled())
+
+	viper.Set("recorder.auth.password_hash", "")
+	viper.Set("recorder.auth.api_tokens", []string{})
+	viper.Set("recorder.auth.session_ttl", "florble")
+	a = NewAccessControl()
+	assert.Equal(t, defaultSessionTTL, a.TTL)
+	assert.False(t, a.Enabled())
+}
+
+func TestHashPassword(t *testing.T) {
+	h, err := HashPassword("hunter2")
+	assert.NoError(t, err)
+	_, err = (&AccessControl{PasswordHash: h}).Login("hunter2")
+	assert.NoError(t, err)
+}
+
+func TestAccessControlLogin(t *testing.T) {
+	a := testAccessControl(t)
+
+	_, err := a.Login("hunter3")
+	assert.Equal(t, ErrInvalidPassword, err)
+
+	s, err := a.Login("hunter2")
+	assert.NoError(t, err)
+	assert.NotEmpty(t, s.ID)
+	assert.NotEmpty(t, s.CSRFToken)
+	assert.NotEqual(t, s.ID, s.CSRFToken)
+
+	r := httptest.NewRequest("GET", "/", nil)
+	assert.Nil(t, a.Session(r))
+	r.AddCookie(&http.Cookie{Name: sessionCookie, Value: s.ID})
+	assert.Equal(t, s, a.Session(r))
+
+	s.Expires = time.Now().Add(-time.Second)
+	assert.Nil(t, a.Session(r))
+
+	s, err = a.Login("hunter2")
+	assert.NoError(t, err)
+	r = httptest.NewRequest("GET", "/", nil)
+	r.AddCookie(&http.Cookie{Name: sessionCookie, Value: s.ID})
+	a.Logout(s.ID)
+	assert.Nil(t, a.Session(r))
+
+	_, err = (&AccessControl{Tokens: []string{"t0k3n"}}).Login("")
+	assert.EqualError(t, err, "logging in with a password is not configured")
+}
+
+func TestAccessControlProtectDisabled(t *testing.T) {
+	var token string
+	h := protected(&AccessControl{}, &token)
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("POST", "/record", nil))
+	assert.Equal(t, http.StatusTeapot, rr.Code)
+	assert.Empty(t, token)
+}
+
+func TestAccessControlProtect(t *testing.T) {
+	a := testAccessControl(t)
+	var token string
+	h := protected(a, &token)
+	s, err := a.Login("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tests = []struct {
+		name    string
+		method  string
+		target  string
+		cookie  string
+		bearer  string
+		csrf    string
+		header  string
+		status  int
+		body    string
+		session bool
+	}{
+		{"login", "POST", "/login", "", "", "", "", http.StatusTeapot, "", false},
+		{"static", "GET", "/static/devices.js", "", "", "", "", http.StatusTeapot, "", false},
+		{"page", "GET", "/catalogue?q=a", "", "", "", "", http.StatusFound, "", false},
+		{"post", "POST", "/record", "", "", "", "", http.StatusUnauthorized, "authentication required", false},
+		{"api", "GET", "/api/v1/devices", "", "", "", "", http.StatusUnauthorized,
+			`{"error":{"status":401,"message":"authentication required"}}`, false},
+		{"token", "POST", "/api/v1/record", "", "t0k3n", "", "", http.StatusTeapot, "", false},
+		{"bad token", "GET", "/api/v1/devices", s.ID, "florble", "", "", http.StatusUnauthorized,
+			`{"error":{"status":401,"message":"invalid API token"}}`, false},
+		{"session", "GET", "/", s.ID, "", "", "", http.StatusTeapot, "", true},
+		{"bad session", "GET", "/api/v1/devices", "florble", "", "", "", http.StatusUnauthorized, "", false},
+		{"no csrf", "POST", "/record", s.ID, "", "", "", http.StatusForbidden, "invalid or missing CSRF token", false},
+		{"bad csrf", "POST", "/api/v1/undo", s.ID, "", "", "florble", http.StatusForbidden,
+			`{"error":{"status":403,"message":"invalid or missing CSRF token"}}`, false},
+		{"csrf form", "POST", "/record", s.ID, "", s.CSRFToken, "", http.StatusTeapot, "", true},
+		{"csrf header", "POST", "/api/v1/undo", s.ID, "", "", s.CSRFToken, http.StatusTeapot, "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token = ""
+			v := url.Values{}
+			if tt.csrf != "" {
+				v.Set("csrf_token", tt.csrf)
+			}
+			r := httptest.NewRequest(tt.method, tt.target, strings.NewReader(v.Encode()))
+			r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			if tt.cookie != "" {
+				r.AddCookie(&http.Cookie{Name: sessionCookie, Value: tt.cookie})
+			}
+			if tt.bearer != "" {
+				r.Header.Set("Authorization", "Bearer "+tt.bearer)
+			}
+			if tt.header != "" {
+				r.Header.Set("X-CSRF-Token", tt.header)
+			}
+
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, r)
+			assert.Equal(t, tt.status, rr.Code)
+			if strings.HasPrefix(tt.body, "{") {
+				assert.JSONEq(t, tt.body, rr.Body.String())
+			} else {
+				assert.Contains(t, rr.Body.String(), tt.body)
+			}
+			if tt.session {
+				assert.Equal(t, s.CSRFToken, token)
+			} else {
+				assert.Empty(t, token)
+			}
+			if tt.status == http.StatusFound {
+				assert.Equal(t, "/login?next="+url.QueryEscape(tt.target), rr.Header().Get("Location"))
+			}
+		})
+	}
+}
+
+func TestSafeRedirect(t *testing.T) {
+	assert.Equal(t, "/catalogue?q=a", safeRedirect("/catalogue?q=a"))
+	assert.Equal(t, "/", safeRedirect(""))
+	assert.Equal(t, "/", safeRedirect("https://example.com/"))
+	assert.Equal(t, "/", safeRedirect("//example.com/"))
+	assert.Equal(t, "/", safeRedirect("/\\example.com/"))
+}
@@ -0,0 +1,271 @@
+package diskplayer
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ApiError is the error object returned by the recorder API, with the HTTP status code of the response.
+// If a recording was refused because the disk already holds a different album or playlist, its contents are included.
+type ApiError struct {
+	Status   int           `json:"status"`
+	Message  string        `json:"message"`
+	Existing *DiskContents `json:"existing,omitempty"`
+}
+
+// apiDevicesHandler handles API requests for the attached removable devices which the recorder is permitted to use,
+// along with the operation using each of them, if any.
+// This is polled by the recorder page so that the device picker is refreshed when a drive is plugged in, and shows
+// which devices are busy.
+func (s *RealDiskplayerServer) apiDevicesHandler(w http.ResponseWriter, r *http.Request) {
+	if !apiMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	ds, err := s.listDevices()
+	if err != nil {
+		s.apiFailure(w, err)
+		return
+	}
+	apiRespond(w, http.StatusOK, map[string]interface{}{"devices": ds})
+}
+
+// apiDiskHandler handles API requests for the contents of the disk in the device identified by the device_path
+// query value.
+func (s *RealDiskplayerServer) apiDiskHandler(w http.ResponseWriter, r *http.Request) {
+	if !apiMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	c, err := s.disk(r.FormValue("device_path"))
+	if err != nil {
+		s.apiFailure(w, err)
+		return
+	}
+	apiRespond(w, http.StatusOK, c)
+}
+
+// apiRecordHandler handles API requests to record an album or playlist to a disk. The request body is a JSON encoded
+// RecordRequest, and the contents of the disk are returned once recorded, including the album or playlist details if
+// a Spotify client is available.
+func (s *RealDiskplayerServer) apiRecordHandler(w http.ResponseWriter, r *http.Request) {
+	if !apiMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	req := &RecordRequest{}
+	if !apiDecode(w, r, req) {
+		return
+	}
+
+	c, err := s.record(req, r.RemoteAddr)
+	if err != nil {
+		s.apiFailure(w, err)
+		return
+	}
+	d := s.diskContents(c.DevicePath, c.URI)
+	d.Kind = c.Kind
+	d.DiskID = c.DiskID
+	apiRespond(w, http.StatusOK, d)
+}
+
+// apiUndoHandler handles API requests to undo the last recording made to a disk. The request body is a JSON object
+// with a device_path field, and the restored contents of the disk are returned in the same way as for recording.
+func (s *RealDiskplayerServer) apiUndoHandler(w http.ResponseWriter, r *http.Request) {
+	if !apiMethod(w, r, http.MethodPost) {
+		return
+	}
+
+	var req struct {
+		DevicePath string `json:"device_path"`
+	}
+	if !apiDecode(w, r, &req) {
+		return
+	}
+
+	c, err := s.undo(req.DevicePath, r.RemoteAddr)
+	if err != nil {
+		s.apiFailure(w, err)
+		return
+	}
+	d := s.diskContents(c.DevicePath, c.URI)
+	d.Kind = c.Kind
+	d.DiskID = c.DiskID
+	apiRespond(w, http.StatusOK, d)
+}
+
+// apiSearchHandler handles API requests to search Spotify for albums, playlists, artists and shows matching the q
+// query value, returning up to limit items of each kind with their web URLs so that any of them can be recorded.
+func (s *RealDiskplayerServer) apiSearchHandler(w http.ResponseWriter, r *http.Request) {
+	if !apiMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	is, err := s.search(r)
+	if err != nil {
+		s.apiFailure(w, err)
+		return
+	}
+	if is == nil {
+		is = []SearchItem{}
+	}
+	apiRespond(w, http.StatusOK, map[string]interface{}{"results": is})
+}
+
+// apiLibraryHandler handles API requests for the albums, playlists or shows in the library of the authenticated
+// Spotify user. The kind query value is one of "album" (the default), "playlist" or "show", the q value filters the
+// items by title or artist, and the offset and limit values select a page of the items.
+func (s *RealDiskplayerServer) apiLibraryHandler(w http.ResponseWriter, r *http.Request) {
+	if !apiMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	lr, err := s.library(r)
+	if err != nil {
+		s.apiFailure(w, err)
+		return
+	}
+	if lr.Items == nil {
+		lr.Items = []SearchItem{}
+	}
+	apiRespond(w, http.StatusOK, lr)
+}
+
+// apiHistoryHandler handles API requests for the catalogue of recordings, most recent first. The q query value
+// searches the title, artist, URI and disk identity of each entry, the kind value restricts it to albums, playlists,
+// artists, shows or tracks, the device_path value to a single device, the disk value to the history of a single disk
+// identity and the limit value to a number of entries.
+func (s *RealDiskplayerServer) apiHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	if !apiMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	f, err := catalogueFilter(r)
+	if err != nil {
+		apiError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	apiRespond(w, http.StatusOK, map[string]interface{}{"history": s.catalogue.Search(f)})
+}
+
+// apiExportHandler handles API requests to download the catalogue of recordings as a file, in the format given by the
+// format query value, either "csv" or "json". The entries are filtered as for apiHistoryHandler.
+func (s *RealDiskplayerServer) apiExportHandler(w http.ResponseWriter, r *http.Request) {
+	if !apiMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	f, err := catalogueFilter(r)
+	if err != nil {
+		apiError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	es := s.catalogue.Search(f)
+
+	switch t := r.FormValue("format"); t {
+	case "", "json":
+		w.Header().Set("Content-Disposition", "attachment; filename=\"diskplayer-catalogue.json\"")
+		apiRespond(w, http.StatusOK, es)
+	case "csv":
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", "attachment; filename=\"diskplayer-catalogue.csv\"")
+		err := WriteCatalogueCSV(w, es)
+		if err != nil {
+			log.Println(err)
+		}
+	default:
+		apiError(w, http.StatusBadRequest, fmt.Sprintf("invalid format: %s", t))
+	}
+}
+
+// apiDuplicatesHandler handles API requests for the albums and playlists currently held by more than one disk, along
+// with the most recent catalogue entry for each of those disks.
+func (s *RealDiskplayerServer) apiDuplicatesHandler(w http.ResponseWriter, r *http.Request) {
+	if !apiMethod(w, r, http.MethodGet) {
+		return
+	}
+
+	apiRespond(w, http.StatusOK, map[string]interface{}{"duplicates": s.catalogue.Duplicates()})
+}
+
+// catalogueFilter returns the catalogue filter given by the q, kind, device_path, disk and limit values of the request.
+// An error is returned if the kind or limit is invalid.
+func catalogueFilter(r *http.Request) (CatalogueFilter, error) {
+	f := CatalogueFilter{
+		Query:      strings.TrimSpace(r.FormValue("q")),
+		Kind:       r.FormValue("kind"),
+		DevicePath: r.FormValue("device_path"),
+		DiskID:     r.FormValue("disk"),
+	}
+	switch f.Kind {
+	case "", "album", "playlist", "artist", "show", "track":
+	default:
+		return f, fmt.Errorf("invalid kind: %s", f.Kind)
+	}
+	if l := r.FormValue("limit"); l != "" {
+		var err error
+		f.Limit, err = strconv.Atoi(l)
+		if err != nil || f.Limit < 0 {
+			return f, fmt.Errorf("invalid limit: %s", l)
+		}
+	}
+	return f, nil
+}
+
+// apiNotFoundHandler handles API requests for unknown endpoints.
+func apiNotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	apiError(w, http.StatusNotFound, fmt.Sprintf("unknown endpoint: %s", r.URL.Path))
+}
+
+// apiMethod returns true if the request uses the HTTP method, otherwise a 405 error is returned and false.
+func apiMethod(w http.ResponseWriter, r *http.Request, method string) bool {
+	if r.Method == method {
+		return true
+	}
+	w.Header().Set("Allow", method)
+	apiError(w, http.StatusMethodNotAllowed, fmt.Sprintf("%s requires a %s request", r.URL.Path, method))
+	return false
+}
+
+// apiDecode decodes the JSON request body into v, returning true if successful, otherwise a 400 error is returned
+// and false.
+func apiDecode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	d := json.NewDecoder(r.Body)
+	d.DisallowUnknownFields()
+	err := d.Decode(v)
+	if err != nil {
+		apiError(w, http.StatusBadRequest, fmt.Sprintf("invalid request body: %s", err))
+		return false
+	}
+	return true
+}
+
+// apiFailure returns the error object for an error encountered by the recorder, with the status code identifying
+// the step which failed.
+func (s *RealDiskplayerServer) apiFailure(w http.ResponseWriter, err error) {
+	e := &ApiError{Status: recordStatus(err), Message: err.Error()}
+	if oe, ok := err.(*overwriteError); ok {
+		e.Existing = s.diskContents(oe.devPath, oe.existing)
+	}
+	apiRespond(w, e.Status, map[string]interface{}{"error": e})
+}
+
+// apiError returns an error object with the status code and message.
+func apiError(w http.ResponseWriter, status int, message string) {
+	apiRespond(w, status, map[string]interface{}{"error": &ApiError{Status: status, Message: message}})
+}
+
+// apiRespond writes the value as the JSON response body with the status code.
+func apiRespond(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		log.Println(err)
+	}
+}
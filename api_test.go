@@ -0,0 +1,288 @@
+package diskplayer
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/dinofizz/diskplayer/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/zmb3/spotify"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func apiGet(h http.HandlerFunc, target string) *httptest.ResponseRecorder {
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", target, nil))
+	return rr
+}
+
+func apiPost(h http.HandlerFunc, target, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestApiDevicesHandler(t *testing.T) {
+	s, _, img, cleanup := recordServer(t)
+	defer cleanup()
+
+	rr := apiGet(s.apiDevicesHandler, "/api/v1/devices")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+
+	var o struct {
+		Devices []DeviceStatus `json:"devices"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &o))
+	assert.Len(t, o.Devices, 1)
+	assert.Equal(t, img, o.Devices[0].Path)
+	assert.Equal(t, img+" (64.0 KiB, no filesystem)", o.Devices[0].Description)
+	assert.Nil(t, o.Devices[0].Busy)
+}
+
+func TestApiDiskHandler(t *testing.T) {
+	s, m, img, cleanup := recordServer(t)
+	defer cleanup()
+
+	rr := apiGet(s.apiDiskHandler, "/api/v1/disk?device_path="+url.QueryEscape(img))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"device_path":"`+img+`","uri":""}`, rr.Body.String())
+
+	assert.Equal(t, http.StatusFound, postRecord(s, recordTests[1].in, img, false).Code)
+	p := &spotify.FullPlaylist{}
+	p.Name = "Test Playlist"
+	c := new(mocks.Client)
+	c.On("GetPlaylist", spotify.ID("5XsXwH5uWdhpAWsigjWMTA")).Return(p, nil)
+	s.client = c
+
+	id, err := ReadDiskId(m.Folder(img))
+	assert.NoError(t, err)
+	rr = apiGet(s.apiDiskHandler, "/api/v1/disk?device_path="+url.QueryEscape(img))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"device_path":"`+img+`","uri":"`+recordTests[1].out+`","disk_id":"`+id+`","metadata":{"uri":"`+
+		recordTests[1].out+`","web_url":"`+recordTests[1].in+`","title":"Test Playlist","artist":"","image_url":""}}`,
+		rr.Body.String())
+	assert.False(t, m.Mounted(img))
+}
+
+func TestApiDiskHandlerErrors(t *testing.T) {
+	s, m, img, cleanup := recordServer(t)
+	defer cleanup()
+
+	rr := apiGet(s.apiDiskHandler, "/api/v1/disk?device_path=/dev/not_a_real_device")
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.JSONEq(t, `{"error":{"status":403,"message":"device /dev/not_a_real_device is not an attached removable device"}}`,
+		rr.Body.String())
+
+	m.MountErr = errors.New("device busy")
+	rr = apiGet(s.apiDiskHandler, "/api/v1/disk?device_path="+url.QueryEscape(img))
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.JSONEq(t, `{"error":{"status":503,"message":"unable to mount `+img+`: device busy"}}`, rr.Body.String())
+
+	rr = apiPost(s.apiDiskHandler, "/api/v1/disk", "")
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+	assert.Equal(t, "GET", rr.Header().Get("Allow"))
+}
+
+func TestApiRecordHandler(t *testing.T) {
+	s, m, img, cleanup := recordServer(t)
+	defer cleanup()
+
+	rr := apiPost(s.apiRecordHandler, "/api/v1/record", `{"device_path":"`+img+`","uri":"`+recordTests[0].out+`"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	id, err := ReadDiskId(m.Folder(img))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, id)
+	assert.JSONEq(t, `{"device_path":"`+img+`","uri":"`+recordTests[0].out+`","disk_id":"`+id+`"}`, rr.Body.String())
+	assert.False(t, m.Mounted(img))
+
+	rr = apiPost(s.apiRecordHandler, "/api/v1/record", `{"device_path":"`+img+`","web_url":"`+recordTests[1].in+`"}`)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.JSONEq(t, `{"error":{"status":409,"message":"the disk in `+img+` already holds `+recordTests[0].out+
+		`","existing":{"device_path":"`+img+`","uri":"`+recordTests[0].out+`"}}}`, rr.Body.String())
+
+	rr = apiPost(s.apiRecordHandler, "/api/v1/record", `{"device_path":"`+img+`","web_url":"`+recordTests[1].in+
+		`","confirm_overwrite":true}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"device_path":"`+img+`","uri":"`+recordTests[1].out+`","disk_id":"`+id+`"}`, rr.Body.String())
+}
+
+func TestApiRecordHandlerErrors(t *testing.T) {
+	s, _, img, cleanup := recordServer(t)
+	defer cleanup()
+
+	rr := apiPost(s.apiRecordHandler, "/api/v1/record", `{"device_path":"`+img+`","uri":"spotify:episode:abc"}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.JSONEq(t, `{"error":{"status":400,"message":"URL represents neither album, playlist, artist, show nor track: `+
+		`https://open.spotify.com/episode/abc"}}`, rr.Body.String())
+
+	rr = apiPost(s.apiRecordHandler, "/api/v1/record", `{"device":"`+img+`"}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), `invalid request body: json: unknown field \"device\"`)
+
+	rr = apiGet(s.apiRecordHandler, "/api/v1/record")
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+	assert.JSONEq(t, `{"error":{"status":405,"message":"/api/v1/record requires a POST request"}}`, rr.Body.String())
+}
+
+func TestApiUndoAndHistoryHandlers(t *testing.T) {
+	s, m, img, cleanup := recordServer(t)
+	defer cleanup()
+
+	rr := apiPost(s.apiUndoHandler, "/api/v1/undo", `{"device_path":"`+img+`"}`)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.JSONEq(t, `{"error":{"status":404,"message":"no previous recording found on `+img+`"}}`, rr.Body.String())
+
+	assert.Equal(t, http.StatusFound, postRecord(s, recordTests[0].in, img, false).Code)
+	assert.Equal(t, http.StatusFound, postRecord(s, recordTests[1].in, img, true).Code)
+
+	rr = apiPost(s.apiUndoHandler, "/api/v1/undo", `{"device_path":"`+img+`"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	id, err := ReadDiskId(m.Folder(img))
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"device_path":"`+img+`","uri":"`+recordTests[0].out+`","disk_id":"`+id+`"}`, rr.Body.String())
+
+	rr = apiGet(s.apiHistoryHandler, "/api/v1/history?limit=2&device_path="+url.QueryEscape(img))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	var o struct {
+		History []CatalogueEntry `json:"history"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &o))
+	assert.Len(t, o.History, 2)
+	assert.Equal(t, "undo", o.History[0].Action)
+	assert.Equal(t, recordTests[0].out, o.History[0].URI)
+	assert.Equal(t, "record", o.History[1].Action)
+	assert.Equal(t, recordTests[1].out, o.History[1].URI)
+	assert.Equal(t, img, o.History[1].DevicePath)
+	assert.Equal(t, id, o.History[0].DiskID)
+	assert.Equal(t, id, o.History[1].DiskID)
+	assert.Equal(t, recordTests[1].in, o.History[1].WebURL)
+	assert.Equal(t, "DISKPLAYER", o.History[1].DiskLabel)
+	assert.NotEmpty(t, o.History[1].DiskUUID)
+
+	rr = apiGet(s.apiHistoryHandler, "/api/v1/history?kind=album&q="+url.QueryEscape("1S7mumn7"))
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &o))
+	assert.Len(t, o.History, 2)
+	assert.Equal(t, recordTests[0].out, o.History[0].URI)
+	assert.Equal(t, recordTests[0].out, o.History[1].URI)
+
+	rr = apiGet(s.apiHistoryHandler, "/api/v1/history?limit=lots")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.JSONEq(t, `{"error":{"status":400,"message":"invalid limit: lots"}}`, rr.Body.String())
+
+	rr = apiGet(s.apiHistoryHandler, "/api/v1/history?kind=florble")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.JSONEq(t, `{"error":{"status":400,"message":"invalid kind: florble"}}`, rr.Body.String())
+}
+
+func TestApiExportHandler(t *testing.T) {
+	s, _, img, cleanup := recordServer(t)
+	defer cleanup()
+
+	assert.Equal(t, http.StatusFound, postRecord(s, recordTests[0].in, img, false).Code)
+
+	rr := apiGet(s.apiExportHandler, "/api/v1/history/export?format=csv")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/csv", rr.Header().Get("Content-Type"))
+	assert.Contains(t, rr.Header().Get("Content-Disposition"), "diskplayer-catalogue.csv")
+	ls := strings.Split(strings.TrimSpace(rr.Body.String()), "\n")
+	assert.Len(t, ls, 2)
+	assert.Equal(t, "time,action,uri,web_url,title,artist,device_path,model,disk_id,disk_uuid,disk_label,remote_addr",
+		ls[0])
+	assert.Contains(t, ls[1], ",record,"+recordTests[0].out+","+recordTests[0].in+",,,"+img+",")
+
+	rr = apiGet(s.apiExportHandler, "/api/v1/history/export")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Header().Get("Content-Disposition"), "diskplayer-catalogue.json")
+	var es []CatalogueEntry
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &es))
+	assert.Len(t, es, 1)
+
+	rr = apiGet(s.apiExportHandler, "/api/v1/history/export?format=xml")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.JSONEq(t, `{"error":{"status":400,"message":"invalid format: xml"}}`, rr.Body.String())
+}
+
+func TestApiDuplicatesHandler(t *testing.T) {
+	s, _, _, cleanup := recordServer(t)
+	defer cleanup()
+
+	rr := apiGet(s.apiDuplicatesHandler, "/api/v1/history/duplicates")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"duplicates":{}}`, rr.Body.String())
+
+	assert.NoError(t, s.catalogue.Add(CatalogueEntry{URI: recordTests[0].out, DiskID: "a"}))
+	assert.NoError(t, s.catalogue.Add(CatalogueEntry{URI: recordTests[0].out, DiskID: "b"}))
+	rr = apiGet(s.apiDuplicatesHandler, "/api/v1/history/duplicates")
+	var o struct {
+		Duplicates map[string][]CatalogueEntry `json:"duplicates"`
+	}
+	assert.NoError(t, json.Unmarshal(rr.Body.Bytes(), &o))
+	assert.Len(t, o.Duplicates[recordTests[0].out], 2)
+	assert.Equal(t, "b", o.Duplicates[recordTests[0].out][0].DiskID)
+}
+
+func TestApiNotFoundHandler(t *testing.T) {
+	rr := apiGet(apiNotFoundHandler, "/api/v1/florble")
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.JSONEq(t, `{"error":{"status":404,"message":"unknown endpoint: /api/v1/florble"}}`, rr.Body.String())
+}
+
+func TestApiSearchHandler(t *testing.T) {
+	s := NewRecordServer(searchClient(), nil)
+
+	rr := apiGet(s.apiSearchHandler, "/api/v1/search?q=test")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `{"kind":"album","uri":"spotify:album:1S7mumn7D4riEX2gVWYgPO",`+
+		`"web_url":"https://open.spotify.com/album/1S7mumn7D4riEX2gVWYgPO","title":"Test Album",`+
+		`"artist":"Artist One","image_url":"https://i.scdn.co/image/album"}`)
+	assert.Contains(t, rr.Body.String(), `"kind":"show"`)
+
+	rr = apiGet(s.apiSearchHandler, "/api/v1/search?q=test&limit=51")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.JSONEq(t, `{"error":{"status":400,"message":"invalid limit: 51"}}`, rr.Body.String())
+
+	rr = apiGet(s.apiSearchHandler, "/api/v1/search")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.JSONEq(t, `{"error":{"status":400,"message":"a search query is required"}}`, rr.Body.String())
+
+	rr = apiGet(NewRecordServer(nil, nil).apiSearchHandler, "/api/v1/search?q=test")
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Contains(t, rr.Body.String(), "no Spotify client available")
+}
+
+func TestApiLibraryHandler(t *testing.T) {
+	s := NewRecordServer(libraryClient(45), nil)
+
+	rr := apiGet(s.apiLibraryHandler, "/api/v1/library?offset=40&limit=2")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"kind":"album","offset":40,"limit":2,"total":45,"items":[`+
+		`{"kind":"album","uri":"spotify:album:album40","web_url":"https://open.spotify.com/album/album40",`+
+		`"title":"Album 40","artist":"","image_url":""},`+
+		`{"kind":"album","uri":"spotify:album:album41","web_url":"https://open.spotify.com/album/album41",`+
+		`"title":"Album 41","artist":"","image_url":""}]}`, rr.Body.String())
+
+	rr = apiGet(s.apiLibraryHandler, "/api/v1/library?q=florble")
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"kind":"album","offset":0,"limit":20,"total":0,"items":[]}`, rr.Body.String())
+
+	rr = apiGet(s.apiLibraryHandler, "/api/v1/library?kind=artist")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.JSONEq(t, `{"error":{"status":400,"message":"invalid library kind: artist"}}`, rr.Body.String())
+
+	rr = apiGet(s.apiLibraryHandler, "/api/v1/library?offset=-1")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.JSONEq(t, `{"error":{"status":400,"message":"invalid offset: -1"}}`, rr.Body.String())
+
+	rr = apiGet(s.apiLibraryHandler, "/api/v1/library?limit=0")
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.JSONEq(t, `{"error":{"status":400,"message":"invalid limit: 0"}}`, rr.Body.String())
+
+	rr = apiGet(NewRecordServer(nil, nil).apiLibraryHandler, "/api/v1/library")
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+}
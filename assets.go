@@ -0,0 +1,107 @@
+package diskplayer
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"log"
+	"net/http"
+	"os"
+	"path"
+)
+
+// embedded holds the templates and static files of the recorder web pages, so that the recorder does not depend on
+// the directory from which it is run.
+//
+//go:embed templates static
+var embedded embed.FS
+
+// pages holds the parsed recorder web page templates. It is parsed from the embedded templates when the program
+// starts, and replaced by templates from the override directory, if any, when the recorder is started.
+var pages = mustLoadTemplates()
+
+// Templates holds the parsed templates of the recorder web pages, keyed by file name.
+type Templates struct {
+	ts map[string]*template.Template
+}
+
+// Assets returns the templates and static files of the recorder web pages. Files found beneath the override
+// directory, in the templates and static folders, take precedence over the embedded files. If no directory is given
+// only the embedded files are used.
+func Assets(dir string) fs.FS {
+	if dir == "" {
+		return embedded
+	}
+	return overlayFS{os.DirFS(dir), embedded}
+}
+
+// LoadTemplates parses each of the recorder web page templates, using any found in the templates folder of the
+// override directory in place of the embedded one.
+// An error is returned if a template could not be parsed.
+func LoadTemplates(dir string) (*Templates, error) {
+	es, err := fs.ReadDir(embedded, "templates")
+	if err != nil {
+		return nil, err
+	}
+
+	fsys := Assets(dir)
+	ts := &Templates{ts: map[string]*template.Template{}}
+	for _, e := range es {
+		t, err := template.ParseFS(fsys, path.Join("templates", e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		ts.ts[e.Name()] = t
+	}
+	return ts, nil
+}
+
+// mustLoadTemplates returns the embedded templates, panicking if they cannot be parsed.
+func mustLoadTemplates() *Templates {
+	ts, err := LoadTemplates("")
+	if err != nil {
+		panic(fmt.Sprintf("unable to parse embedded templates: %s", err))
+	}
+	return ts
+}
+
+// render executes the named template with the data and writes the result as the response. The template is executed
+// before anything is written, so that an error results in a 500 error rather than a blank or partial page.
+func (t *Templates) render(w http.ResponseWriter, name string, data interface{}) {
+	tt, ok := t.ts[name]
+	if !ok {
+		log.Printf("Unknown template %s", name)
+		http.Error(w, "unknown template "+name, http.StatusInternalServerError)
+		return
+	}
+
+	var b bytes.Buffer
+	err := tt.Execute(&b, data)
+	if err != nil {
+		log.Printf("Unable to render template %s: %s", name, err)
+		http.Error(w, fmt.Sprintf("unable to render template %s: %s", name, err), http.StatusInternalServerError)
+		return
+	}
+	_, err = b.WriteTo(w)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// overlayFS is a filesystem which opens each file from the first of its filesystems holding it.
+type overlayFS []fs.FS
+
+// Open opens the named file from the first filesystem holding it, or returns the error from the last filesystem.
+func (o overlayFS) Open(name string) (fs.File, error) {
+	var err error
+	for _, f := range o {
+		var fl fs.File
+		fl, err = f.Open(name)
+		if err == nil {
+			return fl, nil
+		}
+	}
+	return nil, err
+}
@@ -0,0 +1,101 @@
+package diskplayer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/fs"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// assetsDir creates a temporary override directory holding the files, returning its path.
+func assetsDir(t *testing.T, files map[string]string) string {
+	d, err := ioutil.TempDir("", "diskplayer_assets")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for n, c := range files {
+		p := filepath.Join(d, n)
+		err := os.MkdirAll(filepath.Dir(p), 0755)
+		if err == nil {
+			err = ioutil.WriteFile(p, []byte(c), 0644)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	return d
+}
+
+func TestLoadTemplates(t *testing.T) {
+	ts, err := LoadTemplates("")
+	assert.NoError(t, err)
+	for _, n := range []string{"catalogue.html", "confirm.html", "error.html", "index.html", "labels.html",
+		"login.html", "success.html"} {
+		assert.Contains(t, ts.ts, n)
+	}
+}
+
+func TestLoadTemplatesOverride(t *testing.T) {
+	d := assetsDir(t, map[string]string{"templates/index.html": "<p>{{len .Devices}} devices</p>"})
+	defer os.RemoveAll(d)
+
+	ts, err := LoadTemplates(d)
+	assert.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	ts.render(rr, "index.html", &IndexPage{Devices: []DeviceStatus{{}, {}}})
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "<p>2 devices</p>", rr.Body.String())
+
+	rr = httptest.NewRecorder()
+	ts.render(rr, "error.html", &ErrorPage{Body: []byte("florble")})
+	assert.Contains(t, rr.Body.String(), "<h2>Recording Error!</h2>")
+}
+
+func TestLoadTemplatesOverrideError(t *testing.T) {
+	d := assetsDir(t, map[string]string{"templates/index.html": "{{range .Devices}}"})
+	defer os.RemoveAll(d)
+
+	_, err := LoadTemplates(d)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "index.html")
+}
+
+func TestTemplatesRenderError(t *testing.T) {
+	rr := httptest.NewRecorder()
+	pages.render(rr, "index.html", struct{}{})
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.Contains(t, rr.Body.String(), "unable to render template index.html")
+	assert.NotContains(t, rr.Body.String(), "<html")
+
+	rr = httptest.NewRecorder()
+	pages.render(rr, "florble.html", nil)
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.Contains(t, rr.Body.String(), "unknown template florble.html")
+}
+
+func TestAssets(t *testing.T) {
+	d := assetsDir(t, map[string]string{"static/devices.js": "// custom", "static/style.css": "body {}"})
+	defer os.RemoveAll(d)
+
+	b, err := fs.ReadFile(Assets(d), "static/devices.js")
+	assert.NoError(t, err)
+	assert.Equal(t, "// custom", string(b))
+	b, err = fs.ReadFile(Assets(d), "static/style.css")
+	assert.NoError(t, err)
+	assert.Equal(t, "body {}", string(b))
+	b, err = fs.ReadFile(Assets(d), "static/disk.js")
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), "/api/v1/disk")
+
+	_, err = fs.ReadFile(Assets(d), "static/florble.js")
+	assert.True(t, os.IsNotExist(err))
+
+	b, err = fs.ReadFile(Assets(""), "static/devices.js")
+	assert.NoError(t, err)
+	assert.Contains(t, string(b), "/api/v1/devices")
+}
@@ -0,0 +1,35 @@
+package diskplayer
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+)
+
+// auditLog records an entry describing a recorder operation to the file defined in the diskplayer.yaml configuration
+// file under the recorder.audit_log field. If no audit log file is configured, or it cannot be written to, the entry
+// is written to the standard logger instead.
+func auditLog(format string, v ...interface{}) {
+	e := "audit: " + fmt.Sprintf(format, v...)
+
+	p := ConfigPath(RECORD_AUDIT_LOG)
+	if p == "" {
+		log.Println(e)
+		return
+	}
+
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		log.Printf("Unable to open audit log %s: %s", p, err)
+		log.Println(e)
+		return
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "%s %s\n", time.Now().Format(time.RFC3339), e)
+	if err != nil {
+		log.Printf("Unable to write audit log %s: %s", p, err)
+		log.Println(e)
+	}
+}
@@ -0,0 +1,26 @@
+package diskplayer
+
+import (
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestAuditLog(t *testing.T) {
+	const p = "./test-fixtures/temp_audit.log"
+	viper.Set("recorder.audit_log", p)
+	defer func() {
+		viper.Set("recorder.audit_log", "")
+		err := os.Remove(p)
+		assert.NoErrorf(t, err, "Failed to remove temporary test file: %s", p)
+	}()
+
+	auditLog("denied %s", "/dev/sda")
+	auditLog("allowed %s", "/dev/sdb")
+
+	b, err := ioutil.ReadFile(p)
+	assert.NoError(t, err)
+	assert.Regexp(t, "^\\S+ audit: denied /dev/sda\n\\S+ audit: allowed /dev/sdb\n$", string(b))
+}
@@ -24,7 +24,8 @@ func NewAuthenticator() (*spotify.Authenticator, error) {
 	s := ConfigValue(SPOTIFY_CLIENT_SECRET)
 
 	auth := spotify.NewAuthenticator(u.String(), spotify.ScopeUserReadPrivate, spotify.ScopePlaylistReadPrivate,
-		spotify.ScopeUserModifyPlaybackState, spotify.ScopeUserReadPlaybackState)
+		spotify.ScopeUserModifyPlaybackState, spotify.ScopeUserReadPlaybackState, spotify.ScopeUserLibraryRead,
+		spotify.ScopePlaylistReadCollaborative, spotify.ScopeUserTopRead, spotify.ScopeUserReadRecentlyPlayed)
 
 	auth.SetAuthInfo(id, s)
 
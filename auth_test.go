@@ -18,8 +18,12 @@ func TestNewAuthenticator(t *testing.T) {
 	viper.Set("spotify.callback_url", "http://localhost:8732/callback")
 	viper.Set("spotify.client_id", "client_id")
 	viper.Set("spotify.client_secret", "client_secret")
-	_, err := NewAuthenticator()
+	a, err := NewAuthenticator()
 	assert.NoError(t, err)
+	assert.Contains(t, a.AuthURL("state"), "user-library-read")
+	assert.Contains(t, a.AuthURL("state"), "playlist-read-collaborative")
+	assert.Contains(t, a.AuthURL("state"), "user-top-read")
+	assert.Contains(t, a.AuthURL("state"), "user-read-recently-played")
 }
 
 func TestNewAuthenticatorParseURLError(t *testing.T) {
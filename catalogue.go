@@ -0,0 +1,231 @@
+package diskplayer
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CatalogueEntry describes a disk recorded by the recorder, or restored to its previous contents by an undo.
+// The disk is identified by the disk identity stamped on it, along with the UUID and label of its filesystem, which
+// stay the same for as long as it is not reformatted.
+// The contents recorded are kept unless they are a plain Spotify URI, so that the disk can be recorded again exactly as
+// it was, e.g. with the tracks a playlist held when it was frozen rather than those it holds now.
+type CatalogueEntry struct {
+	Time       time.Time `json:"time"`
+	Action     string    `json:"action"`
+	URI        string    `json:"uri"`
+	WebURL     string    `json:"web_url"`
+	Title      string    `json:"title,omitempty"`
+	Artist     string    `json:"artist,omitempty"`
+	ImageURL   string    `json:"image_url,omitempty"`
+	DevicePath string    `json:"device_path"`
+	Model      string    `json:"model,omitempty"`
+	DiskID     string    `json:"disk_id,omitempty"`
+	DiskUUID   string    `json:"disk_uuid,omitempty"`
+	DiskLabel  string    `json:"disk_label,omitempty"`
+	RemoteAddr string    `json:"remote_addr"`
+	Contents   *Contents `json:"contents,omitempty"`
+}
+
+// EncodedContents returns the contents recorded in the form in which they are written to a contents file, or an empty
+// string if they are a plain Spotify URI.
+func (e CatalogueEntry) EncodedContents() string {
+	if e.Contents == nil {
+		return ""
+	}
+	s, err := e.Contents.Encode()
+	if err != nil {
+		return ""
+	}
+	return s
+}
+
+// CatalogueFilter restricts the entries returned when searching the catalogue. Empty fields match every entry.
+type CatalogueFilter struct {
+	// Query is matched case insensitively against the title, artist, URI and disk identity, label and UUID of each
+	// entry.
+	Query string
+	// Kind is the kind of Spotify item recorded, i.e. "album" or "playlist".
+	Kind       string
+	DevicePath string
+	// DiskID restricts the entries to those of a single disk, giving its history.
+	DiskID string
+	// Limit is the maximum number of entries returned, if greater than zero.
+	Limit int
+}
+
+// Catalogue is a record of every disk recorded by the recorder. It is kept in a file with one JSON encoded entry per
+// line, so that entries can be appended without rewriting the file. If no file is given entries are only kept in
+// memory.
+type Catalogue struct {
+	path    string
+	mu      sync.Mutex
+	entries []CatalogueEntry
+	loaded  bool
+}
+
+// NewCatalogue returns a catalogue kept in the file at the path, which is created when the first entry is added.
+func NewCatalogue(path string) *Catalogue {
+	return &Catalogue{path: path}
+}
+
+// Add appends the entry to the catalogue.
+// An error is returned if the entry could not be written to the catalogue file, in which case it is still kept in
+// memory.
+func (c *Catalogue) Add(e CatalogueEntry) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.load()
+	c.entries = append(c.entries, e)
+	if c.path == "" {
+		return nil
+	}
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(c.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(append(b, '\n'))
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Search returns the entries in the catalogue matching the filter, most recent first.
+func (c *Catalogue) Search(f CatalogueFilter) []CatalogueEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.load()
+	q := strings.ToLower(f.Query)
+	es := []CatalogueEntry{}
+	for i := len(c.entries) - 1; i >= 0; i-- {
+		if f.Limit > 0 && len(es) == f.Limit {
+			break
+		}
+		e := c.entries[i]
+		if f.DevicePath != "" && e.DevicePath != f.DevicePath {
+			continue
+		}
+		if f.DiskID != "" && e.DiskID != f.DiskID {
+			continue
+		}
+		if f.Kind != "" && !strings.HasPrefix(e.URI, "spotify:"+f.Kind+":") {
+			continue
+		}
+		if q != "" && !strings.Contains(strings.ToLower(strings.Join(
+			[]string{e.Title, e.Artist, e.URI, e.DiskID, e.DiskLabel, e.DiskUUID}, "\n")), q) {
+			continue
+		}
+		es = append(es, e)
+	}
+	return es
+}
+
+// Copied returns true if the disk identity belongs to a disk with a filesystem UUID other than the one given, i.e. the
+// disk identity was copied from another disk along with its contents.
+func (c *Catalogue) Copied(id, uuid string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.load()
+	if id == "" || uuid == "" {
+		return false
+	}
+	for _, e := range c.entries {
+		if e.DiskID == id && e.DiskUUID != "" && e.DiskUUID != uuid {
+			return true
+		}
+	}
+	return false
+}
+
+// Duplicates returns the albums and playlists which are currently held by more than one disk, according to the most
+// recent entry for each disk identity. The entries for each Spotify URI are returned most recent first.
+func (c *Catalogue) Duplicates() map[string][]CatalogueEntry {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.load()
+	seen := map[string]bool{}
+	held := map[string][]CatalogueEntry{}
+	for i := len(c.entries) - 1; i >= 0; i-- {
+		e := c.entries[i]
+		if e.DiskID == "" || seen[e.DiskID] {
+			continue
+		}
+		seen[e.DiskID] = true
+		held[e.URI] = append(held[e.URI], e)
+	}
+
+	ds := map[string][]CatalogueEntry{}
+	for u, es := range held {
+		if len(es) > 1 {
+			ds[u] = es
+		}
+	}
+	return ds
+}
+
+// load reads the entries from the catalogue file the first time the catalogue is used. Lines which cannot be parsed
+// are logged and skipped, so that a partially written entry does not hide the rest of the catalogue.
+func (c *Catalogue) load() {
+	if c.loaded || c.path == "" {
+		return
+	}
+	c.loaded = true
+
+	f, err := os.Open(c.path)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		log.Printf("Unable to read catalogue %s: %s", c.path, err)
+		return
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		if strings.TrimSpace(s.Text()) == "" {
+			continue
+		}
+		var e CatalogueEntry
+		err := json.Unmarshal(s.Bytes(), &e)
+		if err != nil {
+			log.Printf("Skipping invalid entry on line %d of catalogue %s: %s", n, c.path, err)
+			continue
+		}
+		c.entries = append(c.entries, e)
+	}
+	if err := s.Err(); err != nil {
+		log.Printf("Unable to read catalogue %s: %s", c.path, err)
+	}
+}
+
+// WriteCatalogueCSV writes the entries as CSV, with a header row naming the columns.
+// An error is returned if one is encountered.
+func WriteCatalogueCSV(w io.Writer, es []CatalogueEntry) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"time", "action", "uri", "web_url", "title", "artist", "device_path", "model", "disk_id",
+		"disk_uuid", "disk_label", "remote_addr"})
+	for _, e := range es {
+		cw.Write([]string{e.Time.Format(time.RFC3339), e.Action, e.URI, e.WebURL, e.Title, e.Artist, e.DevicePath,
+			e.Model, e.DiskID, e.DiskUUID, e.DiskLabel, e.RemoteAddr})
+	}
+	cw.Flush()
+	return cw.Error()
+}
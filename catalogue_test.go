@@ -0,0 +1,130 @@
+package diskplayer
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var catalogueEntries = []CatalogueEntry{
+	{Action: "record", URI: "spotify:album:1S7mumn7D4riEX2gVWYgPO", Title: "Kind of Blue", Artist: "Miles Davis",
+		DevicePath: "/dev/sda", DiskLabel: "JAZZ"},
+	{Action: "record", URI: "spotify:playlist:5XsXwH5uWdhpAWsigjWMTA", Title: "Road Trip", DevicePath: "/dev/sdb",
+		DiskUUID: "1234-ABCD"},
+	{Action: "undo", URI: "spotify:album:1S7mumn7D4riEX2gVWYgPO", Title: "Kind of Blue", Artist: "Miles Davis",
+		DevicePath: "/dev/sdb", DiskUUID: "1234-ABCD"},
+}
+
+func TestCatalogueSearch(t *testing.T) {
+	c := NewCatalogue("")
+	for _, e := range catalogueEntries {
+		assert.NoError(t, c.Add(e))
+	}
+
+	var tests = []struct {
+		f   CatalogueFilter
+		out []int
+	}{
+		{CatalogueFilter{}, []int{2, 1, 0}},
+		{CatalogueFilter{Limit: 2}, []int{2, 1}},
+		{CatalogueFilter{Query: "miles"}, []int{2, 0}},
+		{CatalogueFilter{Query: "jazz"}, []int{0}},
+		{CatalogueFilter{Query: "1234-abcd"}, []int{2, 1}},
+		{CatalogueFilter{Kind: "playlist"}, []int{1}},
+		{CatalogueFilter{DevicePath: "/dev/sdb", Kind: "album"}, []int{2}},
+		{CatalogueFilter{Query: "florble"}, []int{}},
+	}
+	for _, tt := range tests {
+		es := c.Search(tt.f)
+		out := []int{}
+		for _, e := range es {
+			for i := range catalogueEntries {
+				if e == catalogueEntries[i] {
+					out = append(out, i)
+				}
+			}
+		}
+		assert.Equal(t, tt.out, out, "%+v", tt.f)
+	}
+}
+
+func TestCatalogueDiskHistory(t *testing.T) {
+	c := NewCatalogue("")
+	es := []CatalogueEntry{
+		{URI: "spotify:album:1", DiskID: "a", DiskUUID: "1111-1111"},
+		{URI: "spotify:album:2", DiskID: "b", DiskUUID: "2222-2222"},
+		{URI: "spotify:album:2", DiskID: "a", DiskUUID: "1111-1111"},
+		{URI: "spotify:album:3", DiskID: "c", DiskUUID: "3333-3333"},
+		{URI: "spotify:album:3", DiskID: "d"},
+		{URI: "spotify:album:3"},
+	}
+	for _, e := range es {
+		assert.NoError(t, c.Add(e))
+	}
+
+	assert.Equal(t, []CatalogueEntry{es[2], es[0]}, c.Search(CatalogueFilter{DiskID: "a"}))
+
+	assert.Equal(t, map[string][]CatalogueEntry{
+		"spotify:album:2": {es[2], es[1]},
+		"spotify:album:3": {es[4], es[3]},
+	}, c.Duplicates())
+
+	assert.False(t, c.Copied("a", "1111-1111"))
+	assert.True(t, c.Copied("a", "2222-2222"))
+	assert.False(t, c.Copied("a", ""))
+	assert.False(t, c.Copied("d", "4444-4444"))
+	assert.False(t, c.Copied("e", "1111-1111"))
+}
+
+func TestCataloguePersisted(t *testing.T) {
+	d, err := ioutil.TempDir("", "diskplayer_catalogue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+	p := filepath.Join(d, "catalogue.jsonl")
+
+	c := NewCatalogue(p)
+	assert.Empty(t, c.Search(CatalogueFilter{}))
+	e := catalogueEntries[0]
+	e.Time = time.Date(2019, 11, 3, 20, 15, 0, 0, time.UTC)
+	assert.NoError(t, c.Add(e))
+
+	f, err := os.OpenFile(p, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("{\"action\":\"rec\n")
+	f.Close()
+
+	c = NewCatalogue(p)
+	assert.NoError(t, c.Add(catalogueEntries[1]))
+	es := c.Search(CatalogueFilter{})
+	assert.Len(t, es, 2)
+	assert.True(t, e.Time.Equal(es[1].Time))
+	es[1].Time = e.Time
+	assert.Equal(t, e, es[1])
+	assert.Equal(t, catalogueEntries[1], es[0])
+}
+
+func TestCatalogueAddError(t *testing.T) {
+	c := NewCatalogue("./test-fixtures/not_a_real_path/catalogue.jsonl")
+	assert.Error(t, c.Add(catalogueEntries[0]))
+	assert.Len(t, c.Search(CatalogueFilter{}), 1)
+}
+
+func TestWriteCatalogueCSV(t *testing.T) {
+	e := catalogueEntries[0]
+	e.Time = time.Date(2019, 11, 3, 20, 15, 0, 0, time.UTC)
+	e.Title = "Kind of Blue, Legacy Edition"
+
+	var b bytes.Buffer
+	assert.NoError(t, WriteCatalogueCSV(&b, []CatalogueEntry{e}))
+	assert.Equal(t, "time,action,uri,web_url,title,artist,device_path,model,disk_id,disk_uuid,disk_label,remote_addr\n"+
+		"2019-11-03T20:15:00Z,record,spotify:album:1S7mumn7D4riEX2gVWYgPO,,\"Kind of Blue, Legacy Edition\","+
+		"Miles Davis,/dev/sda,,,,JAZZ,\n", b.String())
+}
@@ -1,10 +1,18 @@
 package diskplayer
 
 import (
+	"encoding/json"
+	"fmt"
 	"github.com/zmb3/spotify"
 	"golang.org/x/oauth2"
+	"net/http"
+	"net/url"
+	"strconv"
 )
 
+// spotifyApiUrl is the base URL of the Spotify Web API, used for requests which the spotify package does not support.
+var spotifyApiUrl = "https://api.spotify.com/v1/"
+
 // Returns an authenticated Spotify client object, or an error if encountered.
 func NewClient(a *spotify.Authenticator, t *oauth2.Token) *SpotifyClient {
 	c := a.NewClient(t)
@@ -16,6 +24,25 @@ type Client interface {
 	Pause() error
 	TransferPlayback(deviceID spotify.ID, play bool) error
 	PlayOpt(opt *spotify.PlayOptions) error
+	GetAlbum(id spotify.ID) (*spotify.FullAlbum, error)
+	GetPlaylist(id spotify.ID) (*spotify.FullPlaylist, error)
+	GetPlaylistTracksOpt(id spotify.ID, opt *spotify.Options, fields string) (*spotify.PlaylistTrackPage, error)
+	GetArtist(id spotify.ID) (*spotify.FullArtist, error)
+	GetTrack(id spotify.ID) (*spotify.FullTrack, error)
+	GetShow(id string) (*spotify.FullShow, error)
+	Search(query string, t spotify.SearchType, limit int) (*spotify.SearchResult, error)
+	SearchShows(query string, limit int) ([]spotify.SimpleShow, error)
+	CurrentUsersAlbumsOpt(opt *spotify.Options) (*spotify.SavedAlbumPage, error)
+	CurrentUsersPlaylistsOpt(opt *spotify.Options) (*spotify.SimplePlaylistPage, error)
+	CurrentUsersShowsOpt(opt *spotify.Options) (*spotify.SavedShowPage, error)
+	PlayerCurrentlyPlaying() (*spotify.CurrentlyPlaying, error)
+	CurrentUsersTracksOpt(opt *spotify.Options) (*spotify.SavedTrackPage, error)
+	CurrentUsersTopTracksOpt(opt *spotify.Options) (*spotify.FullTrackPage, error)
+	PlayerRecentlyPlayedOpt(opt *spotify.RecentlyPlayedOptions) ([]spotify.RecentlyPlayedItem, error)
+	GetShowEpisodesOpt(opt *spotify.Options, id string) (*spotify.SimpleEpisodePage, error)
+	GetRecommendations(seeds spotify.Seeds, attrs *spotify.TrackAttributes,
+		opt *spotify.Options) (*spotify.Recommendations, error)
+	QueueSong(trackID spotify.ID) error
 }
 
 type SpotifyClient struct {
@@ -27,7 +54,6 @@ func (sc *SpotifyClient) PlayerDevices() ([]spotify.PlayerDevice, error) {
 	return sc.client.PlayerDevices()
 }
 
-
 // Pause will pause playback for the currently active device.
 func (sc *SpotifyClient) Pause() error {
 	return sc.client.Pause()
@@ -42,3 +68,146 @@ func (sc *SpotifyClient) TransferPlayback(deviceID spotify.ID, play bool) error
 func (sc *SpotifyClient) PlayOpt(opt *spotify.PlayOptions) error {
 	return sc.client.PlayOpt(opt)
 }
+
+// GetAlbum will return the full album details for the album identified by the Spotify ID.
+func (sc *SpotifyClient) GetAlbum(id spotify.ID) (*spotify.FullAlbum, error) {
+	return sc.client.GetAlbum(id)
+}
+
+// GetPlaylist will return the full playlist details for the playlist identified by the Spotify ID.
+func (sc *SpotifyClient) GetPlaylist(id spotify.ID) (*spotify.FullPlaylist, error) {
+	return sc.client.GetPlaylist(id)
+}
+
+// GetPlaylistTracksOpt will return the page of tracks given by the options from the playlist identified by the
+// Spotify ID. The fields of the tracks returned may be restricted as described for the Spotify Web API, or all are
+// returned if fields is empty.
+func (sc *SpotifyClient) GetPlaylistTracksOpt(id spotify.ID, opt *spotify.Options,
+	fields string) (*spotify.PlaylistTrackPage, error) {
+	return sc.client.GetPlaylistTracksOpt(id, opt, fields)
+}
+
+// GetArtist will return the full artist details for the artist identified by the Spotify ID.
+func (sc *SpotifyClient) GetArtist(id spotify.ID) (*spotify.FullArtist, error) {
+	return sc.client.GetArtist(id)
+}
+
+// GetTrack will return the full track details for the track identified by the Spotify ID.
+func (sc *SpotifyClient) GetTrack(id spotify.ID) (*spotify.FullTrack, error) {
+	return sc.client.GetTrack(id)
+}
+
+// GetShow will return the full show details for the podcast identified by the Spotify ID.
+func (sc *SpotifyClient) GetShow(id string) (*spotify.FullShow, error) {
+	return sc.client.GetShow(id)
+}
+
+// Search will return up to limit items of each of the kinds in the search type matching the query.
+// An error is returned if encountered.
+func (sc *SpotifyClient) Search(query string, t spotify.SearchType, limit int) (*spotify.SearchResult, error) {
+	return sc.client.SearchOpt(query, t, &spotify.Options{Limit: &limit})
+}
+
+// SearchShows will return up to limit podcast shows matching the query which are available in the market of the
+// authenticated user. The spotify package does not support searching for shows, so the request is made directly
+// with the client's token.
+// An error is returned if encountered.
+func (sc *SpotifyClient) SearchShows(query string, limit int) ([]spotify.SimpleShow, error) {
+	t, err := sc.client.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	v := url.Values{"q": {query}, "type": {"show"}, "market": {"from_token"}, "limit": {strconv.Itoa(limit)}}
+	req, err := http.NewRequest(http.MethodGet, spotifyApiUrl+"search?"+v.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	t.SetAuthHeader(req)
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		var e struct {
+			Error spotify.Error `json:"error"`
+		}
+		if json.NewDecoder(resp.Body).Decode(&e) != nil || e.Error.Message == "" {
+			return nil, fmt.Errorf("spotify: HTTP %d: %s", resp.StatusCode, http.StatusText(resp.StatusCode))
+		}
+		return nil, e.Error
+	}
+
+	var r struct {
+		Shows struct {
+			Items []spotify.SimpleShow `json:"items"`
+		} `json:"shows"`
+	}
+	err = json.NewDecoder(resp.Body).Decode(&r)
+	if err != nil {
+		return nil, err
+	}
+	return r.Shows.Items, nil
+}
+
+// CurrentUsersAlbumsOpt will return the page of albums saved in the library of the authenticated user given by the
+// options.
+func (sc *SpotifyClient) CurrentUsersAlbumsOpt(opt *spotify.Options) (*spotify.SavedAlbumPage, error) {
+	return sc.client.CurrentUsersAlbumsOpt(opt)
+}
+
+// CurrentUsersPlaylistsOpt will return the page of playlists owned or followed by the authenticated user given by the
+// options.
+func (sc *SpotifyClient) CurrentUsersPlaylistsOpt(opt *spotify.Options) (*spotify.SimplePlaylistPage, error) {
+	return sc.client.CurrentUsersPlaylistsOpt(opt)
+}
+
+// CurrentUsersShowsOpt will return the page of shows saved in the library of the authenticated user given by the
+// options.
+func (sc *SpotifyClient) CurrentUsersShowsOpt(opt *spotify.Options) (*spotify.SavedShowPage, error) {
+	return sc.client.CurrentUsersShowsOpt(opt)
+}
+
+// PlayerCurrentlyPlaying will return the track currently being played by the authenticated user, along with the
+// context it is being played from, if any.
+func (sc *SpotifyClient) PlayerCurrentlyPlaying() (*spotify.CurrentlyPlaying, error) {
+	return sc.client.PlayerCurrentlyPlaying()
+}
+
+// CurrentUsersTracksOpt will return the page of tracks saved to the Liked Songs of the authenticated user given by the
+// options, most recently saved first.
+func (sc *SpotifyClient) CurrentUsersTracksOpt(opt *spotify.Options) (*spotify.SavedTrackPage, error) {
+	return sc.client.CurrentUsersTracksOpt(opt)
+}
+
+// CurrentUsersTopTracksOpt will return the page of the most played tracks of the authenticated user over the time range
+// given by the options.
+func (sc *SpotifyClient) CurrentUsersTopTracksOpt(opt *spotify.Options) (*spotify.FullTrackPage, error) {
+	return sc.client.CurrentUsersTopTracksOpt(opt)
+}
+
+// PlayerRecentlyPlayedOpt will return the tracks most recently played by the authenticated user, most recent first.
+func (sc *SpotifyClient) PlayerRecentlyPlayedOpt(opt *spotify.RecentlyPlayedOptions) ([]spotify.RecentlyPlayedItem,
+	error) {
+	return sc.client.PlayerRecentlyPlayedOpt(opt)
+}
+
+// GetShowEpisodesOpt will return the page of episodes of the show with the given ID given by the options, newest
+// first.
+func (sc *SpotifyClient) GetShowEpisodesOpt(opt *spotify.Options, id string) (*spotify.SimpleEpisodePage, error) {
+	return sc.client.GetShowEpisodesOpt(opt, id)
+}
+
+// GetRecommendations will return tracks recommended by Spotify for the seeds, with the attributes given.
+func (sc *SpotifyClient) GetRecommendations(seeds spotify.Seeds, attrs *spotify.TrackAttributes,
+	opt *spotify.Options) (*spotify.Recommendations, error) {
+	return sc.client.GetRecommendations(seeds, attrs, opt)
+}
+
+// QueueSong will add the track with the given ID to the queue of the currently active device.
+func (sc *SpotifyClient) QueueSong(trackID spotify.ID) error {
+	return sc.client.QueueSong(trackID)
+}
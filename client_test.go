@@ -4,6 +4,8 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/zmb3/spotify"
 	"golang.org/x/oauth2"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 	"time"
 )
@@ -20,3 +22,45 @@ func TestNewClient(t *testing.T) {
 	c := NewClient(&a, &tok)
 	assert.NotNil(t, c)
 }
+
+func searchShowsServer(t *testing.T, status int, body string) (*SpotifyClient, func()) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/search", r.URL.Path)
+		assert.Equal(t, "Bearer temp_access_token", r.Header.Get("Authorization"))
+		assert.Equal(t, "test show", r.FormValue("q"))
+		assert.Equal(t, "show", r.FormValue("type"))
+		assert.Equal(t, "from_token", r.FormValue("market"))
+		assert.Equal(t, "5", r.FormValue("limit"))
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+
+	u := spotifyApiUrl
+	spotifyApiUrl = ts.URL + "/"
+	tok := oauth2.Token{AccessToken: "temp_access_token", TokenType: "Bearer", Expiry: time.Now().Add(time.Hour)}
+	return NewClient(&spotify.Authenticator{}, &tok), func() {
+		spotifyApiUrl = u
+		ts.Close()
+	}
+}
+
+func TestSearchShows(t *testing.T) {
+	c, cleanup := searchShowsServer(t, http.StatusOK,
+		`{"shows":{"items":[{"id":"4rOoJ6Egrf8K2IrywzwOMk","name":"Test Show","publisher":"Test Publisher"}]}}`)
+	defer cleanup()
+
+	ss, err := c.SearchShows("test show", 5)
+	assert.NoError(t, err)
+	assert.Len(t, ss, 1)
+	assert.Equal(t, spotify.ID("4rOoJ6Egrf8K2IrywzwOMk"), ss[0].ID)
+	assert.Equal(t, "Test Publisher", ss[0].Publisher)
+}
+
+func TestSearchShowsError(t *testing.T) {
+	c, cleanup := searchShowsServer(t, http.StatusUnauthorized,
+		`{"error":{"status":401,"message":"The access token expired"}}`)
+	defer cleanup()
+
+	_, err := c.SearchShows("test show", 5)
+	assert.EqualError(t, err, "The access token expired")
+}
@@ -9,9 +9,15 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "record-current" {
+		recordCurrent(os.Args[2:])
+		return
+	}
+
 	auth := flag.Bool("auth", false, "Retrieve a new Spotify OAuth2 token.")
 	uri := flag.String("uri", "", "Spotify URI of album/playlist to play.")
 	path := flag.String("path", "", "Path to file containing Spotify URI to play.")
+	device := flag.String("device", "", "Path to FAT formatted disk device (or image) containing Spotify URI to play.")
 	pause := flag.Bool("pause", false, "Pause Spotify playback.")
 	flag.Parse()
 	a := flag.Args()
@@ -19,14 +25,15 @@ func main() {
 		log.Fatalf("Unknown argument: %s. You might be missing a \"-\".", a[0]) // Expect user to eliminate unknown arguments
 	}
 
-	if (*auth && *pause) || (*auth && (*uri != "" || *path != "")) || (*pause && (*uri != "" || *path != "")) {
+	play := *uri != "" || *path != "" || *device != ""
+	if (*auth && *pause) || (*auth && play) || (*pause && play) {
 		flag.Usage()
-		log.Fatal("Please specify either [auth] OR [pause] OR ONE OF [uri, path].")
+		log.Fatal("Please specify either [auth] OR [pause] OR ONE OF [uri, path, device].")
 	}
 
-	if *uri != "" && *path != "" {
+	if (*uri != "" && *path != "") || (*uri != "" && *device != "") || (*path != "" && *device != "") {
 		flag.Usage()
-		log.Fatal("Please specify either [uri] or [path], but not both.")
+		log.Fatal("Please specify only one of [uri], [path] or [device].")
 	}
 
 	diskplayer.ReadConfig(diskplayer.DEFAULT_CONFIG_NAME)
@@ -65,6 +72,8 @@ func main() {
 		err = diskplayer.PlayUri(c, *uri)
 	} else if *path != "" {
 		err = diskplayer.PlayPath(c, *path)
+	} else if *device != "" {
+		err = diskplayer.PlayDevice(c, *device)
 	} else {
 		flag.Usage()
 	}
@@ -73,3 +82,46 @@ func main() {
 		log.Fatal(err)
 	}
 }
+
+// recordCurrent runs the record-current command, which records whatever is currently playing on Spotify to the disk
+// given by the -device or -path flag.
+func recordCurrent(args []string) {
+	fs := flag.NewFlagSet("record-current", flag.ExitOnError)
+	device := fs.String("device", "", "Path to FAT formatted disk device (or image) to record to.")
+	path := fs.String("path", "", "Path to the contents file to record to.")
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		log.Fatalf("Unknown argument: %s. You might be missing a \"-\".", fs.Arg(0))
+	}
+
+	if (*device == "") == (*path == "") {
+		fs.Usage()
+		log.Fatal("Please specify one of [device] or [path].")
+	}
+
+	diskplayer.ReadConfig(diskplayer.DEFAULT_CONFIG_NAME)
+
+	an, err := diskplayer.NewAuthenticator()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	t, err := diskplayer.ReadToken()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	c := diskplayer.NewClient(an, t)
+
+	var u string
+	if *device != "" {
+		u, err = diskplayer.RecordCurrentDevice(c, *device)
+	} else {
+		u, err = diskplayer.RecordCurrent(c, *path)
+	}
+
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Printf("Recorded %s", u)
+}
@@ -1,13 +1,60 @@
 package main
 
 import (
+	"bufio"
+	"flag"
+	"fmt"
 	"github.com/dinofizz/diskplayer"
 	"log"
+	"os"
+	"strings"
 )
 
 func main() {
+	hash := flag.Bool("hash-password", false, "Read a password from standard input and print its hash for the "+
+		"recorder.auth.password_hash configuration value.")
+	flag.Parse()
+	a := flag.Args()
+	if len(a) != 0 {
+		log.Fatalf("Unknown argument: %s. You might be missing a \"-\".", a[0])
+	}
+
+	if *hash {
+		fmt.Fprint(os.Stderr, "Password: ")
+		p, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		p = strings.TrimRight(p, "\r\n")
+		if p == "" {
+			log.Fatalf("Unable to read password: %v", err)
+		}
+		h, err := diskplayer.HashPassword(p)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(h)
+		os.Exit(0)
+	}
+
 	diskplayer.ReadConfig(diskplayer.DEFAULT_CONFIG_NAME)
-	ds := diskplayer.NewDiskplayerServer(nil, nil)
+
+	an, err := diskplayer.NewAuthenticator()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var c diskplayer.Client
+	t, err := diskplayer.ReadToken()
+	if err != nil {
+		log.Printf("Unable to read Spotify token, disk labels will not be available: %s", err)
+	} else {
+		c = diskplayer.NewClient(an, t)
+	}
+
+	m, err := diskplayer.NewMounter()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ds := diskplayer.NewRecordServer(c, m)
 	e := ds.RunRecordServer()
 	if e != nil {
 		log.Fatal(e)
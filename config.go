@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"github.com/spf13/viper"
 	"log"
+	"path/filepath"
 )
 
 // ReadConfig reads in the configuration values from the diskplayer.yaml configuration file.
@@ -13,8 +14,18 @@ func ReadConfig(n string) {
 	viper.AddConfigPath("$HOME/.config/diskplayer/")
 	viper.AddConfigPath(".")
 	viper.SetDefault("token.path", "token.json")
+	viper.SetDefault("player.state_path", "player_state.json")
 	viper.SetDefault("spotify.callback_url", "http://localhost:8080/callback")
 	viper.SetDefault("recorder.server_port", "3000")
+	viper.SetDefault("recorder.write_mode", "mount")
+	viper.SetDefault("recorder.mounter", "syscall")
+	viper.SetDefault("recorder.catalogue", "catalogue.jsonl")
+	viper.SetDefault("recorder.auth.session_ttl", "12h")
+	viper.SetDefault("recorder.policy.max_size", "64GB")
+	viper.SetDefault("recorder.policy.deny", []string{"/dev/mmcblk0*"})
+	viper.SetDefault("tls.mode", "off")
+	viper.SetDefault("tls.cert_file", "diskplayer.crt")
+	viper.SetDefault("tls.key_file", "diskplayer.key")
 	err := viper.ReadInConfig()
 	if err != nil {
 		panic(fmt.Errorf("Fatal error config file: %s \n", err))
@@ -31,3 +42,27 @@ func ConfigValue(key string) string {
 
 	return value
 }
+
+// ConfigPath returns the file path held by the configuration value identified by the provided key. A relative path is
+// resolved against the folder holding the configuration file, so that it does not depend on the working directory.
+// An empty string is returned if none is found.
+func ConfigPath(key string) string {
+	p := viper.GetString(key)
+	if p == "" || filepath.IsAbs(p) || viper.ConfigFileUsed() == "" {
+		return p
+	}
+	return filepath.Join(filepath.Dir(viper.ConfigFileUsed()), p)
+}
+
+// ConfigValues returns the list of configuration values identified by the provided key.
+// An empty list is returned if none are found.
+func ConfigValues(key string) []string {
+	return viper.GetStringSlice(key)
+}
+
+// ConfigSize returns the size in bytes of the configuration value identified by the provided key. Sizes may be
+// specified with a unit suffix, i.e. "1440KB" or "64GB".
+// Zero is returned if none is found.
+func ConfigSize(key string) int64 {
+	return int64(viper.GetSizeInBytes(key))
+}
@@ -2,6 +2,8 @@ package diskplayer
 
 import (
 	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
 	"testing"
 )
 
@@ -24,3 +26,23 @@ func TestConfigValue(t *testing.T) {
 	}
 
 }
+
+func TestConfigPath(t *testing.T) {
+	viper.AddConfigPath("./test-fixtures")
+	ReadConfig("test_config")
+	defer viper.Set("recorder.audit_log", "")
+
+	// Relative paths are found alongside test-fixtures/test_config.yaml, whatever the working directory.
+	p, err := filepath.Abs("test-fixtures/audit.log")
+	if err != nil {
+		t.Fatal(err)
+	}
+	viper.Set("recorder.audit_log", "./audit.log")
+	assert.Equal(t, p, ConfigPath("recorder.audit_log"))
+
+	viper.Set("recorder.audit_log", "/var/log/diskplayer/audit.log")
+	assert.Equal(t, "/var/log/diskplayer/audit.log", ConfigPath("recorder.audit_log"))
+
+	viper.Set("recorder.audit_log", "")
+	assert.Equal(t, "", ConfigPath("recorder.audit_log"))
+}
@@ -1,14 +1,32 @@
 package diskplayer
 
 const (
-	DEFAULT_CONFIG_NAME   = "diskplayer"
-	STATE_IDENTIFIER      = "abc123"
-	RECORD_FILENAME		  = "recorder.filename"
-	RECORD_FOLDER_PATH    = "recorder.folder_path"
-	RECORD_SERVER_PORT    = "recorder.server_port"
-	SPOTIFY_CALLBACK_URL  = "spotify.callback_url"
-	SPOTIFY_CLIENT_ID     = "spotify.client_id"
-	SPOTIFY_CLIENT_SECRET = "spotify.client_secret"
-	SPOTIFY_DEVICE_NAME   = "spotify.device_name"
-	TOKEN_PATH            = "token.path"
+	DEFAULT_CONFIG_NAME       = "diskplayer"
+	PLAYER_STATE_PATH         = "player.state_path"
+	STATE_IDENTIFIER          = "abc123"
+	RECORD_ASSETS_PATH        = "recorder.assets_path"
+	RECORD_AUDIT_LOG          = "recorder.audit_log"
+	RECORD_AUTH_PASSWORD_HASH = "recorder.auth.password_hash"
+	RECORD_AUTH_SESSION_TTL   = "recorder.auth.session_ttl"
+	RECORD_AUTH_TOKENS        = "recorder.auth.api_tokens"
+	RECORD_BIND_ADDRESS       = "recorder.bind_address"
+	RECORD_CATALOGUE          = "recorder.catalogue"
+	RECORD_FILENAME           = "recorder.filename"
+	RECORD_FOLDER_PATH        = "recorder.folder_path"
+	RECORD_MOUNTER            = "recorder.mounter"
+	RECORD_POLICY_ALLOW       = "recorder.policy.allow"
+	RECORD_POLICY_DENY        = "recorder.policy.deny"
+	RECORD_POLICY_MAXSIZE     = "recorder.policy.max_size"
+	RECORD_REDIRECT_PORT      = "recorder.redirect_port"
+	RECORD_SERVER_PORT        = "recorder.server_port"
+	RECORD_WRITE_MODE         = "recorder.write_mode"
+	SPOTIFY_CALLBACK_URL      = "spotify.callback_url"
+	SPOTIFY_CLIENT_ID         = "spotify.client_id"
+	SPOTIFY_CLIENT_SECRET     = "spotify.client_secret"
+	SPOTIFY_DEVICE_NAME       = "spotify.device_name"
+	TLS_CERT_FILE             = "tls.cert_file"
+	TLS_HOSTS                 = "tls.hosts"
+	TLS_KEY_FILE              = "tls.key_file"
+	TLS_MODE                  = "tls.mode"
+	TOKEN_PATH                = "token.path"
 )
@@ -0,0 +1,125 @@
+package diskplayer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// CONTENTS_URI is the kind of contents holding a single Spotify URI of an album, playlist, artist, show or track.
+const CONTENTS_URI = "uri"
+
+// CONTENTS_SNAPSHOT is the kind of contents holding a fixed list of Spotify track URIs, recorded from a playlist.
+const CONTENTS_SNAPSHOT = "snapshot"
+
+// CONTENTS_SMART is the kind of contents holding a smart reference, such as the user's Liked Songs, which is resolved
+// into a list of tracks each time the disk is inserted.
+const CONTENTS_SMART = "smart"
+
+// CONTENTS_RADIO is the kind of contents holding a radio, which plays tracks recommended by Spotify for as long as it
+// is playing.
+const CONTENTS_RADIO = "radio"
+
+// CONTENTS_SURPRISE is the kind of contents which play something chosen at random from a pool of Spotify URIs, or
+// from the albums of a playlist.
+const CONTENTS_SURPRISE = "surprise"
+
+// Contents describes what is played when a disk is inserted.
+// Contents of the CONTENTS_URI kind are kept in the contents file as the Spotify URI on its own, as they always have
+// been, so that disks recorded by older versions can still be played and vice versa. Any other kind is kept as a JSON
+// object, which is recognised by the contents starting with "{".
+// The URI identifies the album or playlist the contents were recorded from, and is used to describe the disk. The
+// source of smart contents is one of the SMART_* values, as described for SmartContents, radio contents hold the
+// radio they play, and surprise contents may hold the pool they choose from.
+type Contents struct {
+	Kind   string   `json:"kind"`
+	Source string   `json:"source,omitempty"`
+	URI    string   `json:"uri,omitempty"`
+	Title  string   `json:"title,omitempty"`
+	Tracks []string `json:"tracks,omitempty"`
+	Radio  *Radio   `json:"radio,omitempty"`
+	Pool   []string `json:"pool,omitempty"`
+}
+
+// ParseContents returns the contents held by a contents file, in either the JSON form or as a Spotify URI on the first
+// line.
+// An error is returned if the contents are empty or invalid.
+func ParseContents(b []byte) (*Contents, error) {
+	b = bytes.TrimSpace(b)
+	if len(b) == 0 {
+		return nil, fmt.Errorf("no contents found")
+	}
+
+	if b[0] != '{' {
+		l := strings.TrimSpace(strings.SplitN(string(b), "\n", 2)[0])
+		return &Contents{Kind: CONTENTS_URI, URI: l}, nil
+	}
+
+	c := &Contents{}
+	err := json.Unmarshal(b, c)
+	if err != nil {
+		return nil, fmt.Errorf("invalid contents: %s", err)
+	}
+	if c.Kind == "" {
+		return nil, fmt.Errorf("invalid contents: no kind")
+	}
+	return c, nil
+}
+
+// Encode returns the contents in the form in which they are written to a contents file.
+// An error is returned if one is encountered.
+func (c *Contents) Encode() (string, error) {
+	if c.Kind == CONTENTS_URI {
+		return c.URI, nil
+	}
+
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(b) + "\n", nil
+}
+
+// PlayContents will play the contents of a disk, according to their kind. Radio contents keep playing until something
+// else is played, as described for PlayRadio.
+// An error is returned if one is encountered.
+func PlayContents(c Client, ct *Contents) error {
+	switch ct.Kind {
+	case CONTENTS_URI:
+		return PlayUri(c, ct.URI)
+	case CONTENTS_SNAPSHOT:
+		return PlayTracks(c, ct.Tracks)
+	case CONTENTS_SMART:
+		ts, err := ResolveSmart(c, ct)
+		if err != nil {
+			return err
+		}
+		return PlayTracks(c, ts)
+	case CONTENTS_RADIO:
+		return PlayRadio(c, ct.Radio)
+	case CONTENTS_SURPRISE:
+		return PlaySurprise(c, ct)
+	default:
+		return fmt.Errorf("unknown contents kind: %s", ct.Kind)
+	}
+}
+
+// contentsUri returns the Spotify URI identifying the contents of a disk, or an empty string if there are none.
+func contentsUri(b []byte) string {
+	c, err := ParseContents(b)
+	if err != nil {
+		return ""
+	}
+	return c.URI
+}
+
+// contentsKind returns the kind of the contents of a disk held as a JSON object, or an empty string if they are a
+// plain Spotify URI or there are none.
+func contentsKind(b []byte) string {
+	c, err := ParseContents(b)
+	if err != nil || c.Kind == CONTENTS_URI {
+		return ""
+	}
+	return c.Kind
+}
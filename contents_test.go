@@ -0,0 +1,77 @@
+package diskplayer
+
+import (
+	"github.com/dinofizz/diskplayer/mocks"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/zmb3/spotify"
+	"testing"
+)
+
+var parseContentsTests = []struct {
+	in   string
+	kind string
+	uri  string
+	e    string
+}{
+	{"spotify:album:1S7mumn7D4riEX2gVWYgPO", CONTENTS_URI, "spotify:album:1S7mumn7D4riEX2gVWYgPO", ""},
+	{"spotify:album:1S7mumn7D4riEX2gVWYgPO\nignored\n", CONTENTS_URI, "spotify:album:1S7mumn7D4riEX2gVWYgPO", ""},
+	{`{"kind":"snapshot","uri":"spotify:playlist:5XsXwH5uWdhpAWsigjWMTA","tracks":["spotify:track:a"]}`,
+		CONTENTS_SNAPSHOT, "spotify:playlist:5XsXwH5uWdhpAWsigjWMTA", ""},
+	{" \n", "", "", "no contents found"},
+	{"{florble", "", "", "invalid contents: invalid character 'f' looking for beginning of object key string"},
+	{`{"uri":"spotify:playlist:5XsXwH5uWdhpAWsigjWMTA"}`, "", "", "invalid contents: no kind"},
+}
+
+func TestParseContents(t *testing.T) {
+	for _, tt := range parseContentsTests {
+		t.Run(tt.in, func(t *testing.T) {
+			c, err := ParseContents([]byte(tt.in))
+			if tt.e != "" {
+				assert.EqualError(t, err, tt.e)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.kind, c.Kind)
+			assert.Equal(t, tt.uri, c.URI)
+		})
+	}
+}
+
+func TestContentsEncode(t *testing.T) {
+	c := &Contents{Kind: CONTENTS_URI, URI: "spotify:album:1S7mumn7D4riEX2gVWYgPO"}
+	s, err := c.Encode()
+	assert.NoError(t, err)
+	assert.Equal(t, "spotify:album:1S7mumn7D4riEX2gVWYgPO", s)
+
+	c = &Contents{Kind: CONTENTS_SNAPSHOT, URI: "spotify:playlist:5XsXwH5uWdhpAWsigjWMTA", Title: "Test Playlist",
+		Tracks: []string{"spotify:track:a", "spotify:track:b"}}
+	s, err = c.Encode()
+	assert.NoError(t, err)
+	assert.Equal(t, "{\n  \"kind\": \"snapshot\",\n  \"uri\": \"spotify:playlist:5XsXwH5uWdhpAWsigjWMTA\",\n"+
+		"  \"title\": \"Test Playlist\",\n  \"tracks\": [\n    \"spotify:track:a\",\n    \"spotify:track:b\"\n  ]\n}\n", s)
+
+	p, err := ParseContents([]byte(s))
+	assert.NoError(t, err)
+	assert.Equal(t, c, p)
+}
+
+func TestPlayContentsSnapshot(t *testing.T) {
+	viper.Set("spotify.device_name", "test_device_name")
+
+	m := new(mocks.Client)
+	m.On("PlayerDevices").Return([]spotify.PlayerDevice{{ID: "TEST_ID", Name: "test_device_name"}}, nil)
+	m.On("PlayOpt", mock.MatchedBy(func(o *spotify.PlayOptions) bool {
+		return o.PlaybackContext == nil && len(o.URIs) == 2 && o.URIs[1] == "spotify:track:7GhIk7Il098yCjg4BQjzvb"
+	})).Return(nil)
+
+	err := PlayPath(m, "./test-fixtures/snapshot.contents")
+	assert.NoError(t, err)
+	m.AssertExpectations(t)
+}
+
+func TestPlayContentsUnknownKind(t *testing.T) {
+	err := PlayContents(new(mocks.Client), &Contents{Kind: "florble"})
+	assert.EqualError(t, err, "unknown contents kind: florble")
+}
@@ -0,0 +1,62 @@
+package diskplayer
+
+import (
+	"errors"
+	"github.com/zmb3/spotify"
+)
+
+// ErrNothingPlaying is returned when recording what is currently playing but nothing is playing on Spotify.
+var ErrNothingPlaying = errors.New("nothing is currently playing on Spotify")
+
+// CurrentUri returns the Spotify URI of the album, playlist, artist or show which is currently being played by the
+// authenticated user, or of the current track if it is not being played from one of those (e.g. from a radio
+// station or the user's liked songs).
+// ErrNothingPlaying is returned if nothing is playing, or any other error encountered.
+func CurrentUri(c Client) (string, error) {
+	cp, err := c.PlayerCurrentlyPlaying()
+	if err != nil {
+		return "", err
+	}
+
+	if u := string(cp.PlaybackContext.URI); u != "" {
+		k, _, err := splitSpotifyUri(u)
+		if err == nil && (k == "album" || k == "playlist" || k == "artist" || k == "show") {
+			return u, nil
+		}
+	}
+
+	if cp.Item == nil || cp.Item.ID == "" {
+		return "", ErrNothingPlaying
+	}
+	return "spotify:track:" + string(cp.Item.ID), nil
+}
+
+// RecordCurrent records what is currently being played by the authenticated user, as described for CurrentUri, to
+// the contents file at the path in the same way as Record.
+// The recorded Spotify URI is returned, or an error if one is encountered.
+func RecordCurrent(c Client, fullPath string) (string, error) {
+	u, err := CurrentUri(c)
+	if err != nil {
+		return "", err
+	}
+	return u, Record(webUrl(u), fullPath)
+}
+
+// RecordCurrentDevice records what is currently being played by the authenticated user, as described for CurrentUri,
+// directly to the FAT formatted disk whose device path (or image file path) is passed into the function, in the same
+// way as RecordDevice.
+// The recorded Spotify URI is returned, or an error if one is encountered.
+func RecordCurrentDevice(c Client, device string) (string, error) {
+	u, err := CurrentUri(c)
+	if err != nil {
+		return "", err
+	}
+	return u, RecordDevice(webUrl(u), device)
+}
+
+// trackUri returns true if the Spotify URI identifies a single track, which is played on its own rather than as a
+// playback context.
+func trackUri(u spotify.URI) bool {
+	k, _, err := splitSpotifyUri(string(u))
+	return err == nil && k == "track"
+}
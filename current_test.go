@@ -0,0 +1,74 @@
+package diskplayer
+
+import (
+	"errors"
+	"github.com/dinofizz/diskplayer/mocks"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/zmb3/spotify"
+	"os"
+	"testing"
+)
+
+// currentlyPlaying returns a mock client which is playing the track from the context with the Spotify URI.
+func currentlyPlaying(context, track string) *mocks.Client {
+	cp := &spotify.CurrentlyPlaying{Playing: true}
+	cp.PlaybackContext.URI = spotify.URI(context)
+	if track != "" {
+		cp.Item = &spotify.FullTrack{}
+		cp.Item.ID = spotify.ID(track)
+	}
+
+	m := new(mocks.Client)
+	m.On("PlayerCurrentlyPlaying").Return(cp, nil)
+	return m
+}
+
+var currentUriTests = []struct {
+	context string
+	track   string
+	out     string
+}{
+	{"spotify:album:1S7mumn7D4riEX2gVWYgPO", "4uLU6hMCjMI75M1A2tKUQC", "spotify:album:1S7mumn7D4riEX2gVWYgPO"},
+	{"spotify:playlist:5XsXwH5uWdhpAWsigjWMTA", "4uLU6hMCjMI75M1A2tKUQC", "spotify:playlist:5XsXwH5uWdhpAWsigjWMTA"},
+	{"spotify:show:4rOoJ6Egrf8K2IrywzwOMk", "", "spotify:show:4rOoJ6Egrf8K2IrywzwOMk"},
+	{"spotify:user:florble:collection", "4uLU6hMCjMI75M1A2tKUQC", "spotify:track:4uLU6hMCjMI75M1A2tKUQC"},
+	{"", "4uLU6hMCjMI75M1A2tKUQC", "spotify:track:4uLU6hMCjMI75M1A2tKUQC"},
+}
+
+func TestCurrentUri(t *testing.T) {
+	for _, tt := range currentUriTests {
+		t.Run(tt.out, func(t *testing.T) {
+			u, err := CurrentUri(currentlyPlaying(tt.context, tt.track))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.out, u)
+		})
+	}
+}
+
+func TestCurrentUriErrors(t *testing.T) {
+	_, err := CurrentUri(currentlyPlaying("", ""))
+	assert.Equal(t, ErrNothingPlaying, err)
+
+	m := new(mocks.Client)
+	m.On("PlayerCurrentlyPlaying").Return(nil, errors.New("PlayerCurrentlyPlaying error"))
+	_, err = CurrentUri(m)
+	assert.EqualError(t, err, "PlayerCurrentlyPlaying error")
+}
+
+func TestRecordCurrentDevice(t *testing.T) {
+	viper.Set("recorder.filename", "diskplayer.contents")
+	p := formattedImage(t)
+	defer os.Remove(p)
+
+	u, err := RecordCurrentDevice(currentlyPlaying("", "4uLU6hMCjMI75M1A2tKUQC"), p)
+	assert.NoError(t, err)
+	assert.Equal(t, "spotify:track:4uLU6hMCjMI75M1A2tKUQC", u)
+
+	b, err := ReadDeviceFile(p, "diskplayer.contents")
+	assert.NoError(t, err)
+	assert.Equal(t, u, string(b))
+
+	_, err = RecordCurrentDevice(currentlyPlaying("", ""), p)
+	assert.Equal(t, ErrNothingPlaying, err)
+}
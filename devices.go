@@ -0,0 +1,171 @@
+package diskplayer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// BlockDevice describes a block device attached to the machine running the recorder.
+type BlockDevice struct {
+	Name       string `json:"name"`
+	Path       string `json:"path"`
+	Size       int64  `json:"size"`
+	Model      string `json:"model"`
+	FSType     string `json:"fstype"`
+	Type       string `json:"type"`
+	MountPoint string `json:"mountpoint"`
+	Removable  bool   `json:"removable"`
+	System     bool   `json:"system"`
+}
+
+// systemMountPoints are the mount points which indicate that a disk holds the running operating system.
+var systemMountPoints = []string{"/", "/boot", "/boot/firmware", "/usr", "/var", "/home", "[SWAP]"}
+
+// Description returns a short human readable description of the device, suitable for display in a device picker.
+func (d BlockDevice) Description() string {
+	s := []string{formatSize(d.Size)}
+	if d.Model != "" {
+		s = append(s, d.Model)
+	}
+	if d.FSType != "" {
+		s = append(s, d.FSType)
+	} else {
+		s = append(s, "no filesystem")
+	}
+	return fmt.Sprintf("%s (%s)", d.Path, strings.Join(s, ", "))
+}
+
+// lsblkDevice is a single entry in the lsblk JSON output. Older versions of lsblk report all values as strings, so
+// the size and removable flag are decoded from either representation.
+type lsblkDevice struct {
+	Name       string        `json:"name"`
+	Path       string        `json:"path"`
+	Size       lsblkValue    `json:"size"`
+	Model      string        `json:"model"`
+	FSType     string        `json:"fstype"`
+	Type       string        `json:"type"`
+	MountPoint string        `json:"mountpoint"`
+	Removable  lsblkValue    `json:"rm"`
+	Children   []lsblkDevice `json:"children"`
+}
+
+// lsblkValue is a number or boolean value which lsblk may report either as a JSON literal or a string.
+type lsblkValue string
+
+// UnmarshalJSON implements the json.Unmarshaler interface, accepting strings, numbers, booleans and null.
+func (v *lsblkValue) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		*v = lsblkValue(strings.TrimSpace(s))
+		return nil
+	}
+	if string(b) == "null" {
+		*v = ""
+		return nil
+	}
+	*v = lsblkValue(b)
+	return nil
+}
+
+func (v lsblkValue) int64() int64 {
+	i, _ := strconv.ParseInt(string(v), 10, 64)
+	return i
+}
+
+func (v lsblkValue) bool() bool {
+	return v == "1" || v == "true"
+}
+
+// ListRemovableDevices returns the removable block devices attached to the machine, as reported by lsblk.
+// Removable disks without partitions are returned as is, otherwise each of their partitions is returned.
+// An error is returned if one is encountered.
+func ListRemovableDevices() ([]BlockDevice, error) {
+	cmd := exec.Command("lsblk", "--json", "--bytes", "--output", "NAME,PATH,SIZE,MODEL,FSTYPE,TYPE,MOUNTPOINT,RM")
+	stdout, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseLsblk(stdout)
+}
+
+// parseLsblk parses the JSON output of lsblk and returns the removable devices which may hold disk contents.
+// An error is returned if the output could not be parsed.
+func parseLsblk(b []byte) ([]BlockDevice, error) {
+	var o struct {
+		BlockDevices []lsblkDevice `json:"blockdevices"`
+	}
+	err := json.Unmarshal(b, &o)
+	if err != nil {
+		return nil, err
+	}
+
+	ds := []BlockDevice{}
+	for _, d := range o.BlockDevices {
+		if !d.Removable.bool() || d.Type != "disk" || d.Size.int64() == 0 {
+			continue
+		}
+		sys := isSystemDisk(d)
+		if len(d.Children) == 0 {
+			ds = append(ds, newBlockDevice(d, d, sys))
+			continue
+		}
+		for _, c := range d.Children {
+			ds = append(ds, newBlockDevice(c, d, sys))
+		}
+	}
+
+	return ds, nil
+}
+
+// isSystemDisk returns true if the disk or any of its partitions is mounted at a system mount point.
+func isSystemDisk(d lsblkDevice) bool {
+	for _, m := range systemMountPoints {
+		if d.MountPoint == m {
+			return true
+		}
+	}
+	for _, c := range d.Children {
+		if isSystemDisk(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// newBlockDevice creates a BlockDevice from an lsblk entry, inheriting the model and removable flag from the
+// parent disk as lsblk only reports these for whole disks.
+func newBlockDevice(d, parent lsblkDevice, system bool) BlockDevice {
+	p := d.Path
+	if p == "" {
+		p = "/dev/" + d.Name
+	}
+	return BlockDevice{
+		Name:       d.Name,
+		Path:       p,
+		Size:       d.Size.int64(),
+		Model:      strings.TrimSpace(parent.Model),
+		FSType:     d.FSType,
+		Type:       d.Type,
+		MountPoint: d.MountPoint,
+		Removable:  parent.Removable.bool(),
+		System:     system,
+	}
+}
+
+// formatSize returns the size in bytes as a human readable string.
+func formatSize(b int64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	d, e := int64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		d *= unit
+		e++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(d), "KMGTPE"[e])
+}
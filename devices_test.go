@@ -0,0 +1,86 @@
+package diskplayer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"testing"
+)
+
+func TestParseLsblk(t *testing.T) {
+	b, err := ioutil.ReadFile("./test-fixtures/lsblk.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ds, err := parseLsblk(b)
+	assert.NoError(t, err)
+	assert.Equal(t, []BlockDevice{
+		{
+			Name:      "sda",
+			Path:      "/dev/sda",
+			Size:      1474560,
+			Model:     "TEAC FD-05PUB",
+			FSType:    "vfat",
+			Type:      "disk",
+			Removable: true,
+		},
+		{
+			Name:      "sdc1",
+			Path:      "/dev/sdc1",
+			Size:      8052015104,
+			Model:     "DataTraveler",
+			FSType:    "exfat",
+			Type:      "part",
+			Removable: true,
+		},
+		{
+			Name:       "sdd1",
+			Path:       "/dev/sdd1",
+			Size:       268435456,
+			Model:      "Ultra Fit",
+			FSType:     "vfat",
+			Type:       "part",
+			MountPoint: "/boot/firmware",
+			Removable:  true,
+			System:     true,
+		},
+		{
+			Name:       "sdd2",
+			Path:       "/dev/sdd2",
+			Size:       31741444096,
+			Model:      "Ultra Fit",
+			FSType:     "ext4",
+			Type:       "part",
+			MountPoint: "/",
+			Removable:  true,
+			System:     true,
+		},
+	}, ds)
+}
+
+func TestParseLsblkLegacy(t *testing.T) {
+	b, err := ioutil.ReadFile("./test-fixtures/lsblk_legacy.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ds, err := parseLsblk(b)
+	assert.NoError(t, err)
+	assert.Len(t, ds, 1)
+	assert.Equal(t, "/dev/sda", ds[0].Path)
+	assert.Equal(t, int64(1474560), ds[0].Size)
+	assert.True(t, ds[0].Removable)
+}
+
+func TestParseLsblkError(t *testing.T) {
+	_, err := parseLsblk([]byte("NAME MAJ:MIN RM"))
+	assert.Error(t, err)
+}
+
+func TestBlockDeviceDescription(t *testing.T) {
+	d := BlockDevice{Path: "/dev/sda", Size: 1474560, Model: "TEAC FD-05PUB", FSType: "vfat"}
+	assert.Equal(t, "/dev/sda (1.4 MiB, TEAC FD-05PUB, vfat)", d.Description())
+
+	d = BlockDevice{Path: "/dev/sdb", Size: 512}
+	assert.Equal(t, "/dev/sdb (512 B, no filesystem)", d.Description())
+}
@@ -0,0 +1,90 @@
+package diskplayer
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// DISK_ID_FILENAME is the name of the file holding the identity of a disk, written alongside its contents file.
+const DISK_ID_FILENAME = "diskplayer.id"
+
+// NewDiskId returns a new randomly generated disk identity, in the form of a version 4 UUID.
+// An error is returned if one is encountered.
+func NewDiskId() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16]), nil
+}
+
+// ReadDiskId returns the identity of the disk mounted at the folder, or an empty string if it has none.
+// An error is returned if one is encountered.
+func ReadDiskId(folder string) (string, error) {
+	b, err := ioutil.ReadFile(filepath.Join(folder, DISK_ID_FILENAME))
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return contentsUri(b), nil
+}
+
+// ReadDeviceDiskId returns the identity of the FAT formatted disk whose device path (or image file path) is passed
+// into the function, or an empty string if it has none.
+// An error is returned if one is encountered.
+func ReadDeviceDiskId(device string) (string, error) {
+	b, err := ReadDeviceFile(device, DISK_ID_FILENAME)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return contentsUri(b), nil
+}
+
+// stampDisk writes a new identity to the disk mounted at the folder, unless it already has an identity which is to be
+// kept according to the keep function. The identity of the disk is returned.
+// An error is returned if one is encountered.
+func stampDisk(folder string, keep func(id string) bool) (string, error) {
+	id, err := ReadDiskId(folder)
+	if err != nil || (id != "" && keep(id)) {
+		return id, err
+	}
+
+	id, err = NewDiskId()
+	if err != nil {
+		return "", err
+	}
+	return id, replaceFile(filepath.Join(folder, DISK_ID_FILENAME), []byte(id))
+}
+
+// stampDevice writes a new identity to the FAT formatted disk whose device path (or image file path) is passed into
+// the function, unless it already has an identity which is to be kept according to the keep function. The identity
+// of the disk is returned.
+// An error is returned if one is encountered.
+func stampDevice(device string, keep func(id string) bool) (string, error) {
+	id, err := ReadDeviceDiskId(device)
+	if err != nil || (id != "" && keep(id)) {
+		return id, err
+	}
+
+	id, err = NewDiskId()
+	if err != nil {
+		return "", err
+	}
+	return id, WriteDeviceFile(device, DISK_ID_FILENAME, []byte(id))
+}
+
+// keepDiskId keeps any existing disk identity.
+func keepDiskId(id string) bool {
+	return true
+}
@@ -0,0 +1,77 @@
+package diskplayer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func TestNewDiskId(t *testing.T) {
+	a, err := NewDiskId()
+	assert.NoError(t, err)
+	assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`), a)
+
+	b, err := NewDiskId()
+	assert.NoError(t, err)
+	assert.NotEqual(t, a, b)
+}
+
+func TestStampDisk(t *testing.T) {
+	d, err := ioutil.TempDir("", "diskplayer_stamp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+
+	id, err := ReadDiskId(d)
+	assert.NoError(t, err)
+	assert.Empty(t, id)
+
+	a, err := stampDisk(d, keepDiskId)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, a)
+	id, err = ReadDiskId(d)
+	assert.NoError(t, err)
+	assert.Equal(t, a, id)
+
+	id, err = stampDisk(d, keepDiskId)
+	assert.NoError(t, err)
+	assert.Equal(t, a, id)
+
+	b, err := stampDisk(d, func(id string) bool { return false })
+	assert.NoError(t, err)
+	assert.NotEqual(t, a, b)
+	id, err = ReadDiskId(d)
+	assert.NoError(t, err)
+	assert.Equal(t, b, id)
+
+	_, err = stampDisk(filepath.Join(d, "not_a_real_path"), keepDiskId)
+	assert.Error(t, err)
+}
+
+func TestStampDevice(t *testing.T) {
+	p := formattedImage(t)
+	defer os.Remove(p)
+
+	id, err := ReadDeviceDiskId(p)
+	assert.NoError(t, err)
+	assert.Empty(t, id)
+
+	a, err := stampDevice(p, keepDiskId)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, a)
+	id, err = ReadDeviceDiskId(p)
+	assert.NoError(t, err)
+	assert.Equal(t, a, id)
+
+	id, err = stampDevice(p, keepDiskId)
+	assert.NoError(t, err)
+	assert.Equal(t, a, id)
+
+	b, err := stampDevice(p, func(id string) bool { return false })
+	assert.NoError(t, err)
+	assert.NotEqual(t, a, b)
+}
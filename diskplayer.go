@@ -1,15 +1,20 @@
 package diskplayer
 
 import (
-	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
+	"github.com/dinofizz/diskplayer/fat"
 	"github.com/zmb3/spotify"
+	"io"
+	"io/ioutil"
+	"log"
 	"os"
+	"path/filepath"
 )
 
 // PlayPath will play an album or playlist by reading a Spotify URI from a file whose filepath is passed into the
-// function.
+// function. The identity of the disk, read from alongside the file, is logged if it has one.
 // An error is returned if one is encountered.
 func PlayPath(c Client, p string) error {
 	f, err := os.Open(p)
@@ -18,20 +23,82 @@ func PlayPath(c Client, p string) error {
 	}
 	defer f.Close()
 
-	s := bufio.NewScanner(f)
-	var l string
-	if s.Scan() {
-		l = s.Text()
+	id, err := ReadDiskId(filepath.Dir(p))
+	logDiskId(id, err, p)
+
+	return playContents(c, f, p)
+}
+
+// PlayDevice will play an album or playlist by reading a Spotify URI from the contents file on the FAT formatted disk
+// whose device path (or image file path) is passed into the function. The disk does not need to be mounted.
+// The name of the contents file is specified in the diskplayer.yaml configuration file under the recorder.filename
+// field. The identity of the disk is logged if it has one.
+// An error is returned if one is encountered.
+func PlayDevice(c Client, d string) error {
+	b, err := ReadDeviceFile(d, ConfigValue(RECORD_FILENAME))
+	if err != nil {
+		return err
+	}
+
+	id, err := ReadDeviceDiskId(d)
+	logDiskId(id, err, d)
+
+	return playContents(c, bytes.NewReader(b), d)
+}
+
+// logDiskId logs the identity of the disk read from the source, or the error encountered reading it. Disks recorded
+// before disk identities were introduced have none, and are still played.
+func logDiskId(id string, err error, src string) {
+	if err != nil {
+		log.Printf("Unable to read disk identity from %s: %s", src, err)
+	} else if id == "" {
+		log.Printf("Playing disk from %s, which has no disk identity", src)
+	} else {
+		log.Printf("Playing disk %s from %s", id, src)
+	}
+}
+
+// ReadDeviceFile returns the contents of the named file in the root directory of the FAT formatted disk whose device
+// path (or image file path) is passed into the function.
+// An error is returned if one is encountered.
+func ReadDeviceFile(d, name string) ([]byte, error) {
+	f, err := os.Open(d)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	fs, err := fat.Open(f)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read disk %s: %s", d, err)
+	}
+
+	return fs.ReadFile(name)
+}
+
+// playContents will play the disk contents, which are either a Spotify URI on the first line or a JSON object as
+// described for Contents. The source of the contents is used in error messages.
+// An error is returned if one is encountered.
+func playContents(c Client, r io.Reader, src string) error {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
 	}
 
-	if l == "" {
-		return fmt.Errorf("unable to read line from path: %s", p)
+	if len(bytes.TrimSpace(b)) == 0 {
+		return fmt.Errorf("unable to read line from path: %s", src)
 	}
 
-	return PlayUri(c, l)
+	ct, err := ParseContents(b)
+	if err != nil {
+		return fmt.Errorf("unable to read contents from %s: %s", src, err)
+	}
+
+	return PlayContents(c, ct)
 }
 
-// PlayURI will play the album or playlist Spotify URI that is passed in to the function.
+// PlayURI will play the album, playlist, artist, show or track Spotify URI that is passed in to the function. A track is
+// played on its own, while the others are played as the playback context.
 // An error is returned if one is encountered.
 func PlayUri(c Client, u string) error {
 	if u == "" {
@@ -39,7 +106,35 @@ func PlayUri(c Client, u string) error {
 	}
 
 	spotifyUri := spotify.URI(u)
+	o := &spotify.PlayOptions{}
+	if trackUri(spotifyUri) {
+		o.URIs = []spotify.URI{spotifyUri}
+	} else {
+		o.PlaybackContext = &spotifyUri
+	}
+
+	return play(c, o)
+}
+
+// PlayTracks will play the list of Spotify track URIs that is passed in to the function, in order.
+// An error is returned if one is encountered.
+func PlayTracks(c Client, ts []string) error {
+	if len(ts) == 0 {
+		return errors.New("at least one Spotify track URI is required")
+	}
+
+	o := &spotify.PlayOptions{URIs: make([]spotify.URI, len(ts))}
+	for i, t := range ts {
+		o.URIs[i] = spotify.URI(t)
+	}
 
+	return play(c, o)
+}
+
+// play will start playback with the options on the Spotify client identified by the spotify.device_name field in the
+// diskplayer.yaml configuration file, first transferring playback to it from any other active device.
+// An error is returned if one is encountered.
+func play(c Client, o *spotify.PlayOptions) error {
 	n := ConfigValue(SPOTIFY_DEVICE_NAME)
 	ds, err := c.PlayerDevices()
 	if err != nil {
@@ -64,11 +159,7 @@ func PlayUri(c Client, u string) error {
 		}
 	}
 
-	o := &spotify.PlayOptions{
-		DeviceID:        &playerID,
-		PlaybackContext: &spotifyUri,
-	}
-
+	o.DeviceID = &playerID
 	return c.PlayOpt(o)
 }
 
@@ -35,6 +35,20 @@ func TestPlayUriSuccess(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestPlayUriTrack(t *testing.T) {
+	viper.Set("spotify.device_name", "test_device_name")
+
+	m := new(mocks.Client)
+	m.On("PlayerDevices").Return([]spotify.PlayerDevice{{ID: "TEST_ID", Name: "test_device_name"}}, nil)
+	m.On("PlayOpt", mock.MatchedBy(func(o *spotify.PlayOptions) bool {
+		return o.PlaybackContext == nil && len(o.URIs) == 1 && o.URIs[0] == "spotify:track:4uLU6hMCjMI75M1A2tKUQC"
+	})).Return(nil)
+
+	err := PlayUri(m, "spotify:track:4uLU6hMCjMI75M1A2tKUQC")
+	assert.NoError(t, err)
+	m.AssertExpectations(t)
+}
+
 func TestPlayPathSuccess(t *testing.T) {
 	viper.Set("spotify.device_name", "test_device_name")
 
@@ -340,3 +354,52 @@ func TestPauseError(t *testing.T) {
 	err := Pause(m)
 	assert.EqualError(t, err, e)
 }
+
+func TestPlayDeviceSuccess(t *testing.T) {
+	viper.Set("spotify.device_name", "test_device_name")
+	viper.Set("recorder.filename", "diskplayer.contents")
+
+	m := new(mocks.Client)
+
+	d := spotify.PlayerDevice{
+		ID:     "TEST_ID",
+		Active: false,
+		Name:   "test_device_name",
+	}
+
+	ds := []spotify.PlayerDevice{d}
+
+	u := spotify.URI("spotify:album:3oyu7chRauu88JYPYfFB55")
+	m.On("PlayerDevices").Return(ds, nil)
+	m.On("PlayOpt", mock.MatchedBy(func(o *spotify.PlayOptions) bool {
+		return *o.PlaybackContext == u
+	})).Return(nil)
+
+	err := PlayDevice(m, "./test-fixtures/diskplayer.img")
+	assert.NoError(t, err)
+	m.AssertExpectations(t)
+}
+
+func TestPlayDeviceFileNotFound(t *testing.T) {
+	viper.Set("recorder.filename", "not_a_real_file.contents")
+
+	m := new(mocks.Client)
+	err := PlayDevice(m, "./test-fixtures/diskplayer.img")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestPlayDeviceNotFat(t *testing.T) {
+	viper.Set("recorder.filename", "diskplayer.contents")
+
+	m := new(mocks.Client)
+	err := PlayDevice(m, "./test-fixtures/diskplayer.contents")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to read disk ./test-fixtures/diskplayer.contents")
+}
+
+func TestPlayDeviceInvalidPath(t *testing.T) {
+	m := new(mocks.Client)
+	err := PlayDevice(m, "./test-fixtures/not_a_real_path")
+	_, ok := err.(*os.PathError)
+	assert.True(t, ok)
+}
@@ -0,0 +1,186 @@
+// Package fat implements the parts of the FAT12 and FAT16 filesystems needed by diskplayer: formatting blank media,
+// and reading and writing files in the root directory of a disk image or block device.
+package fat
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	sectorSize      = 512
+	dirEntrySize    = 32
+	maxFat12Cluster = 4085
+	maxFat16Cluster = 65525
+	attrVolumeID    = 0x08
+)
+
+// geometry describes the layout of a FAT filesystem.
+type geometry struct {
+	totalSectors      uint32
+	sectorsPerCluster uint8
+	reservedSectors   uint16
+	numFats           uint8
+	rootEntries       uint16
+	sectorsPerFat     uint16
+	media             uint8
+	sectorsPerTrack   uint16
+	heads             uint16
+	fat16             bool
+}
+
+// floppyGeometries are the standard layouts used for 3.5" floppy disks, keyed by their size in bytes.
+var floppyGeometries = map[int64]geometry{
+	737280:  {totalSectors: 1440, sectorsPerCluster: 2, reservedSectors: 1, numFats: 2, rootEntries: 112, sectorsPerFat: 3, media: 0xF9, sectorsPerTrack: 9, heads: 2},
+	1474560: {totalSectors: 2880, sectorsPerCluster: 1, reservedSectors: 1, numFats: 2, rootEntries: 224, sectorsPerFat: 9, media: 0xF0, sectorsPerTrack: 18, heads: 2},
+	2949120: {totalSectors: 5760, sectorsPerCluster: 2, reservedSectors: 1, numFats: 2, rootEntries: 240, sectorsPerFat: 9, media: 0xF0, sectorsPerTrack: 36, heads: 2},
+}
+
+// Format creates an empty FAT filesystem with the given volume label on the device or image of the given size.
+// Standard floppy disk sizes are given their usual DOS layout, other media is formatted as FAT12 or FAT16 depending on
+// its size. Media larger than 2 GiB is not supported.
+// An error is returned if one is encountered.
+func Format(w io.WriterAt, size int64, label string) error {
+	g, err := newGeometry(size)
+	if err != nil {
+		return err
+	}
+
+	bs := bootSector(g, VolumeLabel(label), uint32(time.Now().UnixNano()))
+	_, err = w.WriteAt(bs, 0)
+	if err != nil {
+		return err
+	}
+
+	fat := make([]byte, int(g.sectorsPerFat)*sectorSize)
+	if g.fat16 {
+		copy(fat, []byte{g.media, 0xFF, 0xFF, 0xFF})
+	} else {
+		copy(fat, []byte{g.media, 0xFF, 0xFF})
+	}
+	for i := 0; i < int(g.numFats); i++ {
+		off := int64(g.reservedSectors)*sectorSize + int64(i)*int64(len(fat))
+		_, err = w.WriteAt(fat, off)
+		if err != nil {
+			return err
+		}
+	}
+
+	root := make([]byte, int(g.rootEntries)*dirEntrySize)
+	copy(root, VolumeLabel(label))
+	root[11] = attrVolumeID
+	off := int64(g.reservedSectors)*sectorSize + int64(g.numFats)*int64(len(fat))
+	_, err = w.WriteAt(root, off)
+	return err
+}
+
+// newGeometry returns the filesystem layout for media of the given size.
+func newGeometry(size int64) (geometry, error) {
+	if g, ok := floppyGeometries[size]; ok {
+		return g, nil
+	}
+
+	total := size / sectorSize
+	if total < 64 {
+		return geometry{}, errors.New("media is too small to be formatted")
+	}
+	if total > 0xFFFFFFFF {
+		return geometry{}, errors.New("media is too large to be formatted as FAT12 or FAT16")
+	}
+
+	g := geometry{
+		totalSectors:    uint32(total),
+		reservedSectors: 1,
+		numFats:         2,
+		rootEntries:     512,
+		media:           0xF8,
+		sectorsPerTrack: 63,
+		heads:           255,
+	}
+	rootSectors := int64(g.rootEntries) * dirEntrySize / sectorSize
+
+	for spc := int64(1); spc <= 64; spc *= 2 {
+		for _, fat16 := range []bool{false, true} {
+			var spf, clusters int64
+			for i := 0; i < 8; i++ {
+				clusters = (total - int64(g.reservedSectors) - int64(g.numFats)*spf - rootSectors) / spc
+				var fatBytes int64
+				if fat16 {
+					fatBytes = (clusters + 2) * 2
+				} else {
+					fatBytes = ((clusters+2)*3 + 1) / 2
+				}
+				spf = (fatBytes + sectorSize - 1) / sectorSize
+			}
+			clusters = (total - int64(g.reservedSectors) - int64(g.numFats)*spf - rootSectors) / spc
+
+			if (!fat16 && clusters > 0 && clusters < maxFat12Cluster) ||
+				(fat16 && clusters >= maxFat12Cluster && clusters < maxFat16Cluster) {
+				g.sectorsPerCluster = uint8(spc)
+				g.sectorsPerFat = uint16(spf)
+				g.fat16 = fat16
+				return g, nil
+			}
+		}
+	}
+
+	return geometry{}, errors.New("media is too large to be formatted as FAT12 or FAT16")
+}
+
+// bootSector returns the boot sector containing the BIOS parameter block describing the filesystem.
+func bootSector(g geometry, label string, serial uint32) []byte {
+	b := make([]byte, sectorSize)
+	copy(b, []byte{0xEB, 0x3C, 0x90})
+	copy(b[3:], "MSWIN4.1")
+	binary.LittleEndian.PutUint16(b[11:], sectorSize)
+	b[13] = g.sectorsPerCluster
+	binary.LittleEndian.PutUint16(b[14:], g.reservedSectors)
+	b[16] = g.numFats
+	binary.LittleEndian.PutUint16(b[17:], g.rootEntries)
+	if g.totalSectors < 0x10000 {
+		binary.LittleEndian.PutUint16(b[19:], uint16(g.totalSectors))
+	} else {
+		binary.LittleEndian.PutUint32(b[32:], g.totalSectors)
+	}
+	b[21] = g.media
+	binary.LittleEndian.PutUint16(b[22:], g.sectorsPerFat)
+	binary.LittleEndian.PutUint16(b[24:], g.sectorsPerTrack)
+	binary.LittleEndian.PutUint16(b[26:], g.heads)
+	if g.media == 0xF8 {
+		b[36] = 0x80
+	}
+	b[38] = 0x29
+	binary.LittleEndian.PutUint32(b[39:], serial)
+	copy(b[43:], label)
+	if g.fat16 {
+		copy(b[54:], "FAT16   ")
+	} else {
+		copy(b[54:], "FAT12   ")
+	}
+	b[510], b[511] = 0x55, 0xAA
+	return b
+}
+
+// VolumeLabel returns the name converted to an 11 character FAT volume label. Letters are converted to upper case,
+// characters which are not permitted in a label are dropped and the result is truncated and padded with spaces.
+// An empty name results in the label "NO NAME".
+func VolumeLabel(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(name) {
+		if b.Len() == 11 {
+			break
+		}
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || strings.ContainsRune(" !#$%&'()-@^_`{}~", r) {
+			b.WriteRune(r)
+		}
+	}
+
+	l := strings.TrimSpace(b.String())
+	if l == "" {
+		l = "NO NAME"
+	}
+	return l + strings.Repeat(" ", 11-len(l))
+}
@@ -0,0 +1,110 @@
+package fat
+
+import (
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+// image is an in-memory disk image.
+type image []byte
+
+func (i image) ReadAt(b []byte, off int64) (int, error) {
+	return copy(b, i[off:]), nil
+}
+
+func (i image) WriteAt(b []byte, off int64) (int, error) {
+	return copy(i[off:], b), nil
+}
+
+func TestFormatFloppy(t *testing.T) {
+	img := make(image, 1474560)
+	err := Format(img, int64(len(img)), "Kind of Blue")
+	assert.NoError(t, err)
+
+	assert.Equal(t, []byte{0xEB, 0x3C, 0x90}, []byte(img[0:3]))
+	assert.Equal(t, uint16(512), binary.LittleEndian.Uint16(img[11:]))
+	assert.Equal(t, uint8(1), img[13])
+	assert.Equal(t, uint16(224), binary.LittleEndian.Uint16(img[17:]))
+	assert.Equal(t, uint16(2880), binary.LittleEndian.Uint16(img[19:]))
+	assert.Equal(t, uint8(0xF0), img[21])
+	assert.Equal(t, uint16(9), binary.LittleEndian.Uint16(img[22:]))
+	assert.Equal(t, "KIND OF BLU", string(img[43:54]))
+	assert.Equal(t, "FAT12   ", string(img[54:62]))
+	assert.Equal(t, []byte{0x55, 0xAA}, []byte(img[510:512]))
+
+	fat1 := img[512 : 512+9*512]
+	fat2 := img[512+9*512 : 512+18*512]
+	assert.Equal(t, []byte{0xF0, 0xFF, 0xFF, 0x00}, []byte(fat1[0:4]))
+	assert.Equal(t, fat1, fat2)
+
+	root := img[19*512:]
+	assert.Equal(t, "KIND OF BLU", string(root[0:11]))
+	assert.Equal(t, uint8(attrVolumeID), root[11])
+}
+
+var geometryTests = []struct {
+	name     string
+	size     int64
+	fat16    bool
+	spc      uint8
+	clusters int64
+	e        string
+}{
+	{"720K floppy", 737280, false, 2, 713, ""},
+	{"1.44M floppy", 1474560, false, 1, 2847, ""},
+	{"4M card", 4 << 20, true, 1, 8095, ""},
+	{"16M card", 16 << 20, true, 1, 32481, ""},
+	{"1G stick", 1 << 30, true, 32, 65518, ""},
+	{"2G stick", 2000000000, true, 64, 61027, ""},
+	{"2G exactly", 2 << 30, false, 0, 0, "media is too large to be formatted as FAT12 or FAT16"},
+	{"4G stick", 4 << 30, false, 0, 0, "media is too large to be formatted as FAT12 or FAT16"},
+	{"tiny", 8192, false, 0, 0, "media is too small to be formatted"},
+}
+
+func TestNewGeometry(t *testing.T) {
+	for _, tt := range geometryTests {
+		t.Run(tt.name, func(t *testing.T) {
+			g, err := newGeometry(tt.size)
+			if tt.e != "" {
+				assert.EqualError(t, err, tt.e)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.fat16, g.fat16)
+			assert.Equal(t, tt.spc, g.sectorsPerCluster)
+
+			rootSectors := int64(g.rootEntries) * dirEntrySize / sectorSize
+			data := int64(g.totalSectors) - int64(g.reservedSectors) - int64(g.numFats)*int64(g.sectorsPerFat) - rootSectors
+			clusters := data / int64(g.sectorsPerCluster)
+			assert.Equal(t, tt.clusters, clusters)
+
+			var fatBytes int64
+			if g.fat16 {
+				fatBytes = (clusters + 2) * 2
+			} else {
+				fatBytes = ((clusters+2)*3 + 1) / 2
+			}
+			assert.True(t, int64(g.sectorsPerFat)*sectorSize >= fatBytes, "FAT is too small for the cluster count")
+		})
+	}
+}
+
+var volumeLabelTests = []struct {
+	in  string
+	out string
+}{
+	{"Kind of Blue", "KIND OF BLU"},
+	{"AM", "AM         "},
+	{"Sigur Rós: ( )", "SIGUR RS ( "},
+	{"...", "NO NAME    "},
+	{"", "NO NAME    "},
+}
+
+func TestVolumeLabel(t *testing.T) {
+	for _, tt := range volumeLabelTests {
+		t.Run(tt.in, func(t *testing.T) {
+			assert.Equal(t, tt.out, VolumeLabel(tt.in))
+		})
+	}
+}
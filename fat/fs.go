@@ -0,0 +1,355 @@
+package fat
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	attrReadOnly  = 0x01
+	attrDirectory = 0x10
+	attrLongName  = 0x0F
+
+	entryFree    = 0x00
+	entryDeleted = 0xE5
+	lastLongName = 0x40
+)
+
+// FS is a FAT12 or FAT16 filesystem on a disk image or block device. Only files in the root directory are supported.
+type FS struct {
+	dev               io.ReaderAt
+	bytesPerSector    int64
+	sectorsPerCluster int64
+	numFats           int64
+	rootEntries       int64
+	sectorsPerFat     int64
+	fat16             bool
+	clusters          uint32
+	fatOffset         int64
+	rootOffset        int64
+	dataOffset        int64
+	label             string
+	serial            uint32
+	fat               []byte
+}
+
+// dirEntry is a file in the root directory, identified by its long name if it has one, otherwise its short name.
+type dirEntry struct {
+	name      string
+	shortName [11]byte
+	attr      byte
+	cluster   uint32
+	size      uint32
+	index     int
+	first     int
+}
+
+// Open reads the boot sector and file allocation table of the FAT filesystem on the disk image or block device.
+// An error is returned if the device does not contain a FAT12 or FAT16 filesystem, or if one is encountered while
+// reading.
+func Open(dev io.ReaderAt) (*FS, error) {
+	b := make([]byte, sectorSize)
+	_, err := dev.ReadAt(b, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	if b[510] != 0x55 || b[511] != 0xAA {
+		return nil, errors.New("no FAT boot sector signature found")
+	}
+
+	fs := &FS{
+		dev:               dev,
+		bytesPerSector:    int64(binary.LittleEndian.Uint16(b[11:])),
+		sectorsPerCluster: int64(b[13]),
+		numFats:           int64(b[16]),
+		rootEntries:       int64(binary.LittleEndian.Uint16(b[17:])),
+		sectorsPerFat:     int64(binary.LittleEndian.Uint16(b[22:])),
+	}
+	reserved := int64(binary.LittleEndian.Uint16(b[14:]))
+	total := int64(binary.LittleEndian.Uint16(b[19:]))
+	if total == 0 {
+		total = int64(binary.LittleEndian.Uint32(b[32:]))
+	}
+
+	switch fs.bytesPerSector {
+	case 512, 1024, 2048, 4096:
+	default:
+		return nil, fmt.Errorf("unsupported sector size %d", fs.bytesPerSector)
+	}
+	if fs.sectorsPerCluster == 0 || fs.numFats == 0 || reserved == 0 {
+		return nil, errors.New("invalid FAT BIOS parameter block")
+	}
+	if fs.sectorsPerFat == 0 {
+		return nil, errors.New("FAT32 filesystems are not supported")
+	}
+
+	rootSectors := (fs.rootEntries*dirEntrySize + fs.bytesPerSector - 1) / fs.bytesPerSector
+	fs.fatOffset = reserved * fs.bytesPerSector
+	fs.rootOffset = fs.fatOffset + fs.numFats*fs.sectorsPerFat*fs.bytesPerSector
+	fs.dataOffset = fs.rootOffset + rootSectors*fs.bytesPerSector
+
+	data := total - reserved - fs.numFats*fs.sectorsPerFat - rootSectors
+	if data <= 0 {
+		return nil, errors.New("invalid FAT BIOS parameter block")
+	}
+	fs.clusters = uint32(data / fs.sectorsPerCluster)
+	fs.fat16 = fs.clusters >= maxFat12Cluster
+	if fs.clusters >= maxFat16Cluster {
+		return nil, errors.New("FAT32 filesystems are not supported")
+	}
+
+	need := int64(fs.clusters+2) * 2
+	if !fs.fat16 {
+		need = (int64(fs.clusters+2)*3 + 1) / 2
+	}
+	if fs.sectorsPerFat*fs.bytesPerSector < need {
+		return nil, errors.New("FAT is too small for the number of clusters")
+	}
+
+	if b[38] == 0x29 {
+		fs.serial = binary.LittleEndian.Uint32(b[39:])
+		fs.label = strings.TrimRight(string(b[43:54]), " ")
+	}
+
+	fs.fat = make([]byte, fs.sectorsPerFat*fs.bytesPerSector)
+	_, err = dev.ReadAt(fs.fat, fs.fatOffset)
+	if err != nil {
+		return nil, err
+	}
+
+	return fs, nil
+}
+
+// Label returns the volume label of the filesystem.
+func (fs *FS) Label() string {
+	return fs.label
+}
+
+// Serial returns the volume serial number of the filesystem.
+func (fs *FS) Serial() uint32 {
+	return fs.serial
+}
+
+// ReadFile returns the contents of the file in the root directory with the given name. Names are matched against
+// both the long and short (8.3) file names, ignoring case.
+// An error satisfying os.IsNotExist is returned if the file is not found, or any other error encountered.
+func (fs *FS) ReadFile(name string) ([]byte, error) {
+	es, err := fs.readRoot()
+	if err != nil {
+		return nil, err
+	}
+
+	e := findEntry(es, name)
+	if e == nil {
+		return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrNotExist}
+	}
+	if e.attr&attrDirectory != 0 {
+		return nil, fmt.Errorf("%s is a directory", name)
+	}
+
+	cs, err := fs.chain(e.cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	cl := fs.sectorsPerCluster * fs.bytesPerSector
+	if int64(e.size) > int64(len(cs))*cl {
+		return nil, fmt.Errorf("%s is larger than its cluster chain", name)
+	}
+
+	b := make([]byte, e.size)
+	for i, c := range cs {
+		off := int64(i) * cl
+		if off >= int64(len(b)) {
+			break
+		}
+		end := off + cl
+		if end > int64(len(b)) {
+			end = int64(len(b))
+		}
+		_, err = fs.dev.ReadAt(b[off:end], fs.clusterOffset(c))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return b, nil
+}
+
+// readRoot returns the files in the root directory.
+func (fs *FS) readRoot() ([]dirEntry, error) {
+	b := make([]byte, fs.rootEntries*dirEntrySize)
+	_, err := fs.dev.ReadAt(b, fs.rootOffset)
+	if err != nil {
+		return nil, err
+	}
+	return parseDir(b), nil
+}
+
+// parseDir parses the raw directory entries, combining long file name entries with the short entry they belong to.
+// Volume labels and deleted entries are skipped.
+func parseDir(b []byte) []dirEntry {
+	var es []dirEntry
+	var lfn []uint16
+	var sum byte
+	first := -1
+
+	for i := 0; i*dirEntrySize < len(b); i++ {
+		d := b[i*dirEntrySize : (i+1)*dirEntrySize]
+		if d[0] == entryFree {
+			break
+		}
+		if d[0] == entryDeleted {
+			lfn, first = nil, -1
+			continue
+		}
+
+		if d[11] == attrLongName {
+			if d[0]&lastLongName != 0 {
+				lfn, sum, first = nil, d[13], i
+			}
+			if first == -1 || d[13] != sum {
+				lfn, first = nil, -1
+				continue
+			}
+			lfn = append(longNameChars(d), lfn...)
+			continue
+		}
+
+		if d[11]&attrVolumeID != 0 {
+			lfn, first = nil, -1
+			continue
+		}
+
+		e := dirEntry{
+			attr:    d[11],
+			cluster: uint32(binary.LittleEndian.Uint16(d[26:])),
+			size:    binary.LittleEndian.Uint32(d[28:]),
+			index:   i,
+			first:   i,
+		}
+		copy(e.shortName[:], d[:11])
+		e.name = displayShortName(e.shortName, d[12])
+		if lfn != nil && checksum(e.shortName) == sum {
+			e.name = decodeLongName(lfn)
+			e.first = first
+		}
+		es = append(es, e)
+		lfn, first = nil, -1
+	}
+
+	return es
+}
+
+// findEntry returns the entry whose long or short name matches the name, ignoring case, or nil if none is found.
+func findEntry(es []dirEntry, name string) *dirEntry {
+	for i, e := range es {
+		if strings.EqualFold(e.name, name) || strings.EqualFold(displayShortName(e.shortName, 0), name) {
+			return &es[i]
+		}
+	}
+	return nil
+}
+
+// longNameChars returns the 13 UTF-16 characters stored in a long file name entry.
+func longNameChars(d []byte) []uint16 {
+	cs := make([]uint16, 0, 13)
+	for _, r := range [][2]int{{1, 11}, {14, 26}, {28, 32}} {
+		for o := r[0]; o < r[1]; o += 2 {
+			cs = append(cs, binary.LittleEndian.Uint16(d[o:]))
+		}
+	}
+	return cs
+}
+
+// decodeLongName decodes the UTF-16 long file name, which is terminated by a null character or padding.
+func decodeLongName(cs []uint16) string {
+	for i, c := range cs {
+		if c == 0x0000 || c == 0xFFFF {
+			cs = cs[:i]
+			break
+		}
+	}
+	return string(utf16.Decode(cs))
+}
+
+// displayShortName returns the 8.3 name as it would be displayed, i.e. "README.TXT". The flags are those set by
+// Windows NT to indicate a lower case base name (0x08) or extension (0x10).
+func displayShortName(n [11]byte, flags byte) string {
+	b := strings.TrimRight(string(n[:8]), " ")
+	x := strings.TrimRight(string(n[8:]), " ")
+	if b != "" && b[0] == 0x05 {
+		b = "\xe5" + b[1:]
+	}
+	if flags&0x08 != 0 {
+		b = strings.ToLower(b)
+	}
+	if flags&0x10 != 0 {
+		x = strings.ToLower(x)
+	}
+	if x == "" {
+		return b
+	}
+	return b + "." + x
+}
+
+// checksum returns the checksum of the short name which is stored in each of its long file name entries.
+func checksum(n [11]byte) byte {
+	var s byte
+	for _, c := range n {
+		s = (s>>1 | s<<7) + c
+	}
+	return s
+}
+
+// chain returns the clusters belonging to the file starting at the given cluster.
+// An error is returned if the chain is broken or loops.
+func (fs *FS) chain(start uint32) ([]uint32, error) {
+	var cs []uint32
+	if start == 0 {
+		return cs, nil
+	}
+
+	for c := start; !fs.isEnd(c); c = fs.entry(c) {
+		if c < 2 || c >= fs.clusters+2 {
+			return nil, fmt.Errorf("invalid cluster %d in FAT chain", c)
+		}
+		if uint32(len(cs)) > fs.clusters {
+			return nil, errors.New("loop detected in FAT chain")
+		}
+		cs = append(cs, c)
+	}
+
+	return cs, nil
+}
+
+// entry returns the value of the FAT entry for cluster n.
+func (fs *FS) entry(n uint32) uint32 {
+	if fs.fat16 {
+		return uint32(binary.LittleEndian.Uint16(fs.fat[n*2:]))
+	}
+	v := uint32(binary.LittleEndian.Uint16(fs.fat[n+n/2:]))
+	if n%2 == 1 {
+		return v >> 4
+	}
+	return v & 0xFFF
+}
+
+// isEnd returns true if the FAT entry value marks the end of a cluster chain.
+func (fs *FS) isEnd(v uint32) bool {
+	if fs.fat16 {
+		return v >= 0xFFF8
+	}
+	return v >= 0xFF8
+}
+
+// clusterOffset returns the byte offset of the start of cluster n.
+func (fs *FS) clusterOffset(n uint32) int64 {
+	return fs.dataOffset + int64(n-2)*fs.sectorsPerCluster*fs.bytesPerSector
+}
@@ -0,0 +1,223 @@
+package fat
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"testing"
+	"unicode/utf16"
+)
+
+// formatImage returns a newly formatted in-memory disk image of the given size.
+func formatImage(t *testing.T, size int64) image {
+	img := make(image, size)
+	err := Format(img, size, "TEST")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return img
+}
+
+// putFile writes a file into the root directory of the image at the given directory slot, using the provided
+// clusters in order. If long is not empty, long file name entries are written before the short entry.
+// The number of directory slots used is returned.
+func putFile(t *testing.T, img image, slot int, long, short string, data []byte, clusters []uint32) int {
+	fs, err := Open(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sn [11]byte
+	copy(sn[:], short)
+
+	var es [][]byte
+	if long != "" {
+		cs := utf16.Encode([]rune(long))
+		cs = append(cs, 0)
+		for len(cs)%13 != 0 {
+			cs = append(cs, 0xFFFF)
+		}
+		n := len(cs) / 13
+		for i := n; i >= 1; i-- {
+			d := make([]byte, dirEntrySize)
+			d[0] = byte(i)
+			if i == n {
+				d[0] |= lastLongName
+			}
+			d[11] = attrLongName
+			d[13] = checksum(sn)
+			part := cs[(i-1)*13 : i*13]
+			j := 0
+			for _, r := range [][2]int{{1, 11}, {14, 26}, {28, 32}} {
+				for o := r[0]; o < r[1]; o += 2 {
+					binary.LittleEndian.PutUint16(d[o:], part[j])
+					j++
+				}
+			}
+			es = append(es, d)
+		}
+	}
+
+	d := make([]byte, dirEntrySize)
+	copy(d, sn[:])
+	d[11] = 0x20
+	if len(clusters) > 0 {
+		binary.LittleEndian.PutUint16(d[26:], uint16(clusters[0]))
+	}
+	binary.LittleEndian.PutUint32(d[28:], uint32(len(data)))
+	es = append(es, d)
+
+	for i, e := range es {
+		copy(img[fs.rootOffset+int64(slot+i)*dirEntrySize:], e)
+	}
+
+	cl := fs.sectorsPerCluster * fs.bytesPerSector
+	for i, c := range clusters {
+		end := int64(i+1) * cl
+		if end > int64(len(data)) {
+			end = int64(len(data))
+		}
+		copy(img[fs.clusterOffset(c):], data[int64(i)*cl:end])
+
+		next := uint32(0xFFFF)
+		if i+1 < len(clusters) {
+			next = clusters[i+1]
+		}
+		fs.setEntry(c, next)
+	}
+	err = fs.writeFat(img)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return len(es)
+}
+
+func TestOpen(t *testing.T) {
+	fs, err := Open(formatImage(t, 1474560))
+	assert.NoError(t, err)
+	assert.False(t, fs.fat16)
+	assert.Equal(t, uint32(2847), fs.clusters)
+	assert.Equal(t, "TEST", fs.Label())
+	assert.Equal(t, int64(19*512), fs.rootOffset)
+	assert.Equal(t, int64(33*512), fs.dataOffset)
+
+	fs, err = Open(formatImage(t, 16<<20))
+	assert.NoError(t, err)
+	assert.True(t, fs.fat16)
+}
+
+func TestOpenNotFat(t *testing.T) {
+	_, err := Open(make(image, 4096))
+	assert.EqualError(t, err, "no FAT boot sector signature found")
+}
+
+func TestReadFileLongName(t *testing.T) {
+	img := formatImage(t, 1474560)
+	putFile(t, img, 1, "diskplayer.contents", "DISKPL~1CON", []byte("spotify:album:3oyu7chRauu88JYPYfFB55"), []uint32{2})
+
+	fs, err := Open(img)
+	assert.NoError(t, err)
+
+	b, err := fs.ReadFile("diskplayer.contents")
+	assert.NoError(t, err)
+	assert.Equal(t, "spotify:album:3oyu7chRauu88JYPYfFB55", string(b))
+
+	b, err = fs.ReadFile("DISKPLAYER.CONTENTS")
+	assert.NoError(t, err)
+	assert.Equal(t, "spotify:album:3oyu7chRauu88JYPYfFB55", string(b))
+
+	b, err = fs.ReadFile("diskpl~1.con")
+	assert.NoError(t, err)
+	assert.Equal(t, "spotify:album:3oyu7chRauu88JYPYfFB55", string(b))
+}
+
+func TestReadFileShortName(t *testing.T) {
+	img := formatImage(t, 737280)
+	putFile(t, img, 1, "", "README  TXT", []byte("hello"), []uint32{2})
+
+	fs, err := Open(img)
+	assert.NoError(t, err)
+
+	b, err := fs.ReadFile("readme.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(b))
+}
+
+func TestReadFileFragmented(t *testing.T) {
+	for _, size := range []int64{1474560, 16 << 20} {
+		img := formatImage(t, size)
+		data := bytes.Repeat([]byte("0123456789abcdef"), 200)
+		putFile(t, img, 1, "", "FIRST   TXT", []byte("first"), []uint32{2})
+		putFile(t, img, 2, "fragmented file.bin", "FRAGME~1BIN", data, []uint32{9, 3, 12, 4, 7, 5, 6})
+
+		fs, err := Open(img)
+		assert.NoError(t, err)
+
+		b, err := fs.ReadFile("fragmented file.bin")
+		assert.NoError(t, err)
+		assert.Equal(t, data, b)
+
+		b, err = fs.ReadFile("first.txt")
+		assert.NoError(t, err)
+		assert.Equal(t, "first", string(b))
+	}
+}
+
+func TestReadFileEmpty(t *testing.T) {
+	img := formatImage(t, 1474560)
+	putFile(t, img, 1, "", "EMPTY   TXT", nil, nil)
+
+	fs, err := Open(img)
+	assert.NoError(t, err)
+
+	b, err := fs.ReadFile("empty.txt")
+	assert.NoError(t, err)
+	assert.Empty(t, b)
+}
+
+func TestReadFileNotFound(t *testing.T) {
+	img := formatImage(t, 1474560)
+	putFile(t, img, 1, "", "README  TXT", []byte("hello"), []uint32{2})
+
+	fs, err := Open(img)
+	assert.NoError(t, err)
+
+	_, err = fs.ReadFile("diskplayer.contents")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestReadFileDeleted(t *testing.T) {
+	img := formatImage(t, 1474560)
+	putFile(t, img, 1, "diskplayer.contents", "DISKPL~1CON", []byte("spotify:album:abc"), []uint32{2})
+
+	fs, err := Open(img)
+	assert.NoError(t, err)
+	img[fs.rootOffset+3*dirEntrySize] = entryDeleted
+
+	_, err = fs.ReadFile("diskplayer.contents")
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestReadFileLoop(t *testing.T) {
+	img := formatImage(t, 1474560)
+	putFile(t, img, 1, "", "LOOP    BIN", make([]byte, 2048), []uint32{2, 3, 4, 5})
+
+	fs, err := Open(img)
+	assert.NoError(t, err)
+	fs.setEntry(5, 2)
+
+	_, err = fs.ReadFile("loop.bin")
+	assert.EqualError(t, err, "loop detected in FAT chain")
+}
+
+func TestChecksum(t *testing.T) {
+	var n [11]byte
+	copy(n[:], "DISKPL~1CON")
+	var s byte
+	for _, c := range n {
+		s = ((s & 1) << 7) + (s >> 1) + c
+	}
+	assert.Equal(t, s, checksum(n))
+}
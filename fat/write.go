@@ -0,0 +1,317 @@
+package fat
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf16"
+)
+
+// ErrNoSpace is returned when there are not enough free clusters to write a file.
+var ErrNoSpace = errors.New("no space left on disk")
+
+// WriteFile writes the data to the file in the root directory with the given name, creating it if necessary.
+// The data is written to newly allocated clusters before the directory entry is updated to point at them, and only
+// then are the clusters previously used by the file released. An interrupted write therefore leaves either the old or
+// the new contents in place, at worst with some clusters lost until the disk is checked.
+// An error is returned if the filesystem was not opened on a writable device, or if one is encountered.
+func (fs *FS) WriteFile(name string, data []byte) error {
+	w, ok := fs.dev.(io.WriterAt)
+	if !ok {
+		return errors.New("filesystem is read-only")
+	}
+	if name == "" || strings.ContainsAny(name, `/\:*?"<>|`) {
+		return fmt.Errorf("invalid file name: %s", name)
+	}
+
+	root := make([]byte, fs.rootEntries*dirEntrySize)
+	_, err := fs.dev.ReadAt(root, fs.rootOffset)
+	if err != nil {
+		return err
+	}
+	es := parseDir(root)
+
+	e := findEntry(es, name)
+	var old []uint32
+	if e != nil {
+		if e.attr&(attrDirectory|attrReadOnly) != 0 {
+			return fmt.Errorf("%s is a directory or read-only", name)
+		}
+		old, err = fs.chain(e.cluster)
+		if err != nil {
+			return err
+		}
+	}
+
+	cs, err := fs.allocate(len(data))
+	if err != nil {
+		return err
+	}
+
+	cl := fs.sectorsPerCluster * fs.bytesPerSector
+	for i, c := range cs {
+		b := make([]byte, cl)
+		copy(b, data[int64(i)*cl:])
+		_, err = w.WriteAt(b, fs.clusterOffset(c))
+		if err != nil {
+			return err
+		}
+	}
+	err = fs.writeFat(w)
+	if err != nil {
+		return err
+	}
+
+	var first uint32
+	if len(cs) > 0 {
+		first = cs[0]
+	}
+	now := time.Now()
+	if e != nil {
+		d := root[e.index*dirEntrySize : (e.index+1)*dirEntrySize]
+		updateEntry(d, first, uint32(len(data)), now)
+		_, err = w.WriteAt(d, fs.rootOffset+int64(e.index)*dirEntrySize)
+	} else {
+		err = fs.createEntry(w, root, es, name, first, uint32(len(data)), now)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, c := range old {
+		fs.setEntry(c, 0)
+	}
+	return fs.writeFat(w)
+}
+
+// allocate reserves enough free clusters to hold n bytes and links them into a chain in the in-memory FAT.
+// ErrNoSpace is returned if there are not enough free clusters.
+func (fs *FS) allocate(n int) ([]uint32, error) {
+	cl := int(fs.sectorsPerCluster * fs.bytesPerSector)
+	need := (n + cl - 1) / cl
+
+	var cs []uint32
+	for c := uint32(2); c < fs.clusters+2 && len(cs) < need; c++ {
+		if fs.entry(c) == 0 {
+			cs = append(cs, c)
+		}
+	}
+	if len(cs) < need {
+		return nil, ErrNoSpace
+	}
+
+	for i, c := range cs {
+		if i+1 < len(cs) {
+			fs.setEntry(c, cs[i+1])
+		} else {
+			fs.setEntry(c, 0xFFFF)
+		}
+	}
+	return cs, nil
+}
+
+// setEntry sets the value of the FAT entry for cluster n in the in-memory FAT.
+func (fs *FS) setEntry(n, v uint32) {
+	if fs.fat16 {
+		binary.LittleEndian.PutUint16(fs.fat[n*2:], uint16(v))
+		return
+	}
+	v &= 0xFFF
+	o := n + n/2
+	e := binary.LittleEndian.Uint16(fs.fat[o:])
+	if n%2 == 1 {
+		e = e&0x000F | uint16(v)<<4
+	} else {
+		e = e&0xF000 | uint16(v)
+	}
+	binary.LittleEndian.PutUint16(fs.fat[o:], e)
+}
+
+// writeFat writes the in-memory FAT to each of the FAT copies on the device.
+func (fs *FS) writeFat(w io.WriterAt) error {
+	for i := int64(0); i < fs.numFats; i++ {
+		_, err := w.WriteAt(fs.fat, fs.fatOffset+i*fs.sectorsPerFat*fs.bytesPerSector)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createEntry adds a new file to the root directory, with long file name entries if the name is not a valid upper
+// case 8.3 name.
+func (fs *FS) createEntry(w io.WriterAt, root []byte, es []dirEntry, name string, cluster, size uint32, t time.Time) error {
+	sn, exact := shortName(name, es)
+
+	var ds [][]byte
+	if !exact {
+		ds = longNameEntries(name, sn)
+	}
+	d := make([]byte, dirEntrySize)
+	copy(d, sn[:])
+	d[11] = 0x20
+	updateEntry(d, cluster, size, t)
+	binary.LittleEndian.PutUint16(d[14:], dosTime(t))
+	binary.LittleEndian.PutUint16(d[16:], dosDate(t))
+	ds = append(ds, d)
+
+	slot := freeSlots(root, len(ds))
+	if slot < 0 {
+		return errors.New("root directory is full")
+	}
+
+	b := make([]byte, 0, len(ds)*dirEntrySize)
+	for _, d := range ds {
+		b = append(b, d...)
+	}
+	_, err := w.WriteAt(b, fs.rootOffset+int64(slot)*dirEntrySize)
+	return err
+}
+
+// updateEntry updates the first cluster, size and modification time of the directory entry.
+func updateEntry(d []byte, cluster, size uint32, t time.Time) {
+	binary.LittleEndian.PutUint16(d[18:], dosDate(t))
+	binary.LittleEndian.PutUint16(d[22:], dosTime(t))
+	binary.LittleEndian.PutUint16(d[24:], dosDate(t))
+	binary.LittleEndian.PutUint16(d[26:], uint16(cluster))
+	binary.LittleEndian.PutUint32(d[28:], size)
+}
+
+// freeSlots returns the index of the first run of n free or deleted directory entries, or -1 if there is none.
+func freeSlots(root []byte, n int) int {
+	run := 0
+	for i := 0; i*dirEntrySize < len(root); i++ {
+		switch root[i*dirEntrySize] {
+		case entryFree:
+			if (len(root)/dirEntrySize)-i+run >= n {
+				return i - run
+			}
+			return -1
+		case entryDeleted:
+			run++
+			if run == n {
+				return i - run + 1
+			}
+		default:
+			run = 0
+		}
+	}
+	return -1
+}
+
+// shortName returns an 8.3 name for the file which is not used by any of the existing entries. If the name is itself
+// a valid upper case 8.3 name it is used as is, and exact is returned as true.
+func shortName(name string, es []dirEntry) (sn [11]byte, exact bool) {
+	base, ext := name, ""
+	if i := strings.LastIndex(name, "."); i > 0 {
+		base, ext = name[:i], name[i+1:]
+	}
+
+	if len(base) <= 8 && len(ext) <= 3 && validShort(base) && validShort(ext) {
+		copy(sn[:], base+strings.Repeat(" ", 8-len(base))+ext+strings.Repeat(" ", 3-len(ext)))
+		return sn, true
+	}
+
+	b, x := shortChars(base), shortChars(ext)
+	if len(x) > 3 {
+		x = x[:3]
+	}
+	if b == "" {
+		b = "FILE"
+	}
+
+	for n := 1; ; n++ {
+		t := "~" + strconv.Itoa(n)
+		p := b
+		if len(p) > 8-len(t) {
+			p = p[:8-len(t)]
+		}
+		copy(sn[:], p+t+strings.Repeat(" ", 8-len(p)-len(t))+x+strings.Repeat(" ", 3-len(x)))
+		used := false
+		for _, e := range es {
+			if e.shortName == sn {
+				used = true
+				break
+			}
+		}
+		if !used {
+			return sn, false
+		}
+	}
+}
+
+// validShort returns true if s consists only of characters permitted in an upper case 8.3 name.
+func validShort(s string) bool {
+	for _, r := range s {
+		if !shortChar(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// shortChars converts s to upper case and drops any characters not permitted in an 8.3 name.
+func shortChars(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ToUpper(s) {
+		if shortChar(r) {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func shortChar(r rune) bool {
+	return (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') || strings.ContainsRune("!#$%&'()-@^_`{}~", r)
+}
+
+// longNameEntries returns the long file name entries for the name, in the order they are stored on disk.
+func longNameEntries(name string, sn [11]byte) [][]byte {
+	cs := utf16.Encode([]rune(name))
+	if len(cs)%13 != 0 {
+		cs = append(cs, 0)
+	}
+	for len(cs)%13 != 0 {
+		cs = append(cs, 0xFFFF)
+	}
+
+	n := len(cs) / 13
+	ds := make([][]byte, 0, n)
+	for i := n; i >= 1; i-- {
+		d := make([]byte, dirEntrySize)
+		d[0] = byte(i)
+		if i == n {
+			d[0] |= lastLongName
+		}
+		d[11] = attrLongName
+		d[13] = checksum(sn)
+		p := cs[(i-1)*13 : i*13]
+		j := 0
+		for _, r := range [][2]int{{1, 11}, {14, 26}, {28, 32}} {
+			for o := r[0]; o < r[1]; o += 2 {
+				binary.LittleEndian.PutUint16(d[o:], p[j])
+				j++
+			}
+		}
+		ds = append(ds, d)
+	}
+	return ds
+}
+
+// dosTime returns the time in the packed format used in directory entries.
+func dosTime(t time.Time) uint16 {
+	return uint16(t.Hour()<<11 | t.Minute()<<5 | t.Second()/2)
+}
+
+// dosDate returns the date in the packed format used in directory entries.
+func dosDate(t time.Time) uint16 {
+	y := t.Year() - 1980
+	if y < 0 {
+		y = 0
+	}
+	return uint16(y<<9 | int(t.Month())<<5 | t.Day())
+}
@@ -0,0 +1,205 @@
+package fat
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+// freeClusters returns the number of free clusters in the filesystem.
+func freeClusters(fs *FS) int {
+	n := 0
+	for c := uint32(2); c < fs.clusters+2; c++ {
+		if fs.entry(c) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func TestWriteFileNew(t *testing.T) {
+	for _, size := range []int64{1474560, 16 << 20} {
+		img := formatImage(t, size)
+		fs, err := Open(img)
+		assert.NoError(t, err)
+
+		err = fs.WriteFile("diskplayer.contents", []byte("spotify:album:3oyu7chRauu88JYPYfFB55"))
+		assert.NoError(t, err)
+
+		fs, err = Open(img)
+		assert.NoError(t, err)
+		b, err := fs.ReadFile("diskplayer.contents")
+		assert.NoError(t, err)
+		assert.Equal(t, "spotify:album:3oyu7chRauu88JYPYfFB55", string(b))
+
+		es, err := fs.readRoot()
+		assert.NoError(t, err)
+		assert.Len(t, es, 1)
+		assert.Equal(t, "diskplayer.contents", es[0].name)
+		assert.Equal(t, "DISKPL~1CON", string(es[0].shortName[:]))
+
+		fat1 := img[fs.fatOffset : fs.fatOffset+fs.sectorsPerFat*fs.bytesPerSector]
+		fat2 := img[fs.fatOffset+fs.sectorsPerFat*fs.bytesPerSector : fs.rootOffset]
+		assert.Equal(t, fat1, fat2)
+	}
+}
+
+func TestWriteFileShortName(t *testing.T) {
+	img := formatImage(t, 1474560)
+	fs, err := Open(img)
+	assert.NoError(t, err)
+
+	err = fs.WriteFile("README.TXT", []byte("hello"))
+	assert.NoError(t, err)
+
+	img2 := formatImage(t, 1474560)
+	putFile(t, img2, 1, "", "README  TXT", nil, nil)
+	assert.Equal(t, img2[fs.rootOffset+dirEntrySize:fs.rootOffset+dirEntrySize+11],
+		img[fs.rootOffset+dirEntrySize:fs.rootOffset+dirEntrySize+11])
+	assert.Equal(t, byte(0), img[fs.rootOffset+2*dirEntrySize])
+}
+
+func TestWriteFileReplace(t *testing.T) {
+	img := formatImage(t, 1474560)
+	fs, err := Open(img)
+	assert.NoError(t, err)
+	free := freeClusters(fs)
+
+	large := bytes.Repeat([]byte("spotify:track:4uLU6hMCjMI75M1A2tKUQC\n"), 100)
+	err = fs.WriteFile("diskplayer.contents", large)
+	assert.NoError(t, err)
+	assert.Equal(t, free-8, freeClusters(fs))
+
+	err = fs.WriteFile("DISKPLAYER.CONTENTS", []byte("spotify:album:3oyu7chRauu88JYPYfFB55"))
+	assert.NoError(t, err)
+	assert.Equal(t, free-1, freeClusters(fs))
+
+	fs, err = Open(img)
+	assert.NoError(t, err)
+	assert.Equal(t, free-1, freeClusters(fs))
+	es, err := fs.readRoot()
+	assert.NoError(t, err)
+	assert.Len(t, es, 1)
+	b, err := fs.ReadFile("diskplayer.contents")
+	assert.NoError(t, err)
+	assert.Equal(t, "spotify:album:3oyu7chRauu88JYPYfFB55", string(b))
+
+	err = fs.WriteFile("diskplayer.contents", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, free, freeClusters(fs))
+	b, err = fs.ReadFile("diskplayer.contents")
+	assert.NoError(t, err)
+	assert.Empty(t, b)
+}
+
+func TestWriteFileExistingDisk(t *testing.T) {
+	img := formatImage(t, 1474560)
+	n := putFile(t, img, 1, "Other File.txt", "OTHERF~1TXT", []byte("other"), []uint32{2})
+	putFile(t, img, 1+n, "diskplayer.contents", "DISKPL~1CON", []byte("spotify:album:old"), []uint32{3})
+
+	fs, err := Open(img)
+	assert.NoError(t, err)
+	err = fs.WriteFile("diskplayer.contents", []byte("spotify:album:new"))
+	assert.NoError(t, err)
+
+	fs, err = Open(img)
+	assert.NoError(t, err)
+	b, err := fs.ReadFile("diskplayer.contents")
+	assert.NoError(t, err)
+	assert.Equal(t, "spotify:album:new", string(b))
+	b, err = fs.ReadFile("other file.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "other", string(b))
+	assert.Equal(t, uint32(0), fs.entry(3))
+}
+
+func TestWriteFileUniqueShortName(t *testing.T) {
+	img := formatImage(t, 1474560)
+	fs, err := Open(img)
+	assert.NoError(t, err)
+
+	assert.NoError(t, fs.WriteFile("diskplayer.contents", []byte("a")))
+	assert.NoError(t, fs.WriteFile("diskplayer.contents.bak", []byte("b")))
+
+	es, err := fs.readRoot()
+	assert.NoError(t, err)
+	assert.Len(t, es, 2)
+	assert.Equal(t, "DISKPL~1CON", string(es[0].shortName[:]))
+	assert.Equal(t, "DISKPL~1BAK", string(es[1].shortName[:]))
+
+	assert.NoError(t, fs.WriteFile("diskplayer.content", []byte("c")))
+	es, err = fs.readRoot()
+	assert.NoError(t, err)
+	assert.Equal(t, "DISKPL~2CON", string(es[2].shortName[:]))
+}
+
+func TestWriteFileReusesDeletedSlots(t *testing.T) {
+	img := formatImage(t, 1474560)
+	putFile(t, img, 1, "", "OLD     TXT", nil, nil)
+	putFile(t, img, 2, "", "KEEP    TXT", nil, nil)
+
+	fs, err := Open(img)
+	assert.NoError(t, err)
+	img[fs.rootOffset+dirEntrySize] = entryDeleted
+
+	assert.NoError(t, fs.WriteFile("NEW.TXT", []byte("new")))
+	assert.Equal(t, "NEW     TXT", string(img[fs.rootOffset+dirEntrySize:fs.rootOffset+dirEntrySize+11]))
+}
+
+func TestWriteFileNoSpace(t *testing.T) {
+	img := formatImage(t, 65536)
+	fs, err := Open(img)
+	assert.NoError(t, err)
+
+	err = fs.WriteFile("large.bin", make([]byte, 65536))
+	assert.Equal(t, ErrNoSpace, err)
+
+	fs, err = Open(img)
+	assert.NoError(t, err)
+	_, err = fs.ReadFile("large.bin")
+	assert.Error(t, err)
+}
+
+func TestWriteFileRootFull(t *testing.T) {
+	img := formatImage(t, 1474560)
+	fs, err := Open(img)
+	assert.NoError(t, err)
+	for i := int64(1); i < fs.rootEntries; i++ {
+		copy(img[fs.rootOffset+i*dirEntrySize:], "FILLER  TXT")
+	}
+
+	err = fs.WriteFile("diskplayer.contents", []byte("a"))
+	assert.EqualError(t, err, "root directory is full")
+}
+
+func TestWriteFileReadOnly(t *testing.T) {
+	fs, err := Open(bytes.NewReader(formatImage(t, 1474560)))
+	assert.NoError(t, err)
+
+	err = fs.WriteFile("diskplayer.contents", []byte("a"))
+	assert.EqualError(t, err, "filesystem is read-only")
+}
+
+func TestWriteFileInvalidName(t *testing.T) {
+	fs, err := Open(formatImage(t, 1474560))
+	assert.NoError(t, err)
+
+	err = fs.WriteFile("dir/diskplayer.contents", []byte("a"))
+	assert.EqualError(t, err, "invalid file name: dir/diskplayer.contents")
+}
+
+func TestLongNameEntries(t *testing.T) {
+	var sn [11]byte
+	copy(sn[:], "ABCDEF~1TXT")
+	name := strings.Repeat("x", 13)
+	ds := longNameEntries(name, sn)
+	assert.Len(t, ds, 1)
+	assert.Equal(t, byte(0x41), ds[0][0])
+	assert.Equal(t, name, decodeLongName(longNameChars(ds[0])))
+
+	ds = longNameEntries(name+"y", sn)
+	assert.Len(t, ds, 2)
+	assert.Equal(t, byte(0x42), ds[0][0])
+	assert.Equal(t, byte(0x01), ds[1][0])
+}
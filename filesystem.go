@@ -0,0 +1,201 @@
+package diskplayer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// Filesystem describes a filesystem found on a device, along with the options with which it should be mounted.
+// The UUID and label identify the disk, and are empty if the filesystem does not have them.
+type Filesystem struct {
+	Type     string
+	Options  string
+	ReadOnly bool
+	UUID     string
+	Label    string
+}
+
+// UnformattedError is returned when no recognised filesystem is found on a device.
+type UnformattedError struct {
+	Path string
+}
+
+func (e *UnformattedError) Error() string {
+	return fmt.Sprintf("no filesystem found on %s, the disk may be blank or need to be formatted", e.Path)
+}
+
+// ProbeFilesystem reads the superblock of the device or image identified by the path and returns the filesystem
+// found on it. Supported filesystems are vfat, ext2, ext3, ext4, exfat and iso9660, the latter being mounted read-only.
+// An UnformattedError is returned if no known filesystem is found, or any other error that is encountered.
+func ProbeFilesystem(path string) (*Filesystem, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	t, err := probeFilesystem(f)
+	if err != nil {
+		return nil, err
+	}
+
+	fs, err := newFilesystem(path, t)
+	if err != nil {
+		return nil, err
+	}
+
+	fs.UUID, fs.Label = volumeIdentity(f, t)
+	return fs, nil
+}
+
+// newFilesystem returns the filesystem of the given type found on the device or image identified by the path, along
+// with the options with which it should be mounted.
+// An UnformattedError is returned if the type is empty, or an error if the filesystem is not supported.
+func newFilesystem(path, t string) (*Filesystem, error) {
+	switch t {
+	case "":
+		return nil, &UnformattedError{Path: path}
+	case "vfat":
+		return &Filesystem{Type: t, Options: "flush"}, nil
+	case "ext2", "ext3", "ext4", "exfat":
+		return &Filesystem{Type: t}, nil
+	case "iso9660":
+		return &Filesystem{Type: t, Options: "ro", ReadOnly: true}, nil
+	default:
+		return nil, fmt.Errorf("unsupported filesystem %s found on %s", t, path)
+	}
+}
+
+// volumeIdentity returns the UUID and label of the filesystem of the given type, formatted in the same way as by
+// blkid, i.e. "ABCD-1234" for FAT volume serial numbers. Empty strings are returned for any which are not present.
+func volumeIdentity(r io.ReaderAt, t string) (uuid, label string) {
+	b := make([]byte, 2048)
+	n, _ := r.ReadAt(b, 0)
+	b = b[:n]
+
+	switch {
+	case t == "vfat" && len(b) >= 512:
+		// FAT32 has no 16 bit FAT size, and its extended boot record follows the longer BIOS parameter block.
+		o := 36
+		if binary.LittleEndian.Uint16(b[22:]) == 0 {
+			o = 64
+		}
+		if b[o+2] != 0x29 {
+			return "", ""
+		}
+		s := binary.LittleEndian.Uint32(b[o+3:])
+		label = strings.TrimRight(string(b[o+7:o+18]), " ")
+		if label == "NO NAME" {
+			label = ""
+		}
+		return fmt.Sprintf("%04X-%04X", s>>16, s&0xFFFF), label
+	case t == "exfat" && len(b) >= 512:
+		s := binary.LittleEndian.Uint32(b[100:])
+		return fmt.Sprintf("%04X-%04X", s>>16, s&0xFFFF), ""
+	case strings.HasPrefix(t, "ext") && len(b) >= 1024+136:
+		u := b[1024+104 : 1024+120]
+		if !bytes.Equal(u, make([]byte, 16)) {
+			uuid = fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16])
+		}
+		return uuid, strings.TrimRight(string(b[1024+120:1024+136]), "\x00")
+	case t == "iso9660":
+		v := make([]byte, 32)
+		_, err := r.ReadAt(v, 16*2048+40)
+		if err != nil {
+			return "", ""
+		}
+		return "", strings.TrimRight(string(v), " \x00")
+	}
+	return "", ""
+}
+
+// probeFilesystem identifies the filesystem by the signatures in its superblock, returning an empty string if none
+// is recognised.
+func probeFilesystem(r io.ReaderAt) (string, error) {
+	b := make([]byte, 2048)
+	n, err := r.ReadAt(b, 0)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	b = b[:n]
+
+	if len(b) >= 512 {
+		switch {
+		case bytes.Equal(b[3:11], []byte("EXFAT   ")):
+			return "exfat", nil
+		case bytes.Equal(b[3:11], []byte("NTFS    ")):
+			return "ntfs", nil
+		case isFat(b):
+			return "vfat", nil
+		}
+	}
+
+	if len(b) >= 1024+104 {
+		if t := extType(b[1024:]); t != "" {
+			return t, nil
+		}
+	}
+
+	iso := make([]byte, 5)
+	_, err = r.ReadAt(iso, 16*2048+1)
+	if err == nil && bytes.Equal(iso, []byte("CD001")) {
+		return "iso9660", nil
+	}
+
+	return "", nil
+}
+
+// isFat returns true if the boot sector contains a plausible FAT BIOS parameter block.
+func isFat(b []byte) bool {
+	if b[0] != 0xEB && b[0] != 0xE9 {
+		return false
+	}
+	if bytes.HasPrefix(b[54:], []byte("FAT1")) || bytes.HasPrefix(b[82:], []byte("FAT32")) {
+		return true
+	}
+
+	bps := binary.LittleEndian.Uint16(b[11:])
+	spc := b[13]
+	rs := binary.LittleEndian.Uint16(b[14:])
+	nf := b[16]
+	return (bps == 512 || bps == 1024 || bps == 2048 || bps == 4096) &&
+		spc != 0 && spc&(spc-1) == 0 && rs != 0 && (nf == 1 || nf == 2)
+}
+
+// extType returns the ext filesystem version identified by the features in the superblock, or an empty string if
+// the superblock does not belong to an ext filesystem.
+func extType(sb []byte) string {
+	const (
+		magic            = 0xEF53
+		compatJournal    = 0x0004
+		incompatExtents  = 0x0040
+		incompat64Bit    = 0x0080
+		incompatFlexBg   = 0x0200
+		roCompatHugeFile = 0x0008
+		roCompatGdtCsum  = 0x0010
+		roCompatDirNlink = 0x0020
+		roCompatIsize    = 0x0040
+	)
+
+	if binary.LittleEndian.Uint16(sb[56:]) != magic {
+		return ""
+	}
+
+	compat := binary.LittleEndian.Uint32(sb[92:])
+	incompat := binary.LittleEndian.Uint32(sb[96:])
+	roCompat := binary.LittleEndian.Uint32(sb[100:])
+
+	switch {
+	case incompat&(incompatExtents|incompat64Bit|incompatFlexBg) != 0,
+		roCompat&(roCompatHugeFile|roCompatGdtCsum|roCompatDirNlink|roCompatIsize) != 0:
+		return "ext4"
+	case compat&compatJournal != 0:
+		return "ext3"
+	default:
+		return "ext2"
+	}
+}
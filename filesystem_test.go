@@ -0,0 +1,142 @@
+package diskplayer
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// fatBootSector returns a minimal FAT12 floppy boot sector. If label is false the filesystem type label is omitted,
+// as is the case for some disks formatted by older versions of DOS.
+func fatBootSector(label bool) []byte {
+	b := make([]byte, 1474560)
+	copy(b, []byte{0xEB, 0x3C, 0x90})
+	copy(b[3:], "MSDOS5.0")
+	binary.LittleEndian.PutUint16(b[11:], 512)
+	b[13] = 1
+	binary.LittleEndian.PutUint16(b[14:], 1)
+	b[16] = 2
+	if label {
+		copy(b[54:], "FAT12   ")
+	}
+	b[510], b[511] = 0x55, 0xAA
+	return b
+}
+
+// extSuperblock returns an image containing an ext superblock with the provided feature flags.
+func extSuperblock(compat, incompat, roCompat uint32) []byte {
+	b := make([]byte, 8192)
+	binary.LittleEndian.PutUint16(b[1024+56:], 0xEF53)
+	binary.LittleEndian.PutUint32(b[1024+92:], compat)
+	binary.LittleEndian.PutUint32(b[1024+96:], incompat)
+	binary.LittleEndian.PutUint32(b[1024+100:], roCompat)
+	return b
+}
+
+func withSignature(size int, off int, sig string) []byte {
+	b := make([]byte, size)
+	copy(b[off:], sig)
+	return b
+}
+
+var probeTests = []struct {
+	name string
+	in   []byte
+	out  string
+}{
+	{"fat12", fatBootSector(true), "vfat"},
+	{"fat without label", fatBootSector(false), "vfat"},
+	{"ext2", extSuperblock(0, 0, 0), "ext2"},
+	{"ext3", extSuperblock(0x4, 0x2, 0), "ext3"},
+	{"ext4", extSuperblock(0x4, 0x2c2, 0x6b), "ext4"},
+	{"exfat", withSignature(4096, 3, "EXFAT   "), "exfat"},
+	{"ntfs", withSignature(4096, 3, "NTFS    "), "ntfs"},
+	{"iso9660", withSignature(40960, 16*2048+1, "CD001"), "iso9660"},
+	{"blank", make([]byte, 40960), ""},
+	{"short", make([]byte, 100), ""},
+}
+
+func TestProbeFilesystemSignatures(t *testing.T) {
+	for _, tt := range probeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			fs, err := probeFilesystem(bytes.NewReader(tt.in))
+			assert.NoError(t, err)
+			assert.Equal(t, tt.out, fs)
+		})
+	}
+}
+
+func probeImage(t *testing.T, b []byte) (*Filesystem, error) {
+	f, err := ioutil.TempFile("", "diskplayer_probe")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	_, err = f.Write(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return ProbeFilesystem(f.Name())
+}
+
+func TestProbeFilesystem(t *testing.T) {
+	fs, err := probeImage(t, fatBootSector(true))
+	assert.NoError(t, err)
+	assert.Equal(t, &Filesystem{Type: "vfat", Options: "flush"}, fs)
+
+	fs, err = probeImage(t, withSignature(40960, 16*2048+1, "CD001"))
+	assert.NoError(t, err)
+	assert.Equal(t, &Filesystem{Type: "iso9660", Options: "ro", ReadOnly: true}, fs)
+}
+
+func TestProbeFilesystemUnformatted(t *testing.T) {
+	fs, err := probeImage(t, make([]byte, 40960))
+	assert.Nil(t, fs)
+	_, ok := err.(*UnformattedError)
+	assert.True(t, ok)
+	assert.Contains(t, err.Error(), "the disk may be blank or need to be formatted")
+}
+
+func TestProbeFilesystemUnsupported(t *testing.T) {
+	fs, err := probeImage(t, withSignature(4096, 3, "NTFS    "))
+	assert.Nil(t, fs)
+	assert.Contains(t, err.Error(), "unsupported filesystem ntfs found on")
+}
+
+func TestProbeFilesystemInvalidPath(t *testing.T) {
+	_, err := ProbeFilesystem("./test-fixtures/not_a_real_path")
+	_, ok := err.(*os.PathError)
+	assert.True(t, ok)
+}
+
+func TestProbeFilesystemIdentity(t *testing.T) {
+	p := formattedImage(t)
+	defer os.Remove(p)
+	fs, err := ProbeFilesystem(p)
+	assert.NoError(t, err)
+	assert.Regexp(t, "^[0-9A-F]{4}-[0-9A-F]{4}$", fs.UUID)
+	assert.Equal(t, "DISKPLAYER", fs.Label)
+
+	b := extSuperblock(0x4, 0x2c2, 0x6b)
+	copy(b[1024+104:], []byte{0x12, 0x34, 0x56, 0x78, 0x9a, 0xbc, 0xde, 0xf0, 0x01, 0x23, 0x45, 0x67, 0x89, 0xab, 0xcd, 0xef})
+	copy(b[1024+120:], "records")
+	uuid, label := volumeIdentity(bytes.NewReader(b), "ext4")
+	assert.Equal(t, "12345678-9abc-def0-0123-456789abcdef", uuid)
+	assert.Equal(t, "records", label)
+
+	b = withSignature(40960, 16*2048+1, "CD001")
+	copy(b[16*2048+40:], "MIXTAPE                         ")
+	uuid, label = volumeIdentity(bytes.NewReader(b), "iso9660")
+	assert.Equal(t, "", uuid)
+	assert.Equal(t, "MIXTAPE", label)
+
+	uuid, label = volumeIdentity(bytes.NewReader(fatBootSector(true)), "vfat")
+	assert.Equal(t, "", uuid)
+	assert.Equal(t, "", label)
+}
@@ -1,12 +1,15 @@
 module github.com/dinofizz/diskplayer
 
-go 1.13
+go 1.16
 
 require (
 	github.com/docker/docker v1.13.1
+	github.com/godbus/dbus/v5 v5.1.0
+	github.com/skip2/go-qrcode v0.0.0-20200617195104-da1b6568686e
 	github.com/spf13/viper v1.15.0
 	github.com/stretchr/testify v1.8.1
 	github.com/zmb3/spotify v1.3.0
+	golang.org/x/crypto v0.0.0-20220525230936-793ad666bf5e
 	golang.org/x/net v0.7.0 // indirect
 	golang.org/x/oauth2 v0.5.0
 )
@@ -0,0 +1,124 @@
+package diskplayer
+
+import (
+	"fmt"
+	"github.com/skip2/go-qrcode"
+	"html"
+	"io"
+	"strings"
+)
+
+// Label dimensions are in millimetres, sized to fit the label area of a 3.5" floppy disk.
+const (
+	labelWidth      = 70
+	labelHeight     = 70
+	labelMargin     = 4
+	labelCoverSize  = 30
+	labelQRSize     = 26
+	labelLineLength = 14
+	labelMaxLines   = 4
+)
+
+// WriteLabel writes a printable SVG disk label for the provided metadata to the writer.
+// The label contains the cover art, title and artist, as well as a QR code of the Spotify web URL.
+// An error is returned if one is encountered.
+func WriteLabel(w io.Writer, m *Metadata) error {
+	q, err := qrcode.New(m.WebURL, qrcode.Medium)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<?xml version="1.0" encoding="UTF-8"?>`+"\n")
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" `+
+		`width="%dmm" height="%dmm" viewBox="0 0 %d %d">`+"\n", labelWidth, labelHeight, labelWidth, labelHeight)
+	fmt.Fprintf(&b, `<rect x="0" y="0" width="%d" height="%d" fill="white" stroke="#ccc" stroke-width="0.2"/>`+"\n",
+		labelWidth, labelHeight)
+
+	if m.ImageURL != "" {
+		fmt.Fprintf(&b, `<image x="%d" y="%d" width="%d" height="%d" xlink:href="%s" href="%s"/>`+"\n",
+			labelMargin, labelMargin, labelCoverSize, labelCoverSize, escape(m.ImageURL), escape(m.ImageURL))
+	}
+
+	x := labelMargin*2 + labelCoverSize
+	y := labelMargin + 4
+	for _, l := range wrapText(m.Title, labelLineLength, labelMaxLines) {
+		fmt.Fprintf(&b, `<text x="%d" y="%d" font-family="sans-serif" font-size="3.6" font-weight="bold">%s</text>`+"\n",
+			x, y, escape(l))
+		y += 5
+	}
+	for _, l := range wrapText(m.Artist, labelLineLength, 2) {
+		fmt.Fprintf(&b, `<text x="%d" y="%d" font-family="sans-serif" font-size="3">%s</text>`+"\n",
+			x, y, escape(l))
+		y += 4
+	}
+
+	fmt.Fprintf(&b, `<text x="%d" y="%d" font-family="monospace" font-size="2">%s</text>`+"\n",
+		labelMargin, labelHeight-labelMargin, escape(m.URI))
+
+	writeQRCode(&b, q.Bitmap(), labelWidth-labelMargin-labelQRSize, labelHeight-labelMargin*2-labelQRSize, labelQRSize)
+
+	fmt.Fprintf(&b, "</svg>\n")
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// writeQRCode writes the QR code bitmap as a single SVG path scaled to a square of the given size at (x, y).
+func writeQRCode(b *strings.Builder, bm [][]bool, x, y, size int) {
+	fmt.Fprintf(b, `<svg x="%d" y="%d" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`,
+		x, y, size, size, len(bm), len(bm))
+	fmt.Fprintf(b, `<path fill="black" d="`)
+	for r, row := range bm {
+		for c, set := range row {
+			if set {
+				fmt.Fprintf(b, "M%d %dh1v1h-1z", c, r)
+			}
+		}
+	}
+	fmt.Fprintf(b, `"/></svg>`+"\n")
+}
+
+// wrapText splits the text into lines of at most n characters, breaking on spaces where possible.
+// At most max lines are returned, with the last line truncated with an ellipsis if the text does not fit.
+func wrapText(s string, n, max int) []string {
+	var ls []string
+	var l string
+	for _, w := range strings.Fields(s) {
+		for len([]rune(w)) > n {
+			if l != "" {
+				ls = append(ls, l)
+				l = ""
+			}
+			r := []rune(w)
+			ls = append(ls, string(r[:n]))
+			w = string(r[n:])
+		}
+		if l == "" {
+			l = w
+		} else if len([]rune(l))+1+len([]rune(w)) <= n {
+			l += " " + w
+		} else {
+			ls = append(ls, l)
+			l = w
+		}
+	}
+	if l != "" {
+		ls = append(ls, l)
+	}
+
+	if len(ls) > max {
+		ls = ls[:max]
+		r := []rune(ls[max-1])
+		if len(r) >= n {
+			r = r[:n-1]
+		}
+		ls[max-1] = string(r) + "…"
+	}
+	return ls
+}
+
+// escape escapes the string for inclusion in SVG text and attribute values.
+func escape(s string) string {
+	return html.EscapeString(s)
+}
@@ -0,0 +1,61 @@
+package diskplayer
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestWriteLabel(t *testing.T) {
+	m := &Metadata{
+		URI:      "spotify:album:1S7mumn7D4riEX2gVWYgPO",
+		WebURL:   "https://open.spotify.com/album/1S7mumn7D4riEX2gVWYgPO",
+		Title:    "Songs & <Stories>",
+		Artist:   "Test Artist",
+		ImageURL: "https://i.scdn.co/image/cover",
+	}
+
+	var b bytes.Buffer
+	err := WriteLabel(&b, m)
+	assert.NoError(t, err)
+
+	s := b.String()
+	assert.True(t, strings.HasPrefix(s, "<?xml"))
+	assert.Contains(t, s, `width="70mm" height="70mm"`)
+	assert.Contains(t, s, "Songs &amp;")
+	assert.Contains(t, s, "&lt;Stories&gt;")
+	assert.Contains(t, s, "Test Artist")
+	assert.Contains(t, s, `href="https://i.scdn.co/image/cover"`)
+	assert.Contains(t, s, "spotify:album:1S7mumn7D4riEX2gVWYgPO")
+	assert.Contains(t, s, "<path fill=\"black\" d=\"M")
+}
+
+func TestWriteLabelNoImage(t *testing.T) {
+	m := &Metadata{URI: "spotify:playlist:abc", WebURL: "https://open.spotify.com/playlist/abc", Title: "Title"}
+
+	var b bytes.Buffer
+	err := WriteLabel(&b, m)
+	assert.NoError(t, err)
+	assert.NotContains(t, b.String(), "<image")
+}
+
+var wrapTextTests = []struct {
+	in  string
+	n   int
+	max int
+	out []string
+}{
+	{"The Dark Side of the Moon", 14, 4, []string{"The Dark Side", "of the Moon"}},
+	{"Supercalifragilistic", 10, 4, []string{"Supercalif", "ragilistic"}},
+	{"one two three four five", 5, 2, []string{"one", "two…"}},
+	{"", 10, 2, nil},
+}
+
+func TestWrapText(t *testing.T) {
+	for _, tt := range wrapTextTests {
+		t.Run(tt.in, func(t *testing.T) {
+			assert.Equal(t, tt.out, wrapText(tt.in, tt.n, tt.max))
+		})
+	}
+}
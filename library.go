@@ -0,0 +1,108 @@
+package diskplayer
+
+import (
+	"fmt"
+	"github.com/zmb3/spotify"
+	"strings"
+)
+
+// libraryPageLimit is the number of items requested from Spotify at a time when filtering the user's library.
+const libraryPageLimit = 50
+
+// libraryFilterMax is the maximum number of items of a kind in the user's library which are searched when filtering.
+const libraryFilterMax = 1000
+
+// LibraryResults holds a page of the albums, playlists or shows in the library of the authenticated Spotify user,
+// along with the offset of the page and the total number of items available.
+type LibraryResults struct {
+	Kind   string       `json:"kind"`
+	Items  []SearchItem `json:"items"`
+	Offset int          `json:"offset"`
+	Limit  int          `json:"limit"`
+	Total  int          `json:"total"`
+}
+
+// Library will return a page of up to limit items, starting at offset, of the given kind from the library of the
+// authenticated Spotify user. The kind is one of "album" for saved albums, "playlist" for followed and owned playlists
+// or "show" for saved shows. If a query is given only the items whose title or artist contains it are included, and
+// the total is the number of matching items.
+// An error is returned if one is encountered.
+func Library(c Client, kind, query string, offset, limit int) (*LibraryResults, error) {
+	if kind != "album" && kind != "playlist" && kind != "show" {
+		return nil, fmt.Errorf("invalid library kind: %s", kind)
+	}
+
+	lr := &LibraryResults{Kind: kind, Offset: offset, Limit: limit}
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		var err error
+		lr.Items, lr.Total, err = libraryItems(c, kind, offset, limit)
+		if err != nil {
+			return nil, err
+		}
+		return lr, nil
+	}
+
+	var ms []SearchItem
+	for o := 0; o < libraryFilterMax; o += libraryPageLimit {
+		is, t, err := libraryItems(c, kind, o, libraryPageLimit)
+		if err != nil {
+			return nil, err
+		}
+		for _, i := range is {
+			if strings.Contains(strings.ToLower(i.Title), query) || strings.Contains(strings.ToLower(i.Artist), query) {
+				ms = append(ms, i)
+			}
+		}
+		if o+libraryPageLimit >= t {
+			break
+		}
+	}
+
+	lr.Total = len(ms)
+	if offset < len(ms) {
+		ms = ms[offset:]
+		if len(ms) > limit {
+			ms = ms[:limit]
+		}
+		lr.Items = ms
+	}
+	return lr, nil
+}
+
+// libraryItems returns up to limit items of the given kind from the user's library starting at offset, along with the
+// total number of items of that kind in the library.
+// An error is returned if one is encountered.
+func libraryItems(c Client, kind string, offset, limit int) ([]SearchItem, int, error) {
+	o := &spotify.Options{Offset: &offset, Limit: &limit}
+	var is []SearchItem
+	switch kind {
+	case "album":
+		p, err := c.CurrentUsersAlbumsOpt(o)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, a := range p.Albums {
+			is = appendSearchItem(is, kind, a.ID, a.Name, artistNames(a.Artists), a.Images)
+		}
+		return is, p.Total, nil
+	case "playlist":
+		p, err := c.CurrentUsersPlaylistsOpt(o)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, pl := range p.Playlists {
+			is = appendSearchItem(is, kind, pl.ID, pl.Name, pl.Owner.DisplayName, pl.Images)
+		}
+		return is, p.Total, nil
+	default:
+		p, err := c.CurrentUsersShowsOpt(o)
+		if err != nil {
+			return nil, 0, err
+		}
+		for _, sh := range p.Shows {
+			is = appendSearchItem(is, kind, sh.ID, sh.Name, sh.Publisher, sh.Images)
+		}
+		return is, p.Total, nil
+	}
+}
@@ -0,0 +1,96 @@
+package diskplayer
+
+import (
+	"errors"
+	"github.com/dinofizz/diskplayer/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/zmb3/spotify"
+	"strconv"
+	"testing"
+)
+
+// libraryClient returns a mock client whose library holds n saved albums, named "Album 0" onwards, served in pages
+// as the options request them.
+func libraryClient(n int) *mocks.Client {
+	m := new(mocks.Client)
+	m.On("CurrentUsersAlbumsOpt", mock.Anything).Return(func(o *spotify.Options) *spotify.SavedAlbumPage {
+		p := &spotify.SavedAlbumPage{}
+		p.Total = n
+		for i := *o.Offset; i < *o.Offset+*o.Limit && i < n; i++ {
+			a := spotify.SavedAlbum{}
+			a.ID = spotify.ID("album" + strconv.Itoa(i))
+			a.Name = "Album " + strconv.Itoa(i)
+			p.Albums = append(p.Albums, a)
+		}
+		return p
+	}, nil)
+	return m
+}
+
+func TestLibrary(t *testing.T) {
+	lr, err := Library(libraryClient(45), "album", "", 40, 20)
+	assert.NoError(t, err)
+	assert.Equal(t, 45, lr.Total)
+	assert.Equal(t, 40, lr.Offset)
+	assert.Len(t, lr.Items, 5)
+	assert.Equal(t, "album", lr.Items[0].Kind)
+	assert.Equal(t, "Album 40", lr.Items[0].Title)
+	assert.Equal(t, "spotify:album:album40", lr.Items[0].URI)
+}
+
+func TestLibraryFilter(t *testing.T) {
+	m := libraryClient(120)
+	lr, err := Library(m, "album", " ALBUM 1", 5, 10)
+	assert.NoError(t, err)
+	// Album 1, Album 10-19 and Album 100-119 match, across all three pages of the library.
+	assert.Equal(t, 31, lr.Total)
+	assert.Len(t, lr.Items, 10)
+	assert.Equal(t, "Album 14", lr.Items[0].Title)
+	m.AssertNumberOfCalls(t, "CurrentUsersAlbumsOpt", 3)
+
+	lr, err = Library(m, "album", "album 1", 40, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 31, lr.Total)
+	assert.Empty(t, lr.Items)
+}
+
+func TestLibraryPlaylistsAndShows(t *testing.T) {
+	m := new(mocks.Client)
+	pp := &spotify.SimplePlaylistPage{}
+	pl := spotify.SimplePlaylist{ID: "5XsXwH5uWdhpAWsigjWMTA", Name: "Test Playlist"}
+	pl.Owner.DisplayName = "Test Owner"
+	pp.Playlists = []spotify.SimplePlaylist{pl}
+	pp.Total = 1
+	m.On("CurrentUsersPlaylistsOpt", mock.Anything).Return(pp, nil)
+	sp := &spotify.SavedShowPage{}
+	sh := spotify.SavedShow{}
+	sh.ID = "4rOoJ6Egrf8K2IrywzwOMk"
+	sh.Name = "Test Show"
+	sh.Publisher = "Test Publisher"
+	sp.Shows = []spotify.SavedShow{sh}
+	sp.Total = 1
+	m.On("CurrentUsersShowsOpt", mock.Anything).Return(sp, nil)
+
+	lr, err := Library(m, "playlist", "", 0, 20)
+	assert.NoError(t, err)
+	assert.Equal(t, "spotify:playlist:5XsXwH5uWdhpAWsigjWMTA", lr.Items[0].URI)
+	assert.Equal(t, "Test Owner", lr.Items[0].Artist)
+
+	lr, err = Library(m, "show", "publisher", 0, 20)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, lr.Total)
+	assert.Equal(t, "https://open.spotify.com/show/4rOoJ6Egrf8K2IrywzwOMk", lr.Items[0].WebURL)
+}
+
+func TestLibraryErrors(t *testing.T) {
+	m := new(mocks.Client)
+	_, err := Library(m, "artist", "", 0, 20)
+	assert.EqualError(t, err, "invalid library kind: artist")
+
+	m.On("CurrentUsersAlbumsOpt", mock.Anything).Return(nil, errors.New("Insufficient client scope"))
+	_, err = Library(m, "album", "", 0, 20)
+	assert.EqualError(t, err, "Insufficient client scope")
+	_, err = Library(m, "album", "filter", 0, 20)
+	assert.EqualError(t, err, "Insufficient client scope")
+}
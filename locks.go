@@ -0,0 +1,87 @@
+package diskplayer
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Operation describes a recorder operation which holds a device, and the mount point it uses, while it runs.
+type Operation struct {
+	Device      string    `json:"device"`
+	MountPoint  string    `json:"mount_point,omitempty"`
+	Description string    `json:"description"`
+	Started     time.Time `json:"started"`
+}
+
+// BusyError is returned when a device or mount point is already held by another operation.
+type BusyError struct {
+	Resource  string
+	Operation Operation
+}
+
+func (e *BusyError) Error() string {
+	return fmt.Sprintf("%s is busy %s since %s, try again once it has finished", e.Resource,
+		e.Operation.Description, e.Operation.Started.Format("15:04:05"))
+}
+
+// DeviceLocks ensures that no two recorder operations use the same device, or mount a device to the same mount
+// point, at once. Operations fail fast rather than waiting for each other, so that a second request from another
+// browser tab is told that the disk is busy instead of racing the first. The zero value is ready for use.
+type DeviceLocks struct {
+	mu  sync.Mutex
+	ops map[string]*Operation
+}
+
+// Acquire holds the device, and the mount point if it is not empty, for the operation with the given description.
+// A function which releases them again is returned, or a BusyError if either is already held.
+func (l *DeviceLocks) Acquire(device, mountPoint, description string) (func(), error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	keys := []string{"device:" + device}
+	if mountPoint != "" {
+		keys = append(keys, "mount:"+mountPoint)
+	}
+
+	for i, k := range keys {
+		if o, ok := l.ops[k]; ok {
+			r := device
+			if i > 0 {
+				r = "mount point " + mountPoint
+			}
+			return nil, &BusyError{Resource: r, Operation: *o}
+		}
+	}
+
+	if l.ops == nil {
+		l.ops = map[string]*Operation{}
+	}
+	o := &Operation{Device: device, MountPoint: mountPoint, Description: description, Started: time.Now()}
+	for _, k := range keys {
+		l.ops[k] = o
+	}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			for _, k := range keys {
+				delete(l.ops, k)
+			}
+		})
+	}, nil
+}
+
+// Busy returns the operation holding the device, or nil if it is not in use.
+func (l *DeviceLocks) Busy(device string) *Operation {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if o, ok := l.ops["device:"+device]; ok {
+		c := *o
+		return &c
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package diskplayer
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestDeviceLocks(t *testing.T) {
+	var l DeviceLocks
+
+	release, err := l.Acquire("/dev/sda", "/mnt/floppy", "recording spotify:album:abc")
+	assert.NoError(t, err)
+	o := l.Busy("/dev/sda")
+	assert.Equal(t, "recording spotify:album:abc", o.Description)
+	assert.Equal(t, "/mnt/floppy", o.MountPoint)
+	assert.Nil(t, l.Busy("/dev/sdb"))
+
+	_, err = l.Acquire("/dev/sda", "", "reading the disk")
+	assert.IsType(t, &BusyError{}, err)
+	assert.Contains(t, err.Error(), "/dev/sda is busy recording spotify:album:abc since ")
+
+	_, err = l.Acquire("/dev/sdb", "/mnt/floppy", "reading the disk")
+	assert.IsType(t, &BusyError{}, err)
+	assert.Contains(t, err.Error(), "mount point /mnt/floppy is busy recording spotify:album:abc since ")
+	assert.Nil(t, l.Busy("/dev/sdb"))
+
+	r2, err := l.Acquire("/dev/sdb", "/media/usb", "reading the disk")
+	assert.NoError(t, err)
+	r2()
+
+	release()
+	release()
+	assert.Nil(t, l.Busy("/dev/sda"))
+
+	release, err = l.Acquire("/dev/sdb", "/mnt/floppy", "reading the disk")
+	assert.NoError(t, err)
+	release()
+}
@@ -0,0 +1,118 @@
+package diskplayer
+
+import (
+	"fmt"
+	"github.com/zmb3/spotify"
+	"strings"
+)
+
+// Metadata describes the album, playlist, artist, show or track identified by a Spotify URI in a form suitable for display.
+type Metadata struct {
+	URI      string `json:"uri"`
+	WebURL   string `json:"web_url"`
+	Title    string `json:"title"`
+	Artist   string `json:"artist"`
+	ImageURL string `json:"image_url"`
+}
+
+// ResolveMetadata will look up the album, playlist, artist, show or track identified by the Spotify URI and return its
+// title, artist and cover art. The artist of a show is its publisher, and the cover art of a track is that of its
+// album. Smart contents which are not drawn from a Spotify item are described by their title alone.
+// An error is returned if one is encountered.
+func ResolveMetadata(c Client, uri string) (*Metadata, error) {
+	if t := smartTitle(uri); t != "" {
+		return &Metadata{URI: uri, Title: t}, nil
+	}
+
+	k, id, err := splitSpotifyUri(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Metadata{URI: uri, WebURL: spotifyWebUrl(k, id)}
+
+	switch k {
+	case "album":
+		a, err := c.GetAlbum(spotify.ID(id))
+		if err != nil {
+			return nil, err
+		}
+		m.Title = a.Name
+		m.Artist = artistNames(a.Artists)
+		m.ImageURL = imageUrl(a.Images)
+	case "playlist":
+		p, err := c.GetPlaylist(spotify.ID(id))
+		if err != nil {
+			return nil, err
+		}
+		m.Title = p.Name
+		m.Artist = p.Owner.DisplayName
+		m.ImageURL = imageUrl(p.Images)
+	case "artist":
+		a, err := c.GetArtist(spotify.ID(id))
+		if err != nil {
+			return nil, err
+		}
+		m.Title = a.Name
+		m.ImageURL = imageUrl(a.Images)
+	case "show":
+		sh, err := c.GetShow(id)
+		if err != nil {
+			return nil, err
+		}
+		m.Title = sh.Name
+		m.Artist = sh.Publisher
+		m.ImageURL = imageUrl(sh.Images)
+	case "track":
+		t, err := c.GetTrack(spotify.ID(id))
+		if err != nil {
+			return nil, err
+		}
+		m.Title = t.Name
+		m.Artist = artistNames(t.Artists)
+		m.ImageURL = imageUrl(t.Album.Images)
+	default:
+		return nil, fmt.Errorf("no metadata available for Spotify URI: %s", uri)
+	}
+
+	return m, nil
+}
+
+// splitSpotifyUri splits a Spotify URI such as spotify:album:1S7mumn7D4riEX2gVWYgPO into its kind and ID.
+// An error is returned if the URI is not in the expected form.
+func splitSpotifyUri(uri string) (string, string, error) {
+	p := strings.Split(uri, ":")
+	if len(p) != 3 || p[0] != "spotify" || p[1] == "" || p[2] == "" {
+		return "", "", fmt.Errorf("invalid Spotify URI: %s", uri)
+	}
+	return p[1], p[2], nil
+}
+
+// spotifyWebUrl returns the Spotify web URL for the item of the given kind and ID.
+func spotifyWebUrl(kind, id string) string {
+	return "https://open.spotify.com/" + kind + "/" + id
+}
+
+// artistNames returns the names of the provided artists as a comma separated list.
+func artistNames(as []spotify.SimpleArtist) string {
+	n := make([]string, len(as))
+	for i, a := range as {
+		n[i] = a.Name
+	}
+	return strings.Join(n, ", ")
+}
+
+// imageUrl returns the URL of the widest image in the list, or an empty string if there are no images. The first of
+// the widest images is chosen, so the first image is chosen if their widths are not known.
+func imageUrl(is []spotify.Image) string {
+	if len(is) == 0 {
+		return ""
+	}
+	w := is[0]
+	for _, i := range is[1:] {
+		if i.Width > w.Width {
+			w = i
+		}
+	}
+	return w.URL
+}
@@ -0,0 +1,128 @@
+package diskplayer
+
+import (
+	"errors"
+	"github.com/dinofizz/diskplayer/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/zmb3/spotify"
+	"testing"
+)
+
+func TestResolveMetadataAlbum(t *testing.T) {
+	m := new(mocks.Client)
+	a := &spotify.FullAlbum{}
+	a.Name = "Test Album"
+	a.Artists = []spotify.SimpleArtist{{Name: "Artist One"}, {Name: "Artist Two"}}
+	a.Images = []spotify.Image{{URL: "https://i.scdn.co/image/large"}, {URL: "https://i.scdn.co/image/small"}}
+	m.On("GetAlbum", spotify.ID("1S7mumn7D4riEX2gVWYgPO")).Return(a, nil)
+
+	md, err := ResolveMetadata(m, "spotify:album:1S7mumn7D4riEX2gVWYgPO")
+	assert.NoError(t, err)
+	assert.Equal(t, "Test Album", md.Title)
+	assert.Equal(t, "Artist One, Artist Two", md.Artist)
+	assert.Equal(t, "https://i.scdn.co/image/large", md.ImageURL)
+	assert.Equal(t, "https://open.spotify.com/album/1S7mumn7D4riEX2gVWYgPO", md.WebURL)
+}
+
+func TestResolveMetadataPlaylist(t *testing.T) {
+	m := new(mocks.Client)
+	p := &spotify.FullPlaylist{}
+	p.Name = "Test Playlist"
+	p.Owner.DisplayName = "Test Owner"
+	m.On("GetPlaylist", spotify.ID("5XsXwH5uWdhpAWsigjWMTA")).Return(p, nil)
+
+	md, err := ResolveMetadata(m, "spotify:playlist:5XsXwH5uWdhpAWsigjWMTA")
+	assert.NoError(t, err)
+	assert.Equal(t, "Test Playlist", md.Title)
+	assert.Equal(t, "Test Owner", md.Artist)
+	assert.Equal(t, "", md.ImageURL)
+}
+
+func TestResolveMetadataArtist(t *testing.T) {
+	m := new(mocks.Client)
+	a := &spotify.FullArtist{}
+	a.Name = "Test Artist"
+	a.Images = []spotify.Image{{URL: "https://i.scdn.co/image/artist"}}
+	m.On("GetArtist", spotify.ID("0OdUWJ0sBjDrqHygGUXeCF")).Return(a, nil)
+
+	md, err := ResolveMetadata(m, "spotify:artist:0OdUWJ0sBjDrqHygGUXeCF")
+	assert.NoError(t, err)
+	assert.Equal(t, "Test Artist", md.Title)
+	assert.Equal(t, "", md.Artist)
+	assert.Equal(t, "https://i.scdn.co/image/artist", md.ImageURL)
+	assert.Equal(t, "https://open.spotify.com/artist/0OdUWJ0sBjDrqHygGUXeCF", md.WebURL)
+}
+
+func TestResolveMetadataShow(t *testing.T) {
+	m := new(mocks.Client)
+	sh := &spotify.FullShow{}
+	sh.Name = "Test Show"
+	sh.Publisher = "Test Publisher"
+	m.On("GetShow", "4rOoJ6Egrf8K2IrywzwOMk").Return(sh, nil)
+
+	md, err := ResolveMetadata(m, "spotify:show:4rOoJ6Egrf8K2IrywzwOMk")
+	assert.NoError(t, err)
+	assert.Equal(t, "Test Show", md.Title)
+	assert.Equal(t, "Test Publisher", md.Artist)
+}
+
+func TestResolveMetadataTrack(t *testing.T) {
+	m := new(mocks.Client)
+	tr := &spotify.FullTrack{}
+	tr.Name = "Test Track"
+	tr.Artists = []spotify.SimpleArtist{{Name: "Artist One"}}
+	tr.Album.Images = []spotify.Image{{URL: "https://i.scdn.co/image/album"}}
+	m.On("GetTrack", spotify.ID("4uLU6hMCjMI75M1A2tKUQC")).Return(tr, nil)
+
+	md, err := ResolveMetadata(m, "spotify:track:4uLU6hMCjMI75M1A2tKUQC")
+	assert.NoError(t, err)
+	assert.Equal(t, "Test Track", md.Title)
+	assert.Equal(t, "Artist One", md.Artist)
+	assert.Equal(t, "https://i.scdn.co/image/album", md.ImageURL)
+}
+
+func TestResolveMetadataSmart(t *testing.T) {
+	md, err := ResolveMetadata(new(mocks.Client), "diskplayer:liked_songs")
+	assert.NoError(t, err)
+	assert.Equal(t, &Metadata{URI: "diskplayer:liked_songs", Title: "Liked Songs"}, md)
+
+	_, err = ResolveMetadata(new(mocks.Client), "diskplayer:florble")
+	assert.EqualError(t, err, "invalid Spotify URI: diskplayer:florble")
+}
+
+func TestResolveMetadataClientError(t *testing.T) {
+	m := new(mocks.Client)
+	const e = "GetAlbum error"
+	m.On("GetAlbum", spotify.ID("1S7mumn7D4riEX2gVWYgPO")).Return(nil, errors.New(e))
+
+	md, err := ResolveMetadata(m, "spotify:album:1S7mumn7D4riEX2gVWYgPO")
+	assert.Nil(t, md)
+	assert.EqualError(t, err, e)
+}
+
+func TestResolveMetadataInvalidUriError(t *testing.T) {
+	m := new(mocks.Client)
+	md, err := ResolveMetadata(m, "florble")
+	assert.Nil(t, md)
+	assert.EqualError(t, err, "invalid Spotify URI: florble")
+}
+
+var imageUrlTests = []struct {
+	name string
+	in   []spotify.Image
+	out  string
+}{
+	{"none", nil, ""},
+	{"widest last", []spotify.Image{{URL: "small", Width: 64}, {URL: "medium", Width: 300}, {URL: "large", Width: 640}},
+		"large"},
+	{"widest first", []spotify.Image{{URL: "large", Width: 640}, {URL: "small", Width: 64}}, "large"},
+	{"no widths", []spotify.Image{{URL: "first"}, {URL: "second"}}, "first"},
+}
+
+func TestImageUrl(t *testing.T) {
+	for _, tt := range imageUrlTests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.out, imageUrl(tt.in))
+		})
+	}
+}
@@ -10,6 +10,305 @@ type Client struct {
 	mock.Mock
 }
 
+// CurrentUsersAlbumsOpt provides a mock function with given fields: opt
+func (_m *Client) CurrentUsersAlbumsOpt(opt *spotify.Options) (*spotify.SavedAlbumPage, error) {
+	ret := _m.Called(opt)
+
+	var r0 *spotify.SavedAlbumPage
+	if rf, ok := ret.Get(0).(func(*spotify.Options) *spotify.SavedAlbumPage); ok {
+		r0 = rf(opt)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*spotify.SavedAlbumPage)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*spotify.Options) error); ok {
+		r1 = rf(opt)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CurrentUsersPlaylistsOpt provides a mock function with given fields: opt
+func (_m *Client) CurrentUsersPlaylistsOpt(opt *spotify.Options) (*spotify.SimplePlaylistPage, error) {
+	ret := _m.Called(opt)
+
+	var r0 *spotify.SimplePlaylistPage
+	if rf, ok := ret.Get(0).(func(*spotify.Options) *spotify.SimplePlaylistPage); ok {
+		r0 = rf(opt)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*spotify.SimplePlaylistPage)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*spotify.Options) error); ok {
+		r1 = rf(opt)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CurrentUsersShowsOpt provides a mock function with given fields: opt
+func (_m *Client) CurrentUsersShowsOpt(opt *spotify.Options) (*spotify.SavedShowPage, error) {
+	ret := _m.Called(opt)
+
+	var r0 *spotify.SavedShowPage
+	if rf, ok := ret.Get(0).(func(*spotify.Options) *spotify.SavedShowPage); ok {
+		r0 = rf(opt)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*spotify.SavedShowPage)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*spotify.Options) error); ok {
+		r1 = rf(opt)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CurrentUsersTopTracksOpt provides a mock function with given fields: opt
+func (_m *Client) CurrentUsersTopTracksOpt(opt *spotify.Options) (*spotify.FullTrackPage, error) {
+	ret := _m.Called(opt)
+
+	var r0 *spotify.FullTrackPage
+	if rf, ok := ret.Get(0).(func(*spotify.Options) *spotify.FullTrackPage); ok {
+		r0 = rf(opt)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*spotify.FullTrackPage)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*spotify.Options) error); ok {
+		r1 = rf(opt)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CurrentUsersTracksOpt provides a mock function with given fields: opt
+func (_m *Client) CurrentUsersTracksOpt(opt *spotify.Options) (*spotify.SavedTrackPage, error) {
+	ret := _m.Called(opt)
+
+	var r0 *spotify.SavedTrackPage
+	if rf, ok := ret.Get(0).(func(*spotify.Options) *spotify.SavedTrackPage); ok {
+		r0 = rf(opt)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*spotify.SavedTrackPage)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*spotify.Options) error); ok {
+		r1 = rf(opt)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetAlbum provides a mock function with given fields: id
+func (_m *Client) GetAlbum(id spotify.ID) (*spotify.FullAlbum, error) {
+	ret := _m.Called(id)
+
+	var r0 *spotify.FullAlbum
+	if rf, ok := ret.Get(0).(func(spotify.ID) *spotify.FullAlbum); ok {
+		r0 = rf(id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*spotify.FullAlbum)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(spotify.ID) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetArtist provides a mock function with given fields: id
+func (_m *Client) GetArtist(id spotify.ID) (*spotify.FullArtist, error) {
+	ret := _m.Called(id)
+
+	var r0 *spotify.FullArtist
+	if rf, ok := ret.Get(0).(func(spotify.ID) *spotify.FullArtist); ok {
+		r0 = rf(id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*spotify.FullArtist)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(spotify.ID) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPlaylist provides a mock function with given fields: id
+func (_m *Client) GetPlaylist(id spotify.ID) (*spotify.FullPlaylist, error) {
+	ret := _m.Called(id)
+
+	var r0 *spotify.FullPlaylist
+	if rf, ok := ret.Get(0).(func(spotify.ID) *spotify.FullPlaylist); ok {
+		r0 = rf(id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*spotify.FullPlaylist)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(spotify.ID) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetPlaylistTracksOpt provides a mock function with given fields: id, opt, fields
+func (_m *Client) GetPlaylistTracksOpt(id spotify.ID, opt *spotify.Options, fields string) (*spotify.PlaylistTrackPage, error) {
+	ret := _m.Called(id, opt, fields)
+
+	var r0 *spotify.PlaylistTrackPage
+	if rf, ok := ret.Get(0).(func(spotify.ID, *spotify.Options, string) *spotify.PlaylistTrackPage); ok {
+		r0 = rf(id, opt, fields)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*spotify.PlaylistTrackPage)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(spotify.ID, *spotify.Options, string) error); ok {
+		r1 = rf(id, opt, fields)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetRecommendations provides a mock function with given fields: seeds, attrs, opt
+func (_m *Client) GetRecommendations(seeds spotify.Seeds, attrs *spotify.TrackAttributes, opt *spotify.Options) (*spotify.Recommendations, error) {
+	ret := _m.Called(seeds, attrs, opt)
+
+	var r0 *spotify.Recommendations
+	if rf, ok := ret.Get(0).(func(spotify.Seeds, *spotify.TrackAttributes, *spotify.Options) *spotify.Recommendations); ok {
+		r0 = rf(seeds, attrs, opt)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*spotify.Recommendations)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(spotify.Seeds, *spotify.TrackAttributes, *spotify.Options) error); ok {
+		r1 = rf(seeds, attrs, opt)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetShow provides a mock function with given fields: id
+func (_m *Client) GetShow(id string) (*spotify.FullShow, error) {
+	ret := _m.Called(id)
+
+	var r0 *spotify.FullShow
+	if rf, ok := ret.Get(0).(func(string) *spotify.FullShow); ok {
+		r0 = rf(id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*spotify.FullShow)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetShowEpisodesOpt provides a mock function with given fields: opt, id
+func (_m *Client) GetShowEpisodesOpt(opt *spotify.Options, id string) (*spotify.SimpleEpisodePage, error) {
+	ret := _m.Called(opt, id)
+
+	var r0 *spotify.SimpleEpisodePage
+	if rf, ok := ret.Get(0).(func(*spotify.Options, string) *spotify.SimpleEpisodePage); ok {
+		r0 = rf(opt, id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*spotify.SimpleEpisodePage)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*spotify.Options, string) error); ok {
+		r1 = rf(opt, id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// GetTrack provides a mock function with given fields: id
+func (_m *Client) GetTrack(id spotify.ID) (*spotify.FullTrack, error) {
+	ret := _m.Called(id)
+
+	var r0 *spotify.FullTrack
+	if rf, ok := ret.Get(0).(func(spotify.ID) *spotify.FullTrack); ok {
+		r0 = rf(id)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*spotify.FullTrack)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(spotify.ID) error); ok {
+		r1 = rf(id)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // Pause provides a mock function with given fields:
 func (_m *Client) Pause() error {
 	ret := _m.Called()
@@ -38,6 +337,29 @@ func (_m *Client) PlayOpt(opt *spotify.PlayOptions) error {
 	return r0
 }
 
+// PlayerCurrentlyPlaying provides a mock function with given fields:
+func (_m *Client) PlayerCurrentlyPlaying() (*spotify.CurrentlyPlaying, error) {
+	ret := _m.Called()
+
+	var r0 *spotify.CurrentlyPlaying
+	if rf, ok := ret.Get(0).(func() *spotify.CurrentlyPlaying); ok {
+		r0 = rf()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*spotify.CurrentlyPlaying)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func() error); ok {
+		r1 = rf()
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // PlayerDevices provides a mock function with given fields:
 func (_m *Client) PlayerDevices() ([]spotify.PlayerDevice, error) {
 	ret := _m.Called()
@@ -61,6 +383,89 @@ func (_m *Client) PlayerDevices() ([]spotify.PlayerDevice, error) {
 	return r0, r1
 }
 
+// PlayerRecentlyPlayedOpt provides a mock function with given fields: opt
+func (_m *Client) PlayerRecentlyPlayedOpt(opt *spotify.RecentlyPlayedOptions) ([]spotify.RecentlyPlayedItem, error) {
+	ret := _m.Called(opt)
+
+	var r0 []spotify.RecentlyPlayedItem
+	if rf, ok := ret.Get(0).(func(*spotify.RecentlyPlayedOptions) []spotify.RecentlyPlayedItem); ok {
+		r0 = rf(opt)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]spotify.RecentlyPlayedItem)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(*spotify.RecentlyPlayedOptions) error); ok {
+		r1 = rf(opt)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// QueueSong provides a mock function with given fields: trackID
+func (_m *Client) QueueSong(trackID spotify.ID) error {
+	ret := _m.Called(trackID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(spotify.ID) error); ok {
+		r0 = rf(trackID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Search provides a mock function with given fields: query, t, limit
+func (_m *Client) Search(query string, t spotify.SearchType, limit int) (*spotify.SearchResult, error) {
+	ret := _m.Called(query, t, limit)
+
+	var r0 *spotify.SearchResult
+	if rf, ok := ret.Get(0).(func(string, spotify.SearchType, int) *spotify.SearchResult); ok {
+		r0 = rf(query, t, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*spotify.SearchResult)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, spotify.SearchType, int) error); ok {
+		r1 = rf(query, t, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// SearchShows provides a mock function with given fields: query, limit
+func (_m *Client) SearchShows(query string, limit int) ([]spotify.SimpleShow, error) {
+	ret := _m.Called(query, limit)
+
+	var r0 []spotify.SimpleShow
+	if rf, ok := ret.Get(0).(func(string, int) []spotify.SimpleShow); ok {
+		r0 = rf(query, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]spotify.SimpleShow)
+		}
+	}
+
+	var r1 error
+	if rf, ok := ret.Get(1).(func(string, int) error); ok {
+		r1 = rf(query, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // TransferPlayback provides a mock function with given fields: deviceID, play
 func (_m *Client) TransferPlayback(deviceID spotify.ID, play bool) error {
 	ret := _m.Called(deviceID, play)
@@ -0,0 +1,88 @@
+package diskplayer
+
+import (
+	"fmt"
+	"github.com/docker/docker/pkg/mount"
+)
+
+// Mounter mounts and unmounts disk devices so that their contents can be read and written.
+type Mounter interface {
+	// Mount mounts the device using the filesystem type and options, returning the path at which it is mounted. An
+	// AlreadyMountedError is returned if the device was already mounted by someone else, and must not be unmounted.
+	Mount(device, fstype, options string) (string, error)
+	// Unmount unmounts the device.
+	Unmount(device string) error
+	// MountPoint returns the path at which the device will be mounted, or an empty string if it is only known once
+	// the device has been mounted.
+	MountPoint(device string) string
+}
+
+// AlreadyMountedError is returned by a Mounter when the device is already mounted, e.g. by the automounter of a
+// desktop, along with the path at which it is mounted. The device may be used there, but as the mount was not made by
+// the Mounter it is left in place afterwards.
+type AlreadyMountedError struct {
+	Device string
+	Folder string
+}
+
+func (e *AlreadyMountedError) Error() string {
+	return fmt.Sprintf("%s is already mounted at %s", e.Device, e.Folder)
+}
+
+// FilesystemProber is implemented by a Mounter which can find the filesystem on a device without the device being read
+// directly, which would otherwise require root privileges.
+type FilesystemProber interface {
+	// ProbeFilesystem returns the filesystem found on the device, as described for the ProbeFilesystem function.
+	ProbeFilesystem(device string) (*Filesystem, error)
+}
+
+// NewMounter returns the Mounter identified in the diskplayer.yaml configuration file under the recorder.mounter
+// field. This can be one of:
+// "syscall", which mounts the device to the folder specified under the recorder.folder_path field and requires root,
+// "udisks2", which asks the udisks2 daemon to mount the device on behalf of an unprivileged user, or
+// "fake", which does not mount anything, instead using a folder for each device beneath recorder.folder_path.
+// An error is returned if the mounter is not known.
+func NewMounter() (Mounter, error) {
+	switch m := ConfigValue(RECORD_MOUNTER); m {
+	case "syscall":
+		return &SyscallMounter{Folder: ConfigValue(RECORD_FOLDER_PATH)}, nil
+	case "udisks2":
+		return &UdisksMounter{}, nil
+	case "fake":
+		return NewFakeMounter(ConfigValue(RECORD_FOLDER_PATH)), nil
+	default:
+		return nil, fmt.Errorf("unknown mounter: %s", m)
+	}
+}
+
+// SyscallMounter mounts devices to a fixed folder using the mount system call, which requires root privileges.
+type SyscallMounter struct {
+	Folder string
+}
+
+// Mount mounts the device to the folder. An error is returned if something is already mounted there.
+func (m *SyscallMounter) Mount(device, fstype, options string) (string, error) {
+	mounted, err := mount.Mounted(m.Folder)
+	if err != nil {
+		return "", err
+	}
+	if mounted {
+		return "", fmt.Errorf("%s is already mounted", m.Folder)
+	}
+
+	err = mount.Mount(device, m.Folder, fstype, options)
+	if err != nil {
+		return "", err
+	}
+	return m.Folder, nil
+}
+
+// Unmount unmounts the folder.
+func (m *SyscallMounter) Unmount(device string) error {
+	return mount.Unmount(m.Folder)
+}
+
+// MountPoint returns the folder, to which every device is mounted.
+func (m *SyscallMounter) MountPoint(device string) string {
+	return m.Folder
+}
@@ -0,0 +1,99 @@
+package diskplayer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FakeMounter is an in-memory Mounter which does not mount anything. Each device is instead given a folder beneath
+// Root, which persists between mounts in the same way as the contents of a disk. It is intended for tests and for
+// trying out the recorder without a disk drive.
+// If MountErr or UnmountErr are set they are returned by Mount and Unmount respectively.
+type FakeMounter struct {
+	Root       string
+	MountErr   error
+	UnmountErr error
+
+	mu       sync.Mutex
+	mounted  map[string]string
+	mounts   int
+	unmounts int
+}
+
+// NewFakeMounter returns a FakeMounter which keeps device folders beneath the root folder.
+func NewFakeMounter(root string) *FakeMounter {
+	return &FakeMounter{Root: root}
+}
+
+// Mount records the device as mounted and returns its folder, creating it if necessary.
+// An error is returned if the device is already mounted.
+func (m *FakeMounter) Mount(device, fstype, options string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.MountErr != nil {
+		return "", m.MountErr
+	}
+	if _, ok := m.mounted[device]; ok {
+		return "", fmt.Errorf("%s is already mounted", device)
+	}
+
+	p := m.Folder(device)
+	err := os.MkdirAll(p, 0755)
+	if err != nil {
+		return "", err
+	}
+
+	if m.mounted == nil {
+		m.mounted = map[string]string{}
+	}
+	m.mounted[device] = p
+	m.mounts++
+	return p, nil
+}
+
+// Unmount records the device as no longer mounted. An error is returned if it is not mounted.
+func (m *FakeMounter) Unmount(device string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.UnmountErr != nil {
+		return m.UnmountErr
+	}
+	if _, ok := m.mounted[device]; !ok {
+		return fmt.Errorf("%s is not mounted", device)
+	}
+
+	delete(m.mounted, device)
+	m.unmounts++
+	return nil
+}
+
+// Folder returns the folder holding the contents of the device.
+func (m *FakeMounter) Folder(device string) string {
+	return filepath.Join(m.Root, filepath.Base(device))
+}
+
+// MountPoint returns the folder holding the contents of the device.
+func (m *FakeMounter) MountPoint(device string) string {
+	return m.Folder(device)
+}
+
+// Mounted returns true if the device is currently mounted.
+func (m *FakeMounter) Mounted(device string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	_, ok := m.mounted[device]
+	return ok
+}
+
+// Counts returns the number of successful mounts and unmounts.
+func (m *FakeMounter) Counts() (int, int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.mounts, m.unmounts
+}
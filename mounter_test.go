@@ -0,0 +1,175 @@
+package diskplayer
+
+import (
+	"errors"
+	"fmt"
+	"github.com/godbus/dbus/v5"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewMounter(t *testing.T) {
+	viper.Set("recorder.folder_path", "/tmp/diskplayer")
+	defer viper.Set("recorder.mounter", "")
+
+	viper.Set("recorder.mounter", "syscall")
+	m, err := NewMounter()
+	assert.NoError(t, err)
+	assert.Equal(t, &SyscallMounter{Folder: "/tmp/diskplayer"}, m)
+
+	viper.Set("recorder.mounter", "udisks2")
+	m, err = NewMounter()
+	assert.NoError(t, err)
+	assert.IsType(t, &UdisksMounter{}, m)
+
+	viper.Set("recorder.mounter", "fake")
+	m, err = NewMounter()
+	assert.NoError(t, err)
+	assert.Equal(t, "/tmp/diskplayer", m.(*FakeMounter).Root)
+
+	viper.Set("recorder.mounter", "florble")
+	m, err = NewMounter()
+	assert.Nil(t, m)
+	assert.EqualError(t, err, "unknown mounter: florble")
+}
+
+func TestFakeMounter(t *testing.T) {
+	d, err := ioutil.TempDir("", "diskplayer_mounter")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+
+	m := NewFakeMounter(d)
+	p, err := m.Mount("/dev/sda", "vfat", "flush")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(d, "sda"), p)
+	assert.True(t, m.Mounted("/dev/sda"))
+	assert.DirExists(t, p)
+
+	_, err = m.Mount("/dev/sda", "vfat", "flush")
+	assert.EqualError(t, err, "/dev/sda is already mounted")
+
+	err = ioutil.WriteFile(filepath.Join(p, "diskplayer.contents"), []byte("spotify:album:abc"), 0644)
+	assert.NoError(t, err)
+
+	assert.NoError(t, m.Unmount("/dev/sda"))
+	assert.False(t, m.Mounted("/dev/sda"))
+	assert.EqualError(t, m.Unmount("/dev/sda"), "/dev/sda is not mounted")
+
+	p, err = m.Mount("/dev/sda", "vfat", "")
+	assert.NoError(t, err)
+	b, err := ioutil.ReadFile(filepath.Join(p, "diskplayer.contents"))
+	assert.NoError(t, err)
+	assert.Equal(t, "spotify:album:abc", string(b))
+
+	mounts, unmounts := m.Counts()
+	assert.Equal(t, 2, mounts)
+	assert.Equal(t, 1, unmounts)
+}
+
+func TestFakeMounterErrors(t *testing.T) {
+	m := &FakeMounter{Root: os.TempDir(), MountErr: errors.New("mount error")}
+	_, err := m.Mount("/dev/sda", "vfat", "")
+	assert.EqualError(t, err, "mount error")
+
+	m = &FakeMounter{Root: os.TempDir(), UnmountErr: errors.New("unmount error")}
+	assert.EqualError(t, m.Unmount("/dev/sda"), "unmount error")
+}
+
+// udisksDevice is a udisks2 block device object holding a filesystem with the given properties, which is mounted to
+// a folder, or is already mounted there if automounted is set. The methods called on it are recorded.
+type udisksDevice struct {
+	dbus.BusObject
+	folder      string
+	automounted bool
+	props       map[string]string
+	calls       []string
+}
+
+func (d *udisksDevice) Call(method string, flags dbus.Flags, args ...interface{}) *dbus.Call {
+	d.calls = append(d.calls, method)
+	if method == udisksFilesystem+".Mount" && d.automounted {
+		return &dbus.Call{Err: dbus.Error{Name: udisksAlreadyMounted}}
+	}
+	if method == udisksFilesystem+".Mount" {
+		return &dbus.Call{Body: []interface{}{d.folder}}
+	}
+	return &dbus.Call{}
+}
+
+func (d *udisksDevice) GetProperty(p string) (dbus.Variant, error) {
+	if p == udisksFilesystem+".MountPoints" && d.automounted {
+		return dbus.MakeVariant([][]byte{[]byte(d.folder + "\x00")}), nil
+	}
+	v, ok := d.props[p]
+	if !ok {
+		return dbus.Variant{}, fmt.Errorf("no such property: %s", p)
+	}
+	return dbus.MakeVariant(v), nil
+}
+
+// useUdisksDevice makes the udisks2 device the one resolved for every device path, returning a function which undoes
+// this.
+func useUdisksDevice(d *udisksDevice) func() {
+	o := udisksObject
+	udisksObject = func(device string) (dbus.BusObject, error) {
+		return d, nil
+	}
+	return func() { udisksObject = o }
+}
+
+var udisksProbeTests = []struct {
+	name  string
+	props map[string]string
+	out   *Filesystem
+	e     string
+}{
+	{"vfat", map[string]string{udisksBlock + ".IdType": "vfat", udisksBlock + ".IdUUID": "ABCD-1234",
+		udisksBlock + ".IdLabel": "DISKPLAYER"},
+		&Filesystem{Type: "vfat", Options: "flush", UUID: "ABCD-1234", Label: "DISKPLAYER"}, ""},
+	{"iso9660", map[string]string{udisksBlock + ".IdType": "iso9660", udisksBlock + ".IdUUID": "",
+		udisksBlock + ".IdLabel": ""}, &Filesystem{Type: "iso9660", Options: "ro", ReadOnly: true}, ""},
+	{"unformatted", map[string]string{udisksBlock + ".IdType": "", udisksBlock + ".IdUUID": "",
+		udisksBlock + ".IdLabel": ""}, nil,
+		"no filesystem found on /dev/sdz, the disk may be blank or need to be formatted"},
+	{"unsupported", map[string]string{udisksBlock + ".IdType": "ntfs", udisksBlock + ".IdUUID": "",
+		udisksBlock + ".IdLabel": ""}, nil, "unsupported filesystem ntfs found on /dev/sdz"},
+	{"no property", map[string]string{}, nil,
+		"udisks2 unable to probe /dev/sdz: no such property: org.freedesktop.UDisks2.Block.IdType"},
+}
+
+func TestUdisksMounterProbeFilesystem(t *testing.T) {
+	for _, tt := range udisksProbeTests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer useUdisksDevice(&udisksDevice{props: tt.props})()
+
+			fs, err := (&UdisksMounter{}).ProbeFilesystem("/dev/sdz")
+			if tt.e != "" {
+				assert.EqualError(t, err, tt.e)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.out, fs)
+		})
+	}
+}
+
+func TestUdisksMounterMount(t *testing.T) {
+	d := &udisksDevice{folder: "/media/diskplayer/ABCD-1234"}
+	defer useUdisksDevice(d)()
+
+	p, err := (&UdisksMounter{}).Mount("/dev/sdz", "vfat", "flush")
+	assert.NoError(t, err)
+	assert.Equal(t, d.folder, p)
+
+	// A device mounted by someone else is reported along with its mount point, so that it is not unmounted.
+	d.automounted = true
+	_, err = (&UdisksMounter{}).Mount("/dev/sdz", "vfat", "flush")
+	assert.Equal(t, &AlreadyMountedError{Device: "/dev/sdz", Folder: d.folder}, err)
+	assert.EqualError(t, err, "/dev/sdz is already mounted at /media/diskplayer/ABCD-1234")
+}
@@ -0,0 +1,132 @@
+package diskplayer
+
+import (
+	"fmt"
+	"github.com/godbus/dbus/v5"
+	"strings"
+)
+
+const (
+	udisksService        = "org.freedesktop.UDisks2"
+	udisksManagerPath    = "/org/freedesktop/UDisks2/Manager"
+	udisksFilesystem     = "org.freedesktop.UDisks2.Filesystem"
+	udisksBlock          = "org.freedesktop.UDisks2.Block"
+	udisksAlreadyMounted = "org.freedesktop.UDisks2.Error.AlreadyMounted"
+)
+
+// UdisksMounter mounts devices by calling the udisks2 daemon over D-Bus. udisks2 chooses the mount point, typically
+// beneath /media, and permits unprivileged users to mount removable media subject to its polkit rules. The filesystem
+// on a device is also found by udisks2, so that the recorder never needs to read the device itself.
+type UdisksMounter struct{}
+
+// Mount asks udisks2 to mount the device, returning the mount point. If the device is already mounted an
+// AlreadyMountedError holding its existing mount point is returned.
+func (m *UdisksMounter) Mount(device, fstype, options string) (string, error) {
+	o, err := udisksObject(device)
+	if err != nil {
+		return "", err
+	}
+
+	opts := map[string]dbus.Variant{
+		"auth.no_user_interaction": dbus.MakeVariant(true),
+		"fstype":                   dbus.MakeVariant(fstype),
+	}
+	if options != "" {
+		opts["options"] = dbus.MakeVariant(options)
+	}
+
+	var p string
+	err = o.Call(udisksFilesystem+".Mount", 0, opts).Store(&p)
+	if e, ok := err.(dbus.Error); ok && e.Name == udisksAlreadyMounted {
+		p, err = m.mountPoint(o)
+		if err != nil {
+			return "", err
+		}
+		return "", &AlreadyMountedError{Device: device, Folder: p}
+	}
+	if err != nil {
+		return "", fmt.Errorf("udisks2 unable to mount %s: %s", device, err)
+	}
+	return p, nil
+}
+
+// Unmount asks udisks2 to unmount the device.
+func (m *UdisksMounter) Unmount(device string) error {
+	o, err := udisksObject(device)
+	if err != nil {
+		return err
+	}
+
+	opts := map[string]dbus.Variant{"auth.no_user_interaction": dbus.MakeVariant(true)}
+	err = o.Call(udisksFilesystem+".Unmount", 0, opts).Err
+	if err != nil {
+		return fmt.Errorf("udisks2 unable to unmount %s: %s", device, err)
+	}
+	return nil
+}
+
+// MountPoint returns an empty string, as udisks2 chooses a separate mount point for each device when mounting it.
+func (m *UdisksMounter) MountPoint(device string) string {
+	return ""
+}
+
+// ProbeFilesystem returns the filesystem which udisks2 found on the device, as described for the ProbeFilesystem
+// function.
+// An UnformattedError is returned if udisks2 found no filesystem, or any other error that is encountered.
+func (m *UdisksMounter) ProbeFilesystem(device string) (*Filesystem, error) {
+	o, err := udisksObject(device)
+	if err != nil {
+		return nil, err
+	}
+
+	var ps [3]string
+	for i, n := range []string{"IdType", "IdUUID", "IdLabel"} {
+		v, err := o.GetProperty(udisksBlock + "." + n)
+		if err != nil {
+			return nil, fmt.Errorf("udisks2 unable to probe %s: %s", device, err)
+		}
+		ps[i], _ = v.Value().(string)
+	}
+
+	fs, err := newFilesystem(device, ps[0])
+	if err != nil {
+		return nil, err
+	}
+	fs.UUID, fs.Label = ps[1], ps[2]
+	return fs, nil
+}
+
+// udisksObject returns the udisks2 block device object for the device path.
+var udisksObject = func(device string) (dbus.BusObject, error) {
+	c, err := dbus.SystemBus()
+	if err != nil {
+		return nil, err
+	}
+
+	var ps []dbus.ObjectPath
+	spec := map[string]dbus.Variant{"path": dbus.MakeVariant(device)}
+	err = c.Object(udisksService, udisksManagerPath).
+		Call(udisksService+".Manager.ResolveDevice", 0, spec, map[string]dbus.Variant{}).Store(&ps)
+	if err != nil {
+		return nil, fmt.Errorf("udisks2 unable to resolve %s: %s", device, err)
+	}
+	if len(ps) == 0 {
+		return nil, fmt.Errorf("udisks2 does not know of device %s", device)
+	}
+
+	return c.Object(udisksService, ps[0]), nil
+}
+
+// mountPoint returns the first mount point of the udisks2 filesystem object.
+func (m *UdisksMounter) mountPoint(o dbus.BusObject) (string, error) {
+	v, err := o.GetProperty(udisksFilesystem + ".MountPoints")
+	if err != nil {
+		return "", err
+	}
+
+	ps, ok := v.Value().([][]byte)
+	if !ok || len(ps) == 0 {
+		return "", fmt.Errorf("udisks2 reported no mount point for %s", o.Path())
+	}
+	return strings.TrimRight(string(ps[0]), "\x00"), nil
+}
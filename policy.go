@@ -0,0 +1,105 @@
+package diskplayer
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// DevicePolicy describes which block devices the recorder is allowed to mount and write to.
+// Only removable devices which do not hold the running operating system are allowed. Devices larger than MaxSize,
+// or matching any of the Deny rules, are refused. If any Allow rules are given the device must match one of them.
+// Rules are glob patterns matched against the device path, or against the device model if prefixed with "model:".
+type DevicePolicy struct {
+	MaxSize int64
+	Allow   []string
+	Deny    []string
+}
+
+// NewDevicePolicy returns the device policy defined in the diskplayer.yaml configuration file under the
+// recorder.policy fields.
+func NewDevicePolicy() *DevicePolicy {
+	return &DevicePolicy{
+		MaxSize: ConfigSize(RECORD_POLICY_MAXSIZE),
+		Allow:   ConfigValues(RECORD_POLICY_ALLOW),
+		Deny:    ConfigValues(RECORD_POLICY_DENY),
+	}
+}
+
+// Check returns the device identified by the path if it is permitted by the policy.
+// The path must refer to one of the provided removable devices, either directly or through a symbolic link such as
+// /dev/disk/by-id/usb-TEAC_FD-05PUB.
+// An error describing the violation is returned if the device is not permitted.
+func (p *DevicePolicy) Check(path string, ds []BlockDevice) (*BlockDevice, error) {
+	if path == "" {
+		return nil, fmt.Errorf("device path is required")
+	}
+
+	r, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		r = path
+	}
+
+	for _, d := range ds {
+		if d.Path == path || d.Path == r {
+			err := p.check(d)
+			if err != nil {
+				return nil, err
+			}
+			return &d, nil
+		}
+	}
+
+	return nil, fmt.Errorf("device %s is not an attached removable device", path)
+}
+
+// Filter returns the devices from the list which are permitted by the policy.
+func (p *DevicePolicy) Filter(ds []BlockDevice) []BlockDevice {
+	f := []BlockDevice{}
+	for _, d := range ds {
+		if p.check(d) == nil {
+			f = append(f, d)
+		}
+	}
+	return f
+}
+
+// check returns an error describing why the device is not permitted by the policy, or nil if it is.
+func (p *DevicePolicy) check(d BlockDevice) error {
+	if !d.Removable {
+		return fmt.Errorf("device %s is not removable", d.Path)
+	}
+	if d.System {
+		return fmt.Errorf("device %s is a system disk", d.Path)
+	}
+	if p.MaxSize > 0 && d.Size > p.MaxSize {
+		return fmt.Errorf("device %s is %s, larger than the maximum allowed size of %s", d.Path,
+			formatSize(d.Size), formatSize(p.MaxSize))
+	}
+	for _, r := range p.Deny {
+		if matchDeviceRule(r, d) {
+			return fmt.Errorf("device %s is denied by rule \"%s\"", d.Path, r)
+		}
+	}
+	if len(p.Allow) == 0 {
+		return nil
+	}
+	for _, r := range p.Allow {
+		if matchDeviceRule(r, d) {
+			return nil
+		}
+	}
+	return fmt.Errorf("device %s does not match any allow rule", d.Path)
+}
+
+// matchDeviceRule returns true if the device matches the rule. Rules prefixed with "model:" are matched against the
+// device model, otherwise they are matched against the device path.
+func matchDeviceRule(r string, d BlockDevice) bool {
+	v := d.Path
+	if strings.HasPrefix(r, "model:") {
+		r = strings.TrimPrefix(r, "model:")
+		v = d.Model
+	}
+	m, err := filepath.Match(r, v)
+	return err == nil && m
+}
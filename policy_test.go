@@ -0,0 +1,76 @@
+package diskplayer
+
+import (
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+var policyDevices = []BlockDevice{
+	{Path: "/dev/sda", Size: 1474560, Model: "TEAC FD-05PUB", Removable: true},
+	{Path: "/dev/sdb1", Size: 8052015104, Model: "DataTraveler", Removable: true},
+	{Path: "/dev/sdc2", Size: 31741444096, Model: "Ultra Fit", Removable: true, System: true},
+	{Path: "/dev/mmcblk0p2", Size: 31642353664, Removable: false},
+	{Path: "/dev/mmcblk1", Size: 15931539456, Model: "SD Card", Removable: true},
+}
+
+var policyTests = []struct {
+	name   string
+	policy DevicePolicy
+	path   string
+	e      string
+}{
+	{"floppy", DevicePolicy{}, "/dev/sda", ""},
+	{"not attached", DevicePolicy{}, "/dev/sdz", "device /dev/sdz is not an attached removable device"},
+	{"empty path", DevicePolicy{}, "", "device path is required"},
+	{"not removable", DevicePolicy{}, "/dev/mmcblk0p2", "device /dev/mmcblk0p2 is not removable"},
+	{"system disk", DevicePolicy{}, "/dev/sdc2", "device /dev/sdc2 is a system disk"},
+	{"too large", DevicePolicy{MaxSize: 2 << 30}, "/dev/sdb1",
+		"device /dev/sdb1 is 7.5 GiB, larger than the maximum allowed size of 2.0 GiB"},
+	{"denied path", DevicePolicy{Deny: []string{"/dev/mmcblk*"}}, "/dev/mmcblk1",
+		"device /dev/mmcblk1 is denied by rule \"/dev/mmcblk*\""},
+	{"allowed model", DevicePolicy{Allow: []string{"model:TEAC*"}}, "/dev/sda", ""},
+	{"not allowed model", DevicePolicy{Allow: []string{"model:TEAC*"}}, "/dev/sdb1",
+		"device /dev/sdb1 does not match any allow rule"},
+	{"deny before allow", DevicePolicy{Allow: []string{"/dev/sd*"}, Deny: []string{"model:Data*"}}, "/dev/sdb1",
+		"device /dev/sdb1 is denied by rule \"model:Data*\""},
+}
+
+func TestDevicePolicyCheck(t *testing.T) {
+	for _, tt := range policyTests {
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := tt.policy.Check(tt.path, policyDevices)
+			if tt.e == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.path, d.Path)
+			} else {
+				assert.Nil(t, d)
+				assert.EqualError(t, err, tt.e)
+			}
+		})
+	}
+}
+
+func TestDevicePolicyFilter(t *testing.T) {
+	p := DevicePolicy{Deny: []string{"/dev/mmcblk*"}}
+	ds := p.Filter(policyDevices)
+	assert.Len(t, ds, 2)
+	assert.Equal(t, "/dev/sda", ds[0].Path)
+	assert.Equal(t, "/dev/sdb1", ds[1].Path)
+}
+
+func TestNewDevicePolicy(t *testing.T) {
+	viper.Set("recorder.policy.max_size", "1440KB")
+	viper.Set("recorder.policy.allow", []string{"model:TEAC*"})
+	viper.Set("recorder.policy.deny", []string{"/dev/mmcblk0*"})
+	defer func() {
+		viper.Set("recorder.policy.max_size", "")
+		viper.Set("recorder.policy.allow", nil)
+		viper.Set("recorder.policy.deny", nil)
+	}()
+
+	p := NewDevicePolicy()
+	assert.Equal(t, int64(1440*1024), p.MaxSize)
+	assert.Equal(t, []string{"model:TEAC*"}, p.Allow)
+	assert.Equal(t, []string{"/dev/mmcblk0*"}, p.Deny)
+}
@@ -0,0 +1,256 @@
+package diskplayer
+
+import (
+	"errors"
+	"fmt"
+	"github.com/zmb3/spotify"
+	"log"
+	"time"
+)
+
+// radioBatch is the number of recommended tracks requested from Spotify at a time while a radio is playing.
+const radioBatch = 20
+
+// radioLowWater is the number of tracks left to play below which more recommended tracks are queued.
+const radioLowWater = 3
+
+// radioIdleLimit is the number of times in a row nothing is found playing before a radio is considered stopped.
+const radioIdleLimit = 20
+
+// radioUri identifies radio contents which are seeded by genres alone.
+const radioUri = smartUriPrefix + "radio"
+
+// radioPollInterval is how often the player checks what is playing while a radio is playing.
+var radioPollInterval = 30 * time.Second
+
+// Radio describes an endless radio of tracks recommended by Spotify. The seeds are the Spotify URIs of artists and
+// tracks, and together with the genres there may be at most five of them. The tuning holds target, minimum and
+// maximum values for the attributes of the recommended tracks, named as they are by the Spotify Web API, e.g.
+// "target_energy" or "min_tempo".
+type Radio struct {
+	Seeds  []string           `json:"seeds,omitempty"`
+	Genres []string           `json:"genres,omitempty"`
+	Tuning map[string]float64 `json:"tuning,omitempty"`
+}
+
+// tuning sets the value of an attribute of recommended tracks.
+type tuning func(*spotify.TrackAttributes, float64) *spotify.TrackAttributes
+
+// radioTunings holds the function setting each tuning value a radio may hold.
+var radioTunings = map[string]tuning{
+	"min_acousticness":        (*spotify.TrackAttributes).MinAcousticness,
+	"max_acousticness":        (*spotify.TrackAttributes).MaxAcousticness,
+	"target_acousticness":     (*spotify.TrackAttributes).TargetAcousticness,
+	"min_danceability":        (*spotify.TrackAttributes).MinDanceability,
+	"max_danceability":        (*spotify.TrackAttributes).MaxDanceability,
+	"target_danceability":     (*spotify.TrackAttributes).TargetDanceability,
+	"min_duration_ms":         intTuning((*spotify.TrackAttributes).MinDuration),
+	"max_duration_ms":         intTuning((*spotify.TrackAttributes).MaxDuration),
+	"target_duration_ms":      intTuning((*spotify.TrackAttributes).TargetDuration),
+	"min_energy":              (*spotify.TrackAttributes).MinEnergy,
+	"max_energy":              (*spotify.TrackAttributes).MaxEnergy,
+	"target_energy":           (*spotify.TrackAttributes).TargetEnergy,
+	"min_instrumentalness":    (*spotify.TrackAttributes).MinInstrumentalness,
+	"max_instrumentalness":    (*spotify.TrackAttributes).MaxInstrumentalness,
+	"target_instrumentalness": (*spotify.TrackAttributes).TargetInstrumentalness,
+	"min_key":                 intTuning((*spotify.TrackAttributes).MinKey),
+	"max_key":                 intTuning((*spotify.TrackAttributes).MaxKey),
+	"target_key":              intTuning((*spotify.TrackAttributes).TargetKey),
+	"min_liveness":            (*spotify.TrackAttributes).MinLiveness,
+	"max_liveness":            (*spotify.TrackAttributes).MaxLiveness,
+	"target_liveness":         (*spotify.TrackAttributes).TargetLiveness,
+	"min_loudness":            (*spotify.TrackAttributes).MinLoudness,
+	"max_loudness":            (*spotify.TrackAttributes).MaxLoudness,
+	"target_loudness":         (*spotify.TrackAttributes).TargetLoudness,
+	"min_mode":                intTuning((*spotify.TrackAttributes).MinMode),
+	"max_mode":                intTuning((*spotify.TrackAttributes).MaxMode),
+	"target_mode":             intTuning((*spotify.TrackAttributes).TargetMode),
+	"min_popularity":          intTuning((*spotify.TrackAttributes).MinPopularity),
+	"max_popularity":          intTuning((*spotify.TrackAttributes).MaxPopularity),
+	"target_popularity":       intTuning((*spotify.TrackAttributes).TargetPopularity),
+	"min_speechiness":         (*spotify.TrackAttributes).MinSpeechiness,
+	"max_speechiness":         (*spotify.TrackAttributes).MaxSpeechiness,
+	"target_speechiness":      (*spotify.TrackAttributes).TargetSpeechiness,
+	"min_tempo":               (*spotify.TrackAttributes).MinTempo,
+	"max_tempo":               (*spotify.TrackAttributes).MaxTempo,
+	"target_tempo":            (*spotify.TrackAttributes).TargetTempo,
+	"min_time_signature":      intTuning((*spotify.TrackAttributes).MinTimeSignature),
+	"max_time_signature":      intTuning((*spotify.TrackAttributes).MaxTimeSignature),
+	"target_time_signature":   intTuning((*spotify.TrackAttributes).TargetTimeSignature),
+	"min_valence":             (*spotify.TrackAttributes).MinValence,
+	"max_valence":             (*spotify.TrackAttributes).MaxValence,
+	"target_valence":          (*spotify.TrackAttributes).TargetValence,
+}
+
+// intTuning returns a tuning which sets a whole numbered attribute of recommended tracks.
+func intTuning(f func(*spotify.TrackAttributes, int) *spotify.TrackAttributes) tuning {
+	return func(ta *spotify.TrackAttributes, v float64) *spotify.TrackAttributes {
+		return f(ta, int(v))
+	}
+}
+
+// RadioContents returns contents which play the radio. The contents are identified by the URI of the first seed,
+// or by diskplayer:radio if the radio is seeded by genres alone.
+// An error is returned if the radio is invalid, as described for Radio.
+func RadioContents(r *Radio) (*Contents, error) {
+	_, err := radioRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	u := radioUri
+	if len(r.Seeds) > 0 {
+		u = r.Seeds[0]
+	}
+	return &Contents{Kind: CONTENTS_RADIO, URI: u, Title: "Radio", Radio: r}, nil
+}
+
+// PlayRadio will play tracks recommended by Spotify for the radio, and keep queueing more as it runs low on tracks to
+// play, until something else is played or playback has stopped for a while.
+// An error is returned if no tracks could be recommended, or one is encountered.
+func PlayRadio(c Client, r *Radio) error {
+	ts, err := recommend(c, r, nil)
+	if err != nil {
+		return err
+	}
+	if len(ts) == 0 {
+		return errors.New("no tracks could be recommended for the radio")
+	}
+
+	err = PlayTracks(c, ts)
+	if err != nil {
+		return err
+	}
+
+	return keepRadioPlaying(c, r, ts)
+}
+
+// keepRadioPlaying checks what is playing every radioPollInterval, and queues more recommended tracks whenever fewer
+// than radioLowWater of the tracks played by the radio are left to play. The tracks are held in the order in which
+// they will be played, and as Spotify plays queued tracks before the remaining tracks of the list being played, newly
+// queued tracks follow the one currently playing.
+// Returns once something other than the radio is playing, or nothing has been found playing radioIdleLimit times in a
+// row. An error is returned if more tracks could not be recommended or queued.
+func keepRadioPlaying(c Client, r *Radio, ts []string) error {
+	idle := 0
+	for idle < radioIdleLimit {
+		time.Sleep(radioPollInterval)
+
+		cp, err := c.PlayerCurrentlyPlaying()
+		if err != nil {
+			log.Printf("Unable to look up what is currently playing: %s", err)
+		}
+		if err != nil || cp.Item == nil || !cp.Playing {
+			idle++
+			continue
+		}
+		idle = 0
+
+		i := indexOf(ts, string(cp.Item.URI))
+		if i < 0 {
+			return nil
+		}
+		if len(ts)-i-1 >= radioLowWater {
+			continue
+		}
+
+		seen := map[string]bool{}
+		for _, t := range ts {
+			seen[t] = true
+		}
+		ns, err := recommend(c, r, seen)
+		if err != nil {
+			return err
+		}
+		for _, n := range ns {
+			_, id, _ := splitSpotifyUri(n)
+			err = c.QueueSong(spotify.ID(id))
+			if err != nil {
+				return err
+			}
+		}
+		ts = append(ts[:i+1], append(ns, ts[i+1:]...)...)
+	}
+	return nil
+}
+
+// recommend returns the Spotify URIs of tracks recommended for the radio, leaving out any which have been seen
+// already.
+// An error is returned if the radio is invalid or one is encountered.
+func recommend(c Client, r *Radio, seen map[string]bool) ([]string, error) {
+	s, err := radioRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	ta := spotify.NewTrackAttributes()
+	for k, v := range r.Tuning {
+		ta = radioTunings[k](ta, v)
+	}
+
+	l := radioBatch
+	rs, err := c.GetRecommendations(s, ta, &spotify.Options{Limit: &l})
+	if err != nil {
+		return nil, err
+	}
+
+	var ts []string
+	for _, t := range rs.Tracks {
+		u := string(t.URI)
+		if u != "" && !seen[u] {
+			ts = append(ts, u)
+		}
+	}
+	return ts, nil
+}
+
+// radioRequest returns the seeds of the radio as they are requested from Spotify.
+// An error is returned if the radio holds no seeds or more than Spotify allows, a seed is neither an artist nor a
+// track, or the radio holds an unknown tuning value.
+func radioRequest(r *Radio) (spotify.Seeds, error) {
+	s := spotify.Seeds{}
+	if r == nil {
+		return s, errors.New("a radio needs at least one seed")
+	}
+
+	for _, u := range r.Seeds {
+		k, id, err := splitSpotifyUri(u)
+		if err != nil {
+			return s, err
+		}
+		switch k {
+		case "artist":
+			s.Artists = append(s.Artists, spotify.ID(id))
+		case "track":
+			s.Tracks = append(s.Tracks, spotify.ID(id))
+		default:
+			return s, fmt.Errorf("a radio can only be seeded by artists, tracks and genres: %s", u)
+		}
+	}
+	s.Genres = r.Genres
+
+	n := len(r.Seeds) + len(r.Genres)
+	if n == 0 {
+		return s, errors.New("a radio needs at least one seed")
+	}
+	if n > spotify.MaxNumberOfSeeds {
+		return s, fmt.Errorf("a radio can have at most %d seeds", spotify.MaxNumberOfSeeds)
+	}
+
+	for k := range r.Tuning {
+		if radioTunings[k] == nil {
+			return s, fmt.Errorf("unknown radio tuning: %s", k)
+		}
+	}
+	return s, nil
+}
+
+// indexOf returns the index of the string in the slice, or -1 if it is not found.
+func indexOf(ss []string, s string) int {
+	for i, v := range ss {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,122 @@
+package diskplayer
+
+import (
+	"github.com/dinofizz/diskplayer/mocks"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/zmb3/spotify"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var radioContentsTests = []struct {
+	name string
+	in   *Radio
+	uri  string
+	e    string
+}{
+	{"artist", &Radio{Seeds: []string{"spotify:artist:0OdUWJ0sBjDrqHygGUXeCF"}, Genres: []string{"jazz"},
+		Tuning: map[string]float64{"target_energy": 0.8}}, "spotify:artist:0OdUWJ0sBjDrqHygGUXeCF", ""},
+	{"genres", &Radio{Genres: []string{"jazz", "soul"}}, "diskplayer:radio", ""},
+	{"nil", nil, "", "a radio needs at least one seed"},
+	{"empty", &Radio{Tuning: map[string]float64{"target_tempo": 120}}, "", "a radio needs at least one seed"},
+	{"too many", &Radio{Seeds: []string{"spotify:track:a", "spotify:track:b"}, Genres: []string{"a", "b", "c", "d"}},
+		"", "a radio can have at most 5 seeds"},
+	{"album", &Radio{Seeds: []string{"spotify:album:1S7mumn7D4riEX2gVWYgPO"}}, "",
+		"a radio can only be seeded by artists, tracks and genres: spotify:album:1S7mumn7D4riEX2gVWYgPO"},
+	{"invalid", &Radio{Seeds: []string{"florble"}}, "", "invalid Spotify URI: florble"},
+	{"tuning", &Radio{Genres: []string{"jazz"}, Tuning: map[string]float64{"target_florble": 1}}, "",
+		"unknown radio tuning: target_florble"},
+}
+
+func TestRadioContents(t *testing.T) {
+	for _, tt := range radioContentsTests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := RadioContents(tt.in)
+			if tt.e != "" {
+				assert.EqualError(t, err, tt.e)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, &Contents{Kind: CONTENTS_RADIO, URI: tt.uri, Title: "Radio", Radio: tt.in}, c)
+		})
+	}
+}
+
+// radioClient returns a mock client which recommends batches of numbered tracks in turn, and on which the track with
+// each of the given numbers is found playing in turn, followed by one which was not recommended.
+func radioClient(playing ...int) *mocks.Client {
+	viper.Set("spotify.device_name", "test_device_name")
+
+	n := 0
+	m := new(mocks.Client)
+	m.On("PlayerDevices").Return([]spotify.PlayerDevice{{ID: "TEST_ID", Name: "test_device_name"}}, nil)
+	m.On("PlayOpt", mock.Anything).Return(nil)
+	m.On("QueueSong", mock.Anything).Return(nil)
+	m.On("GetRecommendations", mock.Anything, mock.Anything, mock.Anything).Return(
+		func(s spotify.Seeds, ta *spotify.TrackAttributes, o *spotify.Options) *spotify.Recommendations {
+			r := &spotify.Recommendations{}
+			for i := 0; i < *o.Limit; i++ {
+				r.Tracks = append(r.Tracks, spotify.SimpleTrack{URI: spotify.URI("spotify:track:" + strconv.Itoa(n))})
+				n++
+			}
+			return r
+		}, nil)
+
+	for _, p := range append(playing, -1) {
+		cp := &spotify.CurrentlyPlaying{Playing: true, Item: &spotify.FullTrack{}}
+		cp.Item.URI = spotify.URI("spotify:track:" + strconv.Itoa(p))
+		m.On("PlayerCurrentlyPlaying").Return(cp, nil).Once()
+	}
+	return m
+}
+
+func TestPlayRadio(t *testing.T) {
+	defer func(d time.Duration) { radioPollInterval = d }(radioPollInterval)
+	radioPollInterval = time.Millisecond
+
+	// The first batch is played, and a second is queued once fewer than three tracks of the first batch are left to
+	// play. The radio stops once a track it did not play is playing.
+	m := radioClient(5, 16, 17, 20, 23)
+	r := &Radio{Seeds: []string{"spotify:artist:0OdUWJ0sBjDrqHygGUXeCF"}, Genres: []string{"jazz"},
+		Tuning: map[string]float64{"target_energy": 0.8}}
+	err := PlayRadio(m, r)
+	assert.NoError(t, err)
+
+	l := radioBatch
+	m.AssertCalled(t, "GetRecommendations", spotify.Seeds{Artists: []spotify.ID{"0OdUWJ0sBjDrqHygGUXeCF"},
+		Genres: []string{"jazz"}}, spotify.NewTrackAttributes().TargetEnergy(0.8), &spotify.Options{Limit: &l})
+	m.AssertCalled(t, "PlayOpt", mock.MatchedBy(func(o *spotify.PlayOptions) bool {
+		return len(o.URIs) == radioBatch && o.URIs[0] == "spotify:track:0"
+	}))
+	m.AssertNumberOfCalls(t, "GetRecommendations", 2)
+	m.AssertNumberOfCalls(t, "QueueSong", radioBatch)
+	m.AssertCalled(t, "QueueSong", spotify.ID("39"))
+	m.AssertNumberOfCalls(t, "PlayerCurrentlyPlaying", 6)
+}
+
+func TestPlayRadioIdle(t *testing.T) {
+	defer func(d time.Duration) { radioPollInterval = d }(radioPollInterval)
+	radioPollInterval = time.Millisecond
+
+	m := radioClient()
+	m.ExpectedCalls = m.ExpectedCalls[:len(m.ExpectedCalls)-1]
+	m.On("PlayerCurrentlyPlaying").Return(&spotify.CurrentlyPlaying{}, nil)
+
+	err := PlayRadio(m, &Radio{Genres: []string{"jazz"}})
+	assert.NoError(t, err)
+	m.AssertNumberOfCalls(t, "PlayerCurrentlyPlaying", radioIdleLimit)
+	m.AssertNotCalled(t, "QueueSong", mock.Anything)
+}
+
+func TestPlayRadioErrors(t *testing.T) {
+	err := PlayRadio(new(mocks.Client), &Radio{})
+	assert.EqualError(t, err, "a radio needs at least one seed")
+
+	m := new(mocks.Client)
+	m.On("GetRecommendations", mock.Anything, mock.Anything, mock.Anything).Return(&spotify.Recommendations{}, nil)
+	err = PlayRadio(m, &Radio{Genres: []string{"jazz"}})
+	assert.EqualError(t, err, "no tracks could be recommended for the radio")
+}
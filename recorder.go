@@ -3,13 +3,24 @@ package diskplayer
 import (
 	"errors"
 	"fmt"
+	"github.com/dinofizz/diskplayer/fat"
+	"io"
 	"io/ioutil"
+	"os"
+	"path/filepath"
 	"strings"
+	"syscall"
 )
 
-// Record takes in a web URL which links to a Spotify album or playlist and records the corresponding Spotify ID to
-// the filepath specified in the diskplayer.yaml configuration file under the recorder.file_path field.
+// ErrNoBackup is returned when undoing a recording on a disk which holds no previous contents.
+var ErrNoBackup = errors.New("no previous recording found on the disk")
+
+// Record takes in a web URL which links to a Spotify album, playlist, artist, show or track and records the
+// corresponding Spotify URI to the filepath specified in the diskplayer.yaml configuration file under the
+// recorder.file_path field. Snapshot, smart, radio and surprise contents are only recorded by the recorder server.
 // The web URL should be something like https://open.spotify.com/album/1S7mumn7D4riEX2gVWYgPO
+// The previous contents of the file, of whatever kind, are kept as a backup, and the file is read back after writing
+// to verify it. A disk identity is written alongside the contents file, unless the disk already has one.
 // Returns an error if one is encountered.
 func Record(url string, fullPath string) error {
 	s, err := createSpotifyUri(url)
@@ -22,10 +33,104 @@ func Record(url string, fullPath string) error {
 		return err
 	}
 
-	return nil
+	err = verifyFile(s, fullPath)
+	if err != nil {
+		return err
+	}
+
+	_, err = stampDisk(filepath.Dir(fullPath), keepDiskId)
+	return err
+}
+
+// Undo restores the contents file at the path to the Spotify URI it held before it was last recorded, keeping the
+// replaced contents as the new backup so that the undo can itself be undone.
+// The restored Spotify URI is returned, or ErrNoBackup if there is no previous recording, or any other error
+// encountered.
+func Undo(fullPath string) (string, error) {
+	s, err := readBackup(fullPath)
+	if err != nil {
+		return "", err
+	}
+
+	err = writeToDisk(s, fullPath)
+	if err != nil {
+		return "", err
+	}
+
+	return s, verifyFile(s, fullPath)
+}
+
+// RecordDevice takes in a web URL which links to a Spotify album, playlist, artist, show or track and records the
+// corresponding Spotify URI directly to the FAT formatted disk whose device path (or image file path) is passed into
+// the function. The disk must not be mounted. The name of the file written is specified in the diskplayer.yaml
+// configuration file under the recorder.filename field.
+// The previous contents of the file, of whatever kind, are kept as a backup, and the file is read back after writing
+// to verify it. A disk identity is written alongside the contents file, unless the disk already has one.
+// Returns an error if one is encountered.
+func RecordDevice(url string, device string) error {
+	s, err := createSpotifyUri(url)
+	if err != nil {
+		return err
+	}
+
+	n := ConfigValue(RECORD_FILENAME)
+	err = writeToDevice(s, device, n)
+	if err != nil {
+		return err
+	}
+
+	err = verifyDevice(s, device, n)
+	if err != nil {
+		return err
+	}
+
+	_, err = stampDevice(device, keepDiskId)
+	return err
+}
+
+// UndoDevice restores the contents file on the FAT formatted disk whose device path (or image file path) is passed
+// into the function to the Spotify URI it held before it was last recorded. The disk must not be mounted.
+// The restored Spotify URI is returned, or ErrNoBackup if there is no previous recording, or any other error
+// encountered.
+func UndoDevice(device string) (string, error) {
+	n := ConfigValue(RECORD_FILENAME)
+	s, err := readDeviceBackup(device, n)
+	if err != nil {
+		return "", err
+	}
+
+	err = writeToDevice(s, device, n)
+	if err != nil {
+		return "", err
+	}
+
+	return s, verifyDevice(s, device, n)
+}
+
+// WriteDeviceFile writes the data to the named file in the root directory of the FAT formatted disk whose device path
+// (or image file path) is passed into the function, creating the file if necessary.
+// Returns an error if one is encountered.
+func WriteDeviceFile(device, name string, data []byte) error {
+	f, err := os.OpenFile(device, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fs, err := fat.Open(f)
+	if err != nil {
+		return fmt.Errorf("unable to read disk %s: %s", device, err)
+	}
+
+	err = fs.WriteFile(name, data)
+	if err != nil {
+		return err
+	}
+
+	return f.Sync()
 }
 
-// createSpotifyUri creates a Spotify URI from the web URL.
+// createSpotifyUri creates a Spotify URI from the web URL of an album, playlist, artist, show or track.
 // The web URL should be something like https://open.spotify.com/album/1S7mumn7D4riEX2gVWYgPO
 // A string representing the Spotify URI is returned or any error that is encountered.
 func createSpotifyUri(url string) (string, error) {
@@ -36,23 +141,214 @@ func createSpotifyUri(url string) (string, error) {
 		u = "spotify:album:" + id
 	} else if strings.Contains(url, "/playlist/") {
 		u = "spotify:playlist:" + id
+	} else if strings.Contains(url, "/artist/") {
+		u = "spotify:artist:" + id
+	} else if strings.Contains(url, "/show/") {
+		u = "spotify:show:" + id
+	} else if strings.Contains(url, "/track/") {
+		u = "spotify:track:" + id
 	} else {
-		return "", errors.New(fmt.Sprintf("URL represents neither album nor playlist: %s", url))
+		return "", errors.New(fmt.Sprintf("URL represents neither album, playlist, artist, show nor track: %s", url))
 	}
 	return u, nil
 }
 
 // writeToDisk takes a string containing a Spotify URI and writes to the the filepath specified in the diskplayer.yaml
 // configuration file under the recorder.file_path field.
+// The contents are written to a temporary file which is synced and renamed over the contents file, so that an
+// interrupted write never leaves a partially written file behind. Any previous, different contents are first kept in
+// a backup file alongside it.
 // Returns an error if one is encountered.
 func writeToDisk(spotifyUri, fullPath string) error {
+	old, err := readExisting(fullPath)
+	if err != nil {
+		return err
+	}
+
+	if old != "" && old != spotifyUri {
+		err = replaceFile(backupName(fullPath), []byte(old))
+		if err != nil {
+			return err
+		}
+	}
+
+	return replaceFile(fullPath, []byte(spotifyUri))
+}
+
+// readExisting returns the current contents of the file at the path, or an empty string if it does not exist.
+// The file is opened for writing so that a contents file which may not be written to is reported before it is
+// replaced.
+// Returns an error if one is encountered.
+func readExisting(fullPath string) (string, error) {
+	f, err := os.OpenFile(fullPath, os.O_RDWR, 0)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// replaceFile atomically replaces the file at the path with the data, by writing it to a temporary file which is
+// synced to the disk and renamed over the file, before syncing the directory holding it.
+// Returns an error if one is encountered.
+func replaceFile(fullPath string, data []byte) error {
+	t := fullPath + ".tmp"
+	f, err := os.OpenFile(t, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+
+	_, err = f.Write(data)
+	if err == nil {
+		err = f.Sync()
+		// Special files and some filesystems do not support syncing, in which case the contents are still verified
+		// after writing.
+		if errors.Is(err, syscall.EINVAL) {
+			err = nil
+		}
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(t, fullPath)
+	}
+	if err != nil {
+		os.Remove(t)
+		return err
+	}
+
+	d, err := os.Open(filepath.Dir(fullPath))
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// readBackup returns the Spotify URI held in the backup of the contents file at the path.
+// ErrNoBackup is returned if there is no backup, or any other error encountered.
+func readBackup(fullPath string) (string, error) {
+	b, err := ioutil.ReadFile(backupName(fullPath))
+	if os.IsNotExist(err) || (err == nil && len(b) == 0) {
+		return "", ErrNoBackup
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// writeToDevice writes the Spotify URI to the named file on the FAT formatted disk whose device path (or image file
+// path) is passed into the function. Any previous, different contents are first kept in a backup file alongside it.
+// Returns an error if one is encountered.
+func writeToDevice(spotifyUri, device, name string) error {
+	f, err := os.OpenFile(device, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fs, err := fat.Open(f)
+	if err != nil {
+		return fmt.Errorf("unable to read disk %s: %s", device, err)
+	}
+
+	old, err := fs.ReadFile(name)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
 
-	b := []byte(spotifyUri)
-	err := ioutil.WriteFile(fullPath, b, 0644)
+	if len(old) > 0 && string(old) != spotifyUri {
+		err = fs.WriteFile(backupName(name), old)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = fs.WriteFile(name, []byte(spotifyUri))
 	if err != nil {
 		return err
 	}
 
+	return f.Sync()
+}
+
+// readDeviceBackup returns the Spotify URI held in the backup of the named contents file on the FAT formatted disk
+// whose device path (or image file path) is passed into the function.
+// ErrNoBackup is returned if there is no backup, or any other error encountered.
+func readDeviceBackup(device, name string) (string, error) {
+	b, err := ReadDeviceFile(device, backupName(name))
+	if os.IsNotExist(err) || (err == nil && len(b) == 0) {
+		return "", ErrNoBackup
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+// backupName returns the name of the file holding the previous contents of the named contents file.
+func backupName(name string) string {
+	return name + ".bak"
+}
+
+// verifyFile reads back the contents file at the path and checks that it holds the Spotify URI.
+// Returns an error if the file could not be read or its contents do not match.
+func verifyFile(spotifyUri, fullPath string) error {
+	b, err := ioutil.ReadFile(fullPath)
+	if err != nil {
+		return fmt.Errorf("unable to read back %s: %s", fullPath, err)
+	}
+	return verifyContents(spotifyUri, fullPath, b)
+}
+
+// verifyDevice reads back the named file from the FAT formatted disk whose device path (or image file path) is
+// passed into the function and checks that it holds the Spotify URI.
+// Returns an error if the file could not be read or its contents do not match.
+func verifyDevice(spotifyUri, device, name string) error {
+	b, err := ReadDeviceFile(device, name)
+	if err != nil {
+		return fmt.Errorf("unable to read back %s from %s: %s", name, device, err)
+	}
+	return verifyContents(spotifyUri, device, b)
+}
 
+// verifyContents returns an error if the contents read back from the source do not match the Spotify URI.
+func verifyContents(spotifyUri, src string, b []byte) error {
+	if string(b) != spotifyUri {
+		return fmt.Errorf("verification of %s failed, expected %q but read %q", src, spotifyUri, string(b))
+	}
 	return nil
 }
+
+// FormatDevice creates an empty FAT12 or FAT16 filesystem with the given volume label on the device identified by the
+// path, erasing any existing contents. The device must not be mounted.
+// Returns an error if one is encountered.
+func FormatDevice(path, label string) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	size, err := f.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+
+	err = fat.Format(f, size, label)
+	if err != nil {
+		return err
+	}
+
+	return f.Sync()
+}
@@ -6,6 +6,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"testing"
 )
 
@@ -16,12 +17,26 @@ var recordTests = []struct {
 }{
 	{"https://open.spotify.com/album/1S7mumn7D4riEX2gVWYgPO", "spotify:album:1S7mumn7D4riEX2gVWYgPO", ""},
 	{"https://open.spotify.com/playlist/5XsXwH5uWdhpAWsigjWMTA", "spotify:playlist:5XsXwH5uWdhpAWsigjWMTA", ""},
+	{"https://open.spotify.com/artist/0OdUWJ0sBjDrqHygGUXeCF", "spotify:artist:0OdUWJ0sBjDrqHygGUXeCF", ""},
+	{"https://open.spotify.com/show/4rOoJ6Egrf8K2IrywzwOMk", "spotify:show:4rOoJ6Egrf8K2IrywzwOMk", ""},
+	{"https://open.spotify.com/track/4uLU6hMCjMI75M1A2tKUQC", "spotify:track:4uLU6hMCjMI75M1A2tKUQC", ""},
 	{"florble", "",
-		"URL represents neither album nor playlist: florble"},
+		"URL represents neither album, playlist, artist, show nor track: florble"},
+}
+
+// recorderPath returns the path of a contents file in a temporary folder, so that the backup and disk identity written
+// alongside it are removed along with the folder.
+func recorderPath(t *testing.T) (string, func()) {
+	d, err := ioutil.TempDir("", "diskplayer_recorder")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return filepath.Join(d, "test_recorder_path.contents"), func() { os.RemoveAll(d) }
 }
 
 func TestRecord(t *testing.T) {
-	const p = "./test_recorder_path.contents"
+	p, cleanup := recorderPath(t)
+	defer cleanup()
 	viper.Set("recorder.file_path", p)
 
 	for _, tt := range recordTests {
@@ -62,14 +77,9 @@ func TestRecord(t *testing.T) {
 }
 
 func TestRecordWriteError(t *testing.T) {
-	const p = "./test_recorder_path.contents"
+	p, cleanup := recorderPath(t)
+	defer cleanup()
 	viper.Set("recorder.file_path", p)
-	if _, err := os.Stat(p); os.IsExist(err) {
-		err := os.Remove(p)
-		if err != nil {
-			t.Fatalf("Remove %s: %v", p, err)
-		}
-	}
 
 	shmorp := []byte("shmorp")
 	err := ioutil.WriteFile(p, shmorp, 0444)
@@ -82,5 +92,165 @@ func TestRecordWriteError(t *testing.T) {
 	}()
 	err = Record(recordTests[0].in, p)
 	assert.Error(t, err)
-	assert.Equal(t, "open ./test_recorder_path.contents: permission denied", err.Error())
+	assert.EqualError(t, err, "open "+p+": permission denied")
+}
+
+func TestFormatDevice(t *testing.T) {
+	f, err := ioutil.TempFile("", "diskplayer_format")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	err = f.Truncate(1474560)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = FormatDevice(f.Name(), "Kind of Blue")
+	assert.NoError(t, err)
+
+	fs, err := ProbeFilesystem(f.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, "vfat", fs.Type)
+}
+
+func TestFormatDeviceInvalidPath(t *testing.T) {
+	err := FormatDevice("./test-fixtures/not_a_real_path", "label")
+	_, ok := err.(*os.PathError)
+	assert.True(t, ok)
+}
+
+// formattedImage creates a temporary floppy disk image formatted with a FAT12 filesystem, returning its path.
+func formattedImage(t *testing.T) string {
+	f, err := ioutil.TempFile("", "diskplayer_image")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = f.Truncate(1474560)
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = FormatDevice(f.Name(), "diskplayer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return f.Name()
+}
+
+func TestRecordDevice(t *testing.T) {
+	viper.Set("recorder.filename", "diskplayer.contents")
+	p := formattedImage(t)
+	defer os.Remove(p)
+
+	for _, tt := range recordTests {
+		t.Run(tt.in, func(t *testing.T) {
+			err := RecordDevice(tt.in, p)
+			if tt.e != "" {
+				assert.EqualError(t, err, tt.e)
+				return
+			}
+			assert.NoError(t, err)
+
+			b, err := ReadDeviceFile(p, "diskplayer.contents")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.out, string(b))
+		})
+	}
+}
+
+func TestRecordDeviceNotFat(t *testing.T) {
+	viper.Set("recorder.filename", "diskplayer.contents")
+	f, err := ioutil.TempFile("", "diskplayer_image")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.Close()
+
+	err = RecordDevice(recordTests[0].in, f.Name())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to read disk")
+}
+
+func TestVerifyFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "diskplayer_contents")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(recordTests[0].out)
+	f.Close()
+
+	assert.NoError(t, verifyFile(recordTests[0].out, f.Name()))
+	err = verifyFile(recordTests[1].out, f.Name())
+	assert.EqualError(t, err, "verification of "+f.Name()+" failed, expected \""+recordTests[1].out+
+		"\" but read \""+recordTests[0].out+"\"")
+}
+
+func TestRecordBackupAndUndo(t *testing.T) {
+	d, err := ioutil.TempDir("", "diskplayer_record")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(d)
+	p := filepath.Join(d, "diskplayer.contents")
+
+	_, err = Undo(p)
+	assert.Equal(t, ErrNoBackup, err)
+
+	assert.NoError(t, Record(recordTests[0].in, p))
+	_, err = Undo(p)
+	assert.Equal(t, ErrNoBackup, err)
+
+	assert.NoError(t, Record(recordTests[1].in, p))
+	assert.NoError(t, Record(recordTests[1].in, p))
+	b, err := ioutil.ReadFile(p + ".bak")
+	assert.NoError(t, err)
+	assert.Equal(t, recordTests[0].out, string(b))
+	_, err = os.Stat(p + ".tmp")
+	assert.True(t, os.IsNotExist(err))
+
+	s, err := Undo(p)
+	assert.NoError(t, err)
+	assert.Equal(t, recordTests[0].out, s)
+	b, err = ioutil.ReadFile(p)
+	assert.NoError(t, err)
+	assert.Equal(t, recordTests[0].out, string(b))
+	b, err = ioutil.ReadFile(p + ".bak")
+	assert.NoError(t, err)
+	assert.Equal(t, recordTests[1].out, string(b))
+
+	id, err := ReadDiskId(d)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, id)
+	assert.NoError(t, Record(recordTests[0].in, p))
+	s, err = ReadDiskId(d)
+	assert.NoError(t, err)
+	assert.Equal(t, id, s)
+}
+
+func TestUndoDevice(t *testing.T) {
+	viper.Set("recorder.filename", "diskplayer.contents")
+	p := formattedImage(t)
+	defer os.Remove(p)
+
+	_, err := UndoDevice(p)
+	assert.Equal(t, ErrNoBackup, err)
+
+	assert.NoError(t, RecordDevice(recordTests[0].in, p))
+	assert.NoError(t, RecordDevice(recordTests[1].in, p))
+
+	s, err := UndoDevice(p)
+	assert.NoError(t, err)
+	assert.Equal(t, recordTests[0].out, s)
+	b, err := ReadDeviceFile(p, "diskplayer.contents")
+	assert.NoError(t, err)
+	assert.Equal(t, recordTests[0].out, string(b))
+
+	id, err := ReadDeviceDiskId(p)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, id)
 }
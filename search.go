@@ -0,0 +1,77 @@
+package diskplayer
+
+import (
+	"errors"
+	"github.com/zmb3/spotify"
+	"strings"
+)
+
+// defaultSearchLimit is the number of results of each kind returned when searching Spotify.
+const defaultSearchLimit = 8
+
+// SearchItem is an album, playlist, artist or show found by searching Spotify, described in a form suitable for
+// display.
+type SearchItem struct {
+	Kind string `json:"kind"`
+	Metadata
+}
+
+// SearchSpotify will search Spotify for albums, playlists, artists and shows matching the query, returning up to
+// limit items of each kind in that order.
+// An error is returned if one is encountered.
+func SearchSpotify(c Client, query string, limit int) ([]SearchItem, error) {
+	query = strings.TrimSpace(query)
+	if query == "" {
+		return nil, errors.New("a search query is required")
+	}
+
+	r, err := c.Search(query, spotify.SearchTypeAlbum|spotify.SearchTypePlaylist|spotify.SearchTypeArtist, limit)
+	if err != nil {
+		return nil, err
+	}
+	shows, err := c.SearchShows(query, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	var is []SearchItem
+	if r.Albums != nil {
+		for _, a := range r.Albums.Albums {
+			is = appendSearchItem(is, "album", a.ID, a.Name, artistNames(a.Artists), a.Images)
+		}
+	}
+	if r.Playlists != nil {
+		for _, p := range r.Playlists.Playlists {
+			is = appendSearchItem(is, "playlist", p.ID, p.Name, p.Owner.DisplayName, p.Images)
+		}
+	}
+	if r.Artists != nil {
+		for _, a := range r.Artists.Artists {
+			is = appendSearchItem(is, "artist", a.ID, a.Name, "", a.Images)
+		}
+	}
+	for _, sh := range shows {
+		is = appendSearchItem(is, "show", sh.ID, sh.Name, sh.Publisher, sh.Images)
+	}
+
+	return is, nil
+}
+
+// appendSearchItem appends the item of the given kind to the search results. Items without an ID, which Spotify
+// returns in place of results that are no longer available, are skipped.
+func appendSearchItem(is []SearchItem, kind string, id spotify.ID, title, artist string,
+	images []spotify.Image) []SearchItem {
+	if id == "" {
+		return is
+	}
+	return append(is, SearchItem{
+		Kind: kind,
+		Metadata: Metadata{
+			URI:      "spotify:" + kind + ":" + string(id),
+			WebURL:   spotifyWebUrl(kind, string(id)),
+			Title:    title,
+			Artist:   artist,
+			ImageURL: imageUrl(images),
+		},
+	})
+}
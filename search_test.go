@@ -0,0 +1,70 @@
+package diskplayer
+
+import (
+	"errors"
+	"github.com/dinofizz/diskplayer/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/zmb3/spotify"
+	"testing"
+)
+
+const searchTypes = spotify.SearchTypeAlbum | spotify.SearchTypePlaylist | spotify.SearchTypeArtist
+
+func searchClient() *mocks.Client {
+	r := &spotify.SearchResult{
+		Albums:    &spotify.SimpleAlbumPage{},
+		Playlists: &spotify.SimplePlaylistPage{},
+		Artists:   &spotify.FullArtistPage{},
+	}
+	al := spotify.SimpleAlbum{ID: "1S7mumn7D4riEX2gVWYgPO", Name: "Test Album"}
+	al.Artists = []spotify.SimpleArtist{{Name: "Artist One"}}
+	al.Images = []spotify.Image{{URL: "https://i.scdn.co/image/album"}}
+	r.Albums.Albums = []spotify.SimpleAlbum{al}
+	pl := spotify.SimplePlaylist{ID: "5XsXwH5uWdhpAWsigjWMTA", Name: "Test Playlist"}
+	pl.Owner.DisplayName = "Test Owner"
+	r.Playlists.Playlists = []spotify.SimplePlaylist{{}, pl}
+	ar := spotify.FullArtist{}
+	ar.ID = "0OdUWJ0sBjDrqHygGUXeCF"
+	ar.Name = "Test Artist"
+	r.Artists.Artists = []spotify.FullArtist{ar}
+	sh := spotify.SimpleShow{ID: "4rOoJ6Egrf8K2IrywzwOMk", Name: "Test Show", Publisher: "Test Publisher"}
+
+	m := new(mocks.Client)
+	m.On("Search", "test", searchTypes, 8).Return(r, nil)
+	m.On("SearchShows", "test", 8).Return([]spotify.SimpleShow{sh}, nil)
+	return m
+}
+
+func TestSearchSpotify(t *testing.T) {
+	is, err := SearchSpotify(searchClient(), " test ", 8)
+	assert.NoError(t, err)
+	assert.Len(t, is, 4)
+
+	assert.Equal(t, "album", is[0].Kind)
+	assert.Equal(t, "spotify:album:1S7mumn7D4riEX2gVWYgPO", is[0].URI)
+	assert.Equal(t, "https://open.spotify.com/album/1S7mumn7D4riEX2gVWYgPO", is[0].WebURL)
+	assert.Equal(t, "Artist One", is[0].Artist)
+	assert.Equal(t, "https://i.scdn.co/image/album", is[0].ImageURL)
+	assert.Equal(t, "playlist", is[1].Kind)
+	assert.Equal(t, "Test Owner", is[1].Artist)
+	assert.Equal(t, "spotify:artist:0OdUWJ0sBjDrqHygGUXeCF", is[2].URI)
+	assert.Equal(t, "show", is[3].Kind)
+	assert.Equal(t, "https://open.spotify.com/show/4rOoJ6Egrf8K2IrywzwOMk", is[3].WebURL)
+	assert.Equal(t, "Test Publisher", is[3].Artist)
+}
+
+func TestSearchSpotifyErrors(t *testing.T) {
+	m := new(mocks.Client)
+	_, err := SearchSpotify(m, " ", 8)
+	assert.EqualError(t, err, "a search query is required")
+
+	m.On("Search", "test", searchTypes, 8).Return(nil, errors.New("search error"))
+	_, err = SearchSpotify(m, "test", 8)
+	assert.EqualError(t, err, "search error")
+
+	m = new(mocks.Client)
+	m.On("Search", "test", searchTypes, 8).Return(&spotify.SearchResult{}, nil)
+	m.On("SearchShows", "test", 8).Return(nil, errors.New("show search error"))
+	_, err = SearchSpotify(m, "test", 8)
+	assert.EqualError(t, err, "show search error")
+}
@@ -1,24 +1,108 @@
 package diskplayer
 
 import (
-	"github.com/docker/docker/pkg/mount"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"github.com/spf13/viper"
 	"github.com/zmb3/spotify"
 	"golang.org/x/oauth2"
-	"html/template"
+	"io/fs"
+	"io/ioutil"
 	"log"
+	"net"
 	"net/http"
 	"net/url"
-	"os/exec"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type IndexPage struct {
-	Lsblk []byte
+	Devices     []DeviceStatus
+	CSRFToken   string
+	Query       string
+	DevicePath  string
+	Results     []SearchItem
+	SearchError string
 }
 
 type ErrorPage struct {
 	Body []byte
 }
 
+type SuccessPage struct {
+	WebURL     string
+	DevicePath string
+	Undone     bool
+	CSRFToken  string
+}
+
+type ConfirmPage struct {
+	WebURL        string
+	DevicePath    string
+	Snapshot      bool
+	Smart         string
+	Radio         bool
+	RadioGenres   string
+	RadioEnergy   string
+	RadioTempo    string
+	Surprise      bool
+	SurprisePool  string
+	Contents      string
+	Format        bool
+	ConfirmFormat bool
+	Existing      *DiskContents
+	CSRFToken     string
+}
+
+// DiskContents describes the contents file found on a disk, along with the details of the album or playlist it
+// holds if they could be looked up. The kind is set if the contents are more than a plain Spotify URI, as described
+// for Contents.
+type DiskContents struct {
+	DevicePath string    `json:"device_path"`
+	URI        string    `json:"uri"`
+	Kind       string    `json:"kind,omitempty"`
+	DiskID     string    `json:"disk_id,omitempty"`
+	Metadata   *Metadata `json:"metadata,omitempty"`
+}
+
+type CataloguePage struct {
+	Entries    []CatalogueEntry
+	Devices    []DeviceStatus
+	Query      string
+	Kind       string
+	DevicePath string
+	DiskID     string
+	ExportCSV  string
+	ExportJSON string
+	Duplicates map[string][]CatalogueEntry
+	CSRFToken  string
+}
+
+type LibraryPage struct {
+	Results    *LibraryResults
+	Devices    []DeviceStatus
+	Query      string
+	Kind       string
+	DevicePath string
+	Previous   string
+	Next       string
+	Error      string
+	CSRFToken  string
+}
+
+type LoginPage struct {
+	Next  string
+	Error string
+}
+
+type LabelsPage struct {
+	WebURLs []string
+}
+
 type DiskplayerServer interface {
 	RunRecordServer() error
 	RunCallbackServer() (*http.Server, error)
@@ -36,24 +120,103 @@ func NewDiskplayerServer(a *spotify.Authenticator, ch chan *oauth2.Token) *RealD
 	return &RealDiskplayerServer{cbh: h}
 }
 
+// NewRecordServer returns a new DiskplayerServer instance for running the recorder.
+// The Spotify client is used to look up album and playlist details for disk labels, and may be nil if no
+// Spotify token is available. The mounter is used to mount disks for recording.
+func NewRecordServer(c Client, m Mounter) *RealDiskplayerServer {
+	return &RealDiskplayerServer{
+		client:    c,
+		mounter:   m,
+		devices:   ListRemovableDevices,
+		catalogue: NewCatalogue(ConfigPath(RECORD_CATALOGUE)),
+		access:    NewAccessControl(),
+	}
+}
+
 type RealDiskplayerServer struct {
-	cbh CallbackHandler
+	cbh       CallbackHandler
+	client    Client
+	mounter   Mounter
+	devices   func() ([]BlockDevice, error)
+	locks     DeviceLocks
+	catalogue *Catalogue
+	access    *AccessControl
 }
 
 // RunRecordServer creates a web server running on the port defined in the configuration file under the recorder.
-// server_port field.
-// Files are served directly from the "static" folder.
+// server_port field, listening on the address defined under the recorder.bind_address field or on all interfaces if
+// none is set. Access is restricted as defined under the recorder.auth fields.
+// If TLS is configured under the tls fields the server uses HTTPS, and if the recorder.redirect_port field is set a
+// plain HTTP server on that port redirects to it.
+// Templates and static files are embedded in the binary, and may be overridden by files in the templates and static
+// folders of the directory defined under the recorder.assets_path field, resolved as described for ConfigPath.
+// An error is returned if the server could not be started, or a template could not be parsed.
 func (s *RealDiskplayerServer) RunRecordServer() error {
+	c, err := TLSConfig()
+	if err != nil {
+		return err
+	}
+
+	b := viper.GetString(RECORD_BIND_ADDRESS)
 	p := ConfigValue(RECORD_SERVER_PORT)
-	fs := http.FileServer(http.Dir("static"))
-	http.Handle("/static/", http.StripPrefix("/static/", fs))
-	http.HandleFunc("/", indexHandler)
-	http.HandleFunc("/record", recordHandler)
-	return http.ListenAndServe(":"+p, nil)
+	a := net.JoinHostPort(b, p)
+	if !s.access.Enabled() {
+		log.Printf("No recorder.auth password hash or API tokens are configured, anyone who can reach %s may "+
+			"write to disks", a)
+	}
+
+	d := ConfigPath(RECORD_ASSETS_PATH)
+	if d != "" {
+		pages, err = LoadTemplates(d)
+		if err != nil {
+			return fmt.Errorf("unable to load templates from %s: %s", d, err)
+		}
+	}
+	static, err := fs.Sub(Assets(d), "static")
+	if err != nil {
+		return err
+	}
+
+	http.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.FS(static))))
+	http.HandleFunc("/", s.indexHandler)
+	http.HandleFunc("/record", s.recordHandler)
+	http.HandleFunc("/undo", s.undoHandler)
+	http.HandleFunc("/api/v1/", apiNotFoundHandler)
+	http.HandleFunc("/api/v1/devices", s.apiDevicesHandler)
+	http.HandleFunc("/api/v1/disk", s.apiDiskHandler)
+	http.HandleFunc("/api/v1/record", s.apiRecordHandler)
+	http.HandleFunc("/api/v1/undo", s.apiUndoHandler)
+	http.HandleFunc("/api/v1/search", s.apiSearchHandler)
+	http.HandleFunc("/api/v1/library", s.apiLibraryHandler)
+	http.HandleFunc("/api/v1/history", s.apiHistoryHandler)
+	http.HandleFunc("/api/v1/history/export", s.apiExportHandler)
+	http.HandleFunc("/api/v1/history/duplicates", s.apiDuplicatesHandler)
+	http.HandleFunc("/catalogue", s.catalogueHandler)
+	http.HandleFunc("/library", s.libraryHandler)
+	http.HandleFunc("/success", successHandler)
+	http.HandleFunc("/label", s.labelHandler)
+	http.HandleFunc("/labels", labelsHandler)
+	http.HandleFunc("/login", s.loginHandler)
+	http.HandleFunc("/logout", s.logoutHandler)
+
+	h := s.access.Protect(http.DefaultServeMux)
+	if c == nil {
+		return http.ListenAndServe(a, h)
+	}
+
+	if rp := viper.GetString(RECORD_REDIRECT_PORT); rp != "" {
+		go func() {
+			err := http.ListenAndServe(net.JoinHostPort(b, rp), HTTPSRedirect(p))
+			log.Printf("HTTP redirect server stopped: %s", err)
+		}()
+	}
+	server := &http.Server{Addr: a, Handler: h, TLSConfig: c}
+	return server.ListenAndServeTLS("", "")
 }
 
 // RunCallbackServer creates a web server running on the port defined in the configuration file under the spotify.
-// callback_url field.
+// callback_url field. If the callback URL uses https the server uses the TLS configuration defined under the tls
+// fields, which must not be off.
 // A pointer to the server object is returned so that it can be shutdown when no longer needed.
 func (s *RealDiskplayerServer) RunCallbackServer() (*http.Server, error) {
 	r := ConfigValue(SPOTIFY_CALLBACK_URL)
@@ -62,12 +225,36 @@ func (s *RealDiskplayerServer) RunCallbackServer() (*http.Server, error) {
 		return nil, err
 	}
 
-	http.Handle(u.EscapedPath(), s.cbh)
-	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+	var c *tls.Config
+	if u.Scheme == "https" {
+		c, err = TLSConfig()
+		if err != nil {
+			return nil, err
+		}
+		if c == nil {
+			return nil, fmt.Errorf("callback URL %s uses https but tls.mode is off", r)
+		}
+	}
+
+	p := u.Port()
+	if p == "" {
+		p = "80"
+		if c != nil {
+			p = "443"
+		}
+	}
+
+	m := http.NewServeMux()
+	m.Handle(u.EscapedPath(), s.cbh)
+	m.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		log.Println("Got request for:", r.URL.String())
 	})
-	server := &http.Server{Addr: ":" + u.Port(), Handler: nil}
-	go server.ListenAndServe()
+	server := &http.Server{Addr: ":" + p, Handler: m, TLSConfig: c}
+	if c != nil {
+		go server.ListenAndServeTLS("", "")
+	} else {
+		go server.ListenAndServe()
+	}
 	return server, nil
 }
 
@@ -82,62 +269,1238 @@ func (s *RealDiskplayerServer) Authenticator() *spotify.Authenticator {
 // recordHandler handles requests to the server which contain a Spotify web URL to be recorded.
 // Two values are extracted from the request: web_url and device_path:
 // web_url is the complete Spotify web URL pointing to an album or playlist.
-// device_path is the complete path to the disk device, i.e. /dev/sda. This will be mounted to the folder specified
-// in the diskplayer.yaml configuration file.
-// If the recording is successful, redirection to a success page occurs, otherwise an error page is returned.
-func recordHandler(w http.ResponseWriter, r *http.Request) {
-	webUrl := r.FormValue("web_url")
-	devPath := r.FormValue("device_path")
+// device_path is the complete path to the disk device, i.e. /dev/sda.
+// The current, snapshot, smart, format, confirm_format and confirm_overwrite values are also used, as described for
+// RecordRequest, along with the radio values described for formRadio. The surprise_pool value holds the web URLs of
+// the pool of a surprise disk, one per line.
+// If the recording is successful, redirection to a success page occurs. If the disk already holds a different album or
+// playlist, a page asking for confirmation is returned with a 409 status code. Otherwise an error page is returned
+// with a status code identifying the step which failed, as described for record. Only POST requests are accepted, so
+// that a disk cannot be recorded without the CSRF token checked by the access control.
+func (s *RealDiskplayerServer) recordHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		errorPage(w, errors.New("disks can only be recorded with a POST request"))
+		return
+	}
+
+	req := &RecordRequest{
+		DevicePath:       r.FormValue("device_path"),
+		WebURL:           r.FormValue("web_url"),
+		Current:          r.FormValue("current") != "",
+		Snapshot:         r.FormValue("snapshot") != "",
+		Smart:            r.FormValue("smart"),
+		Surprise:         r.FormValue("surprise") != "",
+		Pool:             formLines(r.FormValue("surprise_pool")),
+		Format:           r.FormValue("format") != "",
+		ConfirmFormat:    r.FormValue("confirm_format") != "",
+		ConfirmOverwrite: r.FormValue("confirm_overwrite") != "",
+	}
+
+	var err error
+	req.Radio, err = formRadio(r)
+	if err == nil && r.FormValue("contents") != "" {
+		req.Contents, err = ParseContents([]byte(r.FormValue("contents")))
+	}
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorPage(w, err)
+		return
+	}
+
+	c, err := s.record(req, r.RemoteAddr)
+	if oe, ok := err.(*overwriteError); ok {
+		confirmPage(w, r, req, s.diskContents(oe.devPath, oe.existing))
+		return
+	}
+	if err != nil {
+		w.WriteHeader(recordStatus(err))
+		errorPage(w, err)
+		return
+	}
+
+	http.Redirect(w, r, "/success?web_url="+url.QueryEscape(webUrl(c.URI))+"&device_path="+url.QueryEscape(c.DevicePath),
+		http.StatusFound)
+}
+
+// RecordRequest describes a Spotify album or playlist to be recorded to the disk in a device.
+// The album or playlist is identified by either its Spotify web URL or its Spotify URI. If Current is set, whatever is
+// currently playing on Spotify is recorded instead, as described for CurrentUri, and the web URL and URI are set to
+// identify it.
+// If Snapshot is set the album or playlist must be a playlist, and its current tracks are recorded so that the disk
+// always plays them, however the playlist changes afterwards.
+// If Smart is set to one of the SMART_* sources, smart contents are recorded instead, as described for SmartContents.
+// The album or playlist is then only needed for the SMART_LATEST_EPISODE source, and must be a show.
+// If Radio is set a radio is recorded instead, as described for RadioContents. An album or playlist may be given to
+// seed the radio along with the seeds it holds, and must then be an artist or track.
+// If Surprise is set a surprise disk is recorded instead, as described for SurpriseContents, which chooses from either
+// the album or playlist, which must be a playlist, or the pool of web URLs or URIs.
+// If Contents is set, the contents kept in the catalogue for an earlier recording are recorded again instead, as
+// described for replayContents.
+// If Format and ConfirmFormat are both set the device is first formatted with a FAT filesystem, labelled with the name
+// of the album or playlist. Unless ConfirmOverwrite is set, a disk which already holds a different album or playlist
+// is not recorded over.
+type RecordRequest struct {
+	DevicePath       string    `json:"device_path"`
+	WebURL           string    `json:"web_url"`
+	URI              string    `json:"uri"`
+	Current          bool      `json:"current"`
+	Snapshot         bool      `json:"snapshot"`
+	Smart            string    `json:"smart"`
+	Radio            *Radio    `json:"radio"`
+	Surprise         bool      `json:"surprise"`
+	Pool             []string  `json:"pool"`
+	Contents         *Contents `json:"contents"`
+	Format           bool      `json:"format"`
+	ConfirmFormat    bool      `json:"confirm_format"`
+	ConfirmOverwrite bool      `json:"confirm_overwrite"`
+}
+
+// record records the album or playlist to the disk in the device on behalf of the client at the remote address.
+// The filesystem on the device is detected and it will be mounted using the mounter specified in the diskplayer.yaml
+// configuration file, unless the recorder.write_mode field is set to "direct", in which case the contents are written
+// directly to the FAT filesystem on the device. The contents file is read back after writing to verify it, the disk
+// is stamped with its identity, and a mounted device is always unmounted again.
+// The contents of the disk are returned once recorded, without the album or playlist details. An overwriteError is
+// returned if the disk already holds a different album or playlist and the overwrite was not confirmed. Otherwise a
+// recordError is returned with a status code identifying the step which failed: 403 if the device is not permitted,
+// 400 for an invalid web URL, URI or format request, 409 if the device or its mount point is busy with another
+// operation, 415 for unsupported media, 503 if the device could not be mounted, 507 if the contents or disk identity
+// could not be written, 422 if the contents could not be verified and 500 if the device could not be unmounted.
+func (s *RealDiskplayerServer) record(req *RecordRequest, remote string) (*DiskContents, error) {
+	devPath := req.DevicePath
+	d, err := s.checkDevice(devPath)
+	if err != nil {
+		auditLog("denied %s from %s: %s", devPath, remote, err)
+		return nil, &recordError{http.StatusForbidden, err}
+	}
+
+	if req.Current {
+		err = s.current(req)
+		if err != nil {
+			auditLog("failed %s from %s: %s", devPath, remote, err)
+			return nil, err
+		}
+	}
+
+	target := req.WebURL
+	if req.URI != "" {
+		target = req.URI
+	}
+	if req.Smart != "" {
+		target = strings.TrimSpace(req.Smart + " " + target)
+	}
+	if req.Contents != nil {
+		target = req.Contents.Kind + " " + req.Contents.URI
+	}
+	auditLog("allowed %s from %s: recording %s", devPath, remote, target)
+
+	ct, err := s.recordContents(req)
+	if err != nil {
+		if recordStatus(err) != http.StatusBadRequest {
+			auditLog("failed %s from %s: %s", devPath, remote, err)
+		}
+		return nil, err
+	}
+	uri := ct.URI
+	data, err := ct.Encode()
+	if err != nil {
+		return nil, &recordError{http.StatusInternalServerError, err}
+	}
+
+	release, err := s.lock(d, "recording "+uri)
+	if err != nil {
+		return nil, &recordError{http.StatusConflict, err}
+	}
+	defer release()
+
+	if req.Format {
+		if !req.ConfirmOverwrite {
+			c, err := s.readDisk(d)
+			if err != nil {
+				log.Printf("Unable to read existing contents of %s: %s", devPath, err)
+			} else if c.URI != "" && c.URI != uri {
+				return nil, &overwriteError{devPath, c.URI}
+			}
+		}
+		err := s.formatDevice(d, uri, req.ConfirmFormat)
+		if err != nil {
+			return nil, &recordError{http.StatusBadRequest, err}
+		}
+		auditLog("formatted %s from %s", devPath, remote)
+	}
+
+	fs, err := s.probeFilesystem(devPath)
+	if err == nil && fs.ReadOnly {
+		err = fmt.Errorf("%s media on %s is read-only and cannot be recorded to", fs.Type, devPath)
+	}
+	if err != nil {
+		return nil, &recordError{http.StatusUnsupportedMediaType, err}
+	}
+
+	var id string
+	if ConfigValue(RECORD_WRITE_MODE) == "direct" {
+		if fs.Type != "vfat" {
+			return nil, &recordError{http.StatusUnsupportedMediaType, fmt.Errorf(
+				"%s media on %s cannot be written directly, only FAT formatted disks are supported", fs.Type, devPath)}
+		}
+		if d.MountPoint != "" {
+			return nil, &recordError{http.StatusConflict, fmt.Errorf(
+				"device %s is mounted at %s and cannot be written directly", devPath, d.MountPoint)}
+		}
+		id, err = recordDirect(data, devPath, s.keepDiskId(devPath, fs), req.ConfirmOverwrite)
+	} else {
+		id, err = s.recordMounted(data, devPath, fs, req.ConfirmOverwrite)
+	}
+	if _, ok := err.(*overwriteError); ok {
+		return nil, err
+	}
+	if err != nil {
+		auditLog("failed %s from %s: %s", devPath, remote, err)
+		return nil, err
+	}
+	auditLog("recorded %s %s to %s from %s", ct.Kind, uri, devPath, remote)
+	s.catalogueAdd("record", d, fs, id, data, remote)
+
+	return &DiskContents{DevicePath: devPath, URI: uri, Kind: contentsKind([]byte(data)), DiskID: id}, nil
+}
+
+// recordContents returns the contents to be recorded for the request.
+// A recordError is returned with a 400 status code for an invalid web URL, URI, smart source or radio, or as described
+// for snapshot.
+func (s *RealDiskplayerServer) recordContents(req *RecordRequest) (*Contents, error) {
+	if req.Contents != nil {
+		return replayContents(req.Contents)
+	}
+	if req.Radio != nil {
+		return recordRadio(req)
+	}
+	if req.Surprise {
+		return recordSurprise(req)
+	}
+
+	var uri string
+	if req.Smart == "" || req.Smart == SMART_LATEST_EPISODE {
+		var err error
+		uri, err = recordUri(req)
+		if err != nil {
+			return nil, &recordError{http.StatusBadRequest, err}
+		}
+	}
+
+	if req.Smart != "" {
+		ct, err := SmartContents(req.Smart, uri)
+		if err != nil {
+			return nil, &recordError{http.StatusBadRequest, err}
+		}
+		return ct, nil
+	}
+	if req.Snapshot {
+		return s.snapshot(uri)
+	}
+	return &Contents{Kind: CONTENTS_URI, URI: uri}, nil
+}
+
+// replayContents returns the contents of the request, recorded before and kept in the catalogue, after checking them
+// in the same way as when they were first recorded. The tracks of a snapshot are kept as they are, rather than being
+// looked up again, as are the radio of radio contents and the pool of surprise contents.
+// A recordError is returned with a 400 status code if the contents are invalid.
+func replayContents(ct *Contents) (*Contents, error) {
+	c := ct
+	var err error
+	switch ct.Kind {
+	case CONTENTS_URI:
+		_, err = recordUri(&RecordRequest{URI: ct.URI})
+	case CONTENTS_SNAPSHOT:
+		_, err = recordUri(&RecordRequest{URI: ct.URI})
+		if err == nil && len(ct.Tracks) == 0 {
+			err = fmt.Errorf("snapshot of %s holds no tracks", ct.URI)
+		}
+		for _, t := range ct.Tracks {
+			if err == nil {
+				_, _, err = splitSpotifyUri(t)
+			}
+		}
+	case CONTENTS_SMART:
+		c, err = SmartContents(ct.Source, ct.URI)
+	case CONTENTS_RADIO:
+		c, err = RadioContents(ct.Radio)
+	case CONTENTS_SURPRISE:
+		if len(ct.Pool) > 0 {
+			c, err = SurpriseContents("", ct.Pool)
+		} else {
+			c, err = SurpriseContents(ct.URI, nil)
+		}
+	default:
+		err = fmt.Errorf("unknown contents kind: %s", ct.Kind)
+	}
+	if err != nil {
+		return nil, &recordError{http.StatusBadRequest, err}
+	}
+	return c, nil
+}
+
+// recordRadio returns contents which play the radio of the request, seeded by the album or playlist of the request if
+// one is given.
+// A recordError is returned with a 400 status code for an invalid web URL, URI or radio.
+func recordRadio(req *RecordRequest) (*Contents, error) {
+	r := *req.Radio
+	if req.WebURL != "" || req.URI != "" {
+		u, err := recordUri(req)
+		if err != nil {
+			return nil, &recordError{http.StatusBadRequest, err}
+		}
+		r.Seeds = append([]string{u}, r.Seeds...)
+	}
+
+	ct, err := RadioContents(&r)
+	if err != nil {
+		return nil, &recordError{http.StatusBadRequest, err}
+	}
+	return ct, nil
+}
+
+// recordSurprise returns surprise contents which choose from the playlist or the pool of the request.
+// A recordError is returned with a 400 status code for an invalid web URL or URI, or unless exactly one of a playlist
+// or a pool is given.
+func recordSurprise(req *RecordRequest) (*Contents, error) {
+	var uri string
+	if req.WebURL != "" || req.URI != "" {
+		var err error
+		uri, err = recordUri(req)
+		if err != nil {
+			return nil, &recordError{http.StatusBadRequest, err}
+		}
+	}
+
+	var pool []string
+	for _, p := range req.Pool {
+		pr := &RecordRequest{WebURL: p}
+		if strings.HasPrefix(p, "spotify:") {
+			pr = &RecordRequest{URI: p}
+		}
+		u, err := recordUri(pr)
+		if err != nil {
+			return nil, &recordError{http.StatusBadRequest, err}
+		}
+		pool = append(pool, u)
+	}
+
+	ct, err := SurpriseContents(uri, pool)
+	if err != nil {
+		return nil, &recordError{http.StatusBadRequest, err}
+	}
+	return ct, nil
+}
+
+// formLines returns the lines of a form value, leaving out any which are blank.
+func formLines(v string) []string {
+	var ls []string
+	for _, l := range strings.Split(v, "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			ls = append(ls, l)
+		}
+	}
+	return ls
+}
+
+// formRadio returns the radio given by the values of a recorder form, or nil if the radio value is not set. The
+// radio_genres value is a comma separated list of genres, and the radio_energy and radio_tempo values are the target
+// energy, from 0 to 1, and the target tempo in beats per minute of the tracks played.
+// An error is returned if the energy or tempo is not a number.
+func formRadio(r *http.Request) (*Radio, error) {
+	if r.FormValue("radio") == "" {
+		return nil, nil
+	}
+
+	rd := &Radio{Tuning: map[string]float64{}}
+	for _, g := range strings.Split(r.FormValue("radio_genres"), ",") {
+		if g = strings.TrimSpace(g); g != "" {
+			rd.Genres = append(rd.Genres, g)
+		}
+	}
+	for k, n := range map[string]string{"radio_energy": "target_energy", "radio_tempo": "target_tempo"} {
+		v := strings.TrimSpace(r.FormValue(k))
+		if v == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %s", strings.TrimPrefix(k, "radio_"), v)
+		}
+		rd.Tuning[n] = f
+	}
+	return rd, nil
+}
+
+// snapshot returns contents which play the tracks currently in the playlist identified by the Spotify URI.
+// A recordError is returned with a 503 status code if there is no Spotify client, 400 if the URI does not identify a
+// playlist or 502 if its tracks could not be looked up.
+func (s *RealDiskplayerServer) snapshot(uri string) (*Contents, error) {
+	if s.client == nil {
+		return nil, &recordError{http.StatusServiceUnavailable, errNoClient}
+	}
+	if k, _, _ := splitSpotifyUri(uri); k != "playlist" {
+		return nil, &recordError{http.StatusBadRequest, fmt.Errorf("only playlists can be frozen: %s", uri)}
+	}
+
+	ct, err := Snapshot(s.client, uri)
+	if err != nil {
+		return nil, &recordError{http.StatusBadGateway, fmt.Errorf("unable to freeze %s: %s", uri, err)}
+	}
+	return ct, nil
+}
+
+// current sets the web URL and URI of the request to identify what is currently playing on Spotify.
+// A recordError is returned with a 503 status code if there is no Spotify client, 404 if nothing is playing or 502 if
+// the currently playing item could not be looked up.
+func (s *RealDiskplayerServer) current(req *RecordRequest) error {
+	if s.client == nil {
+		return &recordError{http.StatusServiceUnavailable, errNoClient}
+	}
+
+	u, err := CurrentUri(s.client)
+	if err == ErrNothingPlaying {
+		return &recordError{http.StatusNotFound, err}
+	}
+	if err != nil {
+		return &recordError{http.StatusBadGateway, fmt.Errorf("unable to look up what is currently playing: %s", err)}
+	}
+
+	req.URI = u
+	req.WebURL = webUrl(u)
+	return nil
+}
+
+// recordUri returns the Spotify URI to be recorded for the request, which identifies an album or playlist by either
+// its web URL or its URI.
+// An error is returned if neither identifies an album or playlist.
+func recordUri(req *RecordRequest) (string, error) {
+	if req.URI == "" {
+		return createSpotifyUri(req.WebURL)
+	}
+	k, id, err := splitSpotifyUri(req.URI)
+	if err != nil {
+		return "", err
+	}
+	return createSpotifyUri(spotifyWebUrl(k, id))
+}
+
+// webUrl returns the Spotify web URL for the Spotify URI, or an empty string if it is not a valid URI.
+func webUrl(uri string) string {
+	k, id, err := splitSpotifyUri(uri)
+	if err != nil {
+		return ""
+	}
+	return spotifyWebUrl(k, id)
+}
+
+// recordError is an error encountered during one of the steps of recording a disk, along with the HTTP status code
+// which should be returned for it.
+type recordError struct {
+	status int
+	err    error
+}
+
+func (e *recordError) Error() string {
+	return e.err.Error()
+}
+
+// recordStatus returns the HTTP status code for an error encountered while recording a disk.
+func recordStatus(err error) int {
+	switch e := err.(type) {
+	case *recordError:
+		return e.status
+	case *overwriteError:
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// overwriteError is returned when recording would replace a different album or playlist already on the disk, and
+// the overwrite has not been confirmed.
+type overwriteError struct {
+	devPath  string
+	existing string
+}
+
+func (e *overwriteError) Error() string {
+	return fmt.Sprintf("the disk in %s already holds %s", e.devPath, e.existing)
+}
+
+// recordMounted mounts the device, writes the encoded contents to the contents file and reads them back to verify
+// them, then stamps the disk with its identity.
+// Unless confirmed, an overwriteError is returned without writing if the disk already holds a different Spotify URI.
+// The identity of the disk is returned, or a recordError identifying the step which failed.
+func (s *RealDiskplayerServer) recordMounted(data, devPath string, fs *Filesystem, confirmed bool) (string, error) {
+	var id string
+	err := s.mounted(devPath, fs, func(folder string) error {
+		p := filepath.Join(folder, ConfigValue(RECORD_FILENAME))
+		if !confirmed {
+			b, err := ioutil.ReadFile(p)
+			err = checkOverwrite(data, devPath, b, err)
+			if err != nil {
+				return err
+			}
+		}
+		err := recordFile(data, devPath, p)
+		if err != nil {
+			return err
+		}
+		id, err = stampDisk(folder, s.keepDiskId(devPath, fs))
+		return stampError(devPath, err)
+	})
+	return id, err
+}
+
+// undoMounted mounts the device and restores the previous contents from the backup file alongside the contents file,
+// then stamps the disk with its identity.
+// The restored encoded contents and the identity of the disk are returned, or a recordError identifying the step which
+// failed.
+func (s *RealDiskplayerServer) undoMounted(devPath string, fs *Filesystem) (string, string, error) {
+	var data, id string
+	err := s.mounted(devPath, fs, func(folder string) error {
+		p := filepath.Join(folder, ConfigValue(RECORD_FILENAME))
+		var err error
+		data, err = readBackup(p)
+		if err != nil {
+			return backupError(devPath, err)
+		}
+		err = recordFile(data, devPath, p)
+		if err != nil {
+			return err
+		}
+		id, err = stampDisk(folder, s.keepDiskId(devPath, fs))
+		return stampError(devPath, err)
+	})
+	return data, id, err
+}
+
+// probeFilesystem returns the filesystem found on the device by the mounter if it is a FilesystemProber, otherwise by
+// reading the device, as described for ProbeFilesystem.
+func (s *RealDiskplayerServer) probeFilesystem(devPath string) (*Filesystem, error) {
+	if p, ok := s.mounter.(FilesystemProber); ok {
+		return p.ProbeFilesystem(devPath)
+	}
+	return ProbeFilesystem(devPath)
+}
+
+// mounted mounts the device and calls the function with the folder to which it was mounted. The device is always
+// unmounted once it has been mounted, whether or not the function succeeded, unless it was already mounted by someone
+// else, in which case the existing mount is used and left in place.
+// A recordError is returned if the device could not be mounted or unmounted, otherwise the error returned by the
+// function.
+func (s *RealDiskplayerServer) mounted(devPath string, fs *Filesystem, f func(folder string) error) (err error) {
+	folder, err := s.mounter.Mount(devPath, fs.Type, fs.Options)
+	if ae, ok := err.(*AlreadyMountedError); ok {
+		log.Printf("Using %s, which is already mounted at %s and will be left mounted", devPath, ae.Folder)
+		return f(ae.Folder)
+	}
+	if err != nil {
+		return &recordError{http.StatusServiceUnavailable, fmt.Errorf("unable to mount %s: %s", devPath, err)}
+	}
+	defer func() {
+		uerr := s.mounter.Unmount(devPath)
+		if uerr == nil {
+			return
+		}
+		uerr = fmt.Errorf("unable to unmount %s: %s", devPath, uerr)
+		if err != nil {
+			log.Println(uerr)
+			return
+		}
+		err = &recordError{http.StatusInternalServerError, uerr}
+	}()
+
+	return f(folder)
+}
 
-	folder := ConfigValue(RECORD_FOLDER_PATH)
-	filename := ConfigValue(RECORD_FILENAME)
-	dstPath := folder + "/" + filename
+// recordFile writes the encoded contents to the contents file at the path on the mounted device and reads them back
+// to verify them.
+// A recordError is returned identifying the step which failed.
+func recordFile(data, devPath, p string) error {
+	err := writeToDisk(data, p)
+	if err != nil {
+		return &recordError{http.StatusInsufficientStorage, fmt.Errorf("unable to write to %s: %s", devPath, err)}
+	}
 
-	m, err := mount.Mounted(folder);
+	err = verifyFile(data, p)
 	if err != nil {
+		return &recordError{http.StatusUnprocessableEntity, err}
+	}
+
+	return nil
+}
+
+// recordDirect writes the encoded contents to the contents file on the FAT formatted device without mounting it, and
+// reads them back to verify them, then stamps the disk with its identity. Any existing identity is kept if the keep
+// function returns true.
+// Unless confirmed, an overwriteError is returned without writing if the disk already holds a different Spotify URI.
+// The identity of the disk is returned, or a recordError identifying the step which failed.
+func recordDirect(data, devPath string, keep func(id string) bool, confirmed bool) (string, error) {
+	n := ConfigValue(RECORD_FILENAME)
+	if !confirmed {
+		b, err := ReadDeviceFile(devPath, n)
+		err = checkOverwrite(data, devPath, b, err)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	err := writeToDevice(data, devPath, n)
+	if err != nil {
+		return "", &recordError{http.StatusInsufficientStorage, fmt.Errorf("unable to write to %s: %s", devPath, err)}
+	}
+
+	err = verifyDevice(data, devPath, n)
+	if err != nil {
+		return "", &recordError{http.StatusUnprocessableEntity, err}
+	}
+
+	id, err := stampDevice(devPath, keep)
+	return id, stampError(devPath, err)
+}
+
+// undoDirect restores the previous contents of the FAT formatted device from the backup file without mounting it.
+// The restored encoded contents and the identity of the disk are returned, or a recordError identifying the step
+// which failed.
+func undoDirect(devPath string, keep func(id string) bool) (string, string, error) {
+	data, err := readDeviceBackup(devPath, ConfigValue(RECORD_FILENAME))
+	if err != nil {
+		return "", "", backupError(devPath, err)
+	}
+	id, err := recordDirect(data, devPath, keep, true)
+	return data, id, err
+}
+
+// keepDiskId returns a function which keeps the existing identity of the disk in the device, unless the catalogue
+// shows that it belongs to a disk with another filesystem, in which case the identity was copied from that disk and
+// a new one is stamped in its place.
+func (s *RealDiskplayerServer) keepDiskId(devPath string, fs *Filesystem) func(id string) bool {
+	return func(id string) bool {
+		if s.catalogue.Copied(id, fs.UUID) {
+			log.Printf("Disk %s in %s was copied from another disk, stamping a new disk identity", id, devPath)
+			return false
+		}
+		return true
+	}
+}
+
+// stampError returns a recordError for a failure to write the identity of the disk in the device, or nil if there
+// was no failure.
+func stampError(devPath string, err error) error {
+	if err != nil {
+		return &recordError{http.StatusInsufficientStorage, fmt.Errorf("unable to write disk identity to %s: %s",
+			devPath, err)}
+	}
+	return nil
+}
+
+// checkOverwrite returns an overwriteError if the existing contents read from the disk hold a Spotify URI other than
+// that of the encoded contents being recorded. Contents which could not be read are logged and may be recorded over,
+// as whatever is on the disk could not be played either.
+func checkOverwrite(data, devPath string, b []byte, err error) error {
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Printf("Unable to read existing contents of %s: %s", devPath, err)
+		}
+		return nil
+	}
+	if e := contentsUri(b); e != "" && e != contentsUri([]byte(data)) {
+		return &overwriteError{devPath, e}
+	}
+	return nil
+}
+
+// backupError returns a recordError for a failure to read the backup of the contents file from the device.
+func backupError(devPath string, err error) error {
+	if err == ErrNoBackup {
+		return &recordError{http.StatusNotFound, fmt.Errorf("no previous recording found on %s", devPath)}
+	}
+	return &recordError{http.StatusInternalServerError, fmt.Errorf("unable to read backup from %s: %s", devPath, err)}
+}
+
+// undoHandler handles requests to undo the last recording made to a disk, restoring the contents it held before.
+// The device_path value is the complete path to the disk device.
+// If the undo is successful, redirection to the success page for the restored album or playlist occurs, otherwise
+// an error page is returned with a status code identifying the step which failed, as described for undo.
+func (s *RealDiskplayerServer) undoHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		errorPage(w, errors.New("recordings can only be undone with a POST request"))
+		return
+	}
+
+	c, err := s.undo(r.FormValue("device_path"), r.RemoteAddr)
+	if err != nil {
+		w.WriteHeader(recordStatus(err))
 		errorPage(w, err)
+		return
 	}
 
-	if m == false {
-		err := mount.Mount(devPath, folder, "vfat", "");
+	http.Redirect(w, r, "/success?undone=1&web_url="+url.QueryEscape(webUrl(c.URI))+"&device_path="+
+		url.QueryEscape(c.DevicePath), http.StatusFound)
+}
+
+// undo restores the contents the disk in the device held before it was last recorded, on behalf of the client at the
+// remote address. The device is subject to the same device policy as recording, and is written to using the
+// configured write mode.
+// The restored contents of the disk are returned, without the album or playlist details, or a recordError with the
+// same status codes as for record, or 404 if there is nothing to undo.
+func (s *RealDiskplayerServer) undo(devPath, remote string) (*DiskContents, error) {
+	d, err := s.checkDevice(devPath)
+	if err != nil {
+		auditLog("denied %s from %s: %s", devPath, remote, err)
+		return nil, &recordError{http.StatusForbidden, err}
+	}
+	auditLog("allowed %s from %s: undoing last recording", devPath, remote)
+
+	release, err := s.lock(d, "undoing the last recording")
+	if err != nil {
+		return nil, &recordError{http.StatusConflict, err}
+	}
+	defer release()
+
+	fs, err := s.probeFilesystem(devPath)
+	if err != nil {
+		return nil, &recordError{http.StatusUnsupportedMediaType, err}
+	}
+
+	var data, id string
+	if ConfigValue(RECORD_WRITE_MODE) == "direct" {
+		if d.MountPoint != "" {
+			return nil, &recordError{http.StatusConflict, fmt.Errorf(
+				"device %s is mounted at %s and cannot be written directly", devPath, d.MountPoint)}
+		}
+		data, id, err = undoDirect(devPath, s.keepDiskId(devPath, fs))
+	} else {
+		data, id, err = s.undoMounted(devPath, fs)
+	}
+	if err != nil {
+		auditLog("failed %s from %s: %s", devPath, remote, err)
+		return nil, err
+	}
+	uri := contentsUri([]byte(data))
+	auditLog("restored %s to %s from %s", uri, devPath, remote)
+	s.catalogueAdd("undo", d, fs, id, data, remote)
+
+	return &DiskContents{DevicePath: devPath, URI: uri, Kind: contentsKind([]byte(data)), DiskID: id}, nil
+}
+
+// disk returns the contents of the disk in the device. The device is subject to the same device policy as recording,
+// and the disk is read using the configured write mode, i.e. it is mounted unless the recorder.write_mode field is set
+// to "direct". If a Spotify client is available the album or playlist details are included.
+// A recordError is returned identifying the step which failed.
+func (s *RealDiskplayerServer) disk(devPath string) (*DiskContents, error) {
+	d, err := s.checkDevice(devPath)
+	if err != nil {
+		return nil, &recordError{http.StatusForbidden, err}
+	}
+
+	release, err := s.lock(d, "reading the disk")
+	if err != nil {
+		return nil, &recordError{http.StatusConflict, err}
+	}
+	defer release()
+
+	return s.readDisk(d)
+}
+
+// readDisk reads the Spotify URI from the contents file on the disk in the device, along with the identity of the disk,
+// mounting it unless the recorder.write_mode field is set to "direct". An empty URI is returned if the disk holds no
+// contents file.
+// A recordError is returned identifying the step which failed.
+func (s *RealDiskplayerServer) readDisk(d *BlockDevice) (*DiskContents, error) {
+	fs, err := s.probeFilesystem(d.Path)
+	if err != nil {
+		return nil, &recordError{http.StatusUnsupportedMediaType, err}
+	}
+
+	n := ConfigValue(RECORD_FILENAME)
+	var b []byte
+	var id string
+	if ConfigValue(RECORD_WRITE_MODE) == "direct" {
+		id, err = ReadDeviceDiskId(d.Path)
+		if err == nil {
+			b, err = ReadDeviceFile(d.Path, n)
+		}
+	} else {
+		err = s.mounted(d.Path, fs, func(folder string) error {
+			var err error
+			id, err = ReadDiskId(folder)
+			if err != nil {
+				return err
+			}
+			b, err = ioutil.ReadFile(filepath.Join(folder, n))
+			return err
+		})
+	}
+	if err != nil && !os.IsNotExist(err) {
+		if _, ok := err.(*recordError); !ok {
+			err = &recordError{http.StatusInternalServerError, fmt.Errorf("unable to read %s: %s", d.Path, err)}
+		}
+		return nil, err
+	}
+
+	c := s.diskContents(d.Path, contentsUri(b))
+	c.Kind = contentsKind(b)
+	c.DiskID = id
+	return c, nil
+}
+
+// diskContents returns a description of the disk holding the Spotify URI, including the details of the album or
+// playlist if a Spotify client is available to look them up.
+func (s *RealDiskplayerServer) diskContents(devPath, uri string) *DiskContents {
+	c := &DiskContents{DevicePath: devPath, URI: uri}
+	if uri != "" && s.client != nil {
+		m, err := ResolveMetadata(s.client, uri)
 		if err != nil {
-			errorPage(w, err)
+			log.Printf("Unable to look up details of %s: %s", uri, err)
+		} else {
+			c.Metadata = m
 		}
 	}
+	return c
+}
+
+// catalogueAdd adds the encoded contents recorded to the disk in the device by the action to the catalogue, along
+// with the details of the album or playlist they were recorded from if a Spotify client is available, and the identity
+// of the disk and of the filesystem on it. The recording has already succeeded, so an error writing the catalogue is
+// only logged.
+func (s *RealDiskplayerServer) catalogueAdd(action string, d *BlockDevice, fs *Filesystem, id, data, remote string) {
+	ct, err := ParseContents([]byte(data))
+	if err != nil {
+		log.Printf("Unable to add the contents of %s to the catalogue: %s", d.Path, err)
+		return
+	}
+	uri := ct.URI
+	if ct.Kind == CONTENTS_URI {
+		ct = nil
+	}
 
-	err = Record(webUrl, dstPath)
+	e := CatalogueEntry{
+		Time:       time.Now(),
+		Action:     action,
+		URI:        uri,
+		WebURL:     webUrl(uri),
+		DevicePath: d.Path,
+		Model:      d.Model,
+		DiskID:     id,
+		DiskUUID:   fs.UUID,
+		DiskLabel:  fs.Label,
+		RemoteAddr: remote,
+		Contents:   ct,
+	}
+	if m := s.diskContents(d.Path, uri).Metadata; m != nil {
+		e.Title = m.Title
+		e.Artist = m.Artist
+		e.ImageURL = m.ImageURL
+	}
+
+	err = s.catalogue.Add(e)
+	if err != nil {
+		log.Printf("Unable to add %s on %s to the catalogue: %s", uri, d.Path, err)
+	}
+}
+
+// confirmPage returns an HTML page describing the existing contents of the disk, inserted into the confirm.html
+// template, which asks for confirmation before they are recorded over by the request.
+func confirmPage(w http.ResponseWriter, r *http.Request, req *RecordRequest, c *DiskContents) {
+	p := &ConfirmPage{
+		WebURL:        req.WebURL,
+		DevicePath:    c.DevicePath,
+		Snapshot:      req.Snapshot,
+		Smart:         req.Smart,
+		Radio:         req.Radio != nil,
+		RadioGenres:   r.FormValue("radio_genres"),
+		RadioEnergy:   r.FormValue("radio_energy"),
+		RadioTempo:    r.FormValue("radio_tempo"),
+		Surprise:      req.Surprise,
+		SurprisePool:  r.FormValue("surprise_pool"),
+		Contents:      r.FormValue("contents"),
+		Format:        req.Format,
+		ConfirmFormat: req.ConfirmFormat,
+		Existing:      c,
+		CSRFToken:     csrfToken(r),
+	}
+	w.WriteHeader(http.StatusConflict)
+	pages.render(w, "confirm.html", p)
+}
+
+// successHandler handles requests for the page shown after a successful recording or undo.
+// The web_url value of the recorded disk is used to offer a printable label for download, and the device_path value
+// is used to offer undoing the recording.
+func successHandler(w http.ResponseWriter, r *http.Request) {
+	p := &SuccessPage{
+		WebURL:     r.FormValue("web_url"),
+		DevicePath: r.FormValue("device_path"),
+		Undone:     r.FormValue("undone") != "",
+		CSRFToken:  csrfToken(r),
+	}
+	pages.render(w, "success.html", p)
+}
+
+// loginHandler handles requests to the server for the "/login" location.
+// A GET request returns the login.html template. A POST request logs in with the password form value, setting the
+// session cookie and redirecting to the page given by the next value if successful, or returning the login page with
+// a 401 status code if not.
+func (s *RealDiskplayerServer) loginHandler(w http.ResponseWriter, r *http.Request) {
+	p := &LoginPage{Next: safeRedirect(r.FormValue("next"))}
+	if r.Method == http.MethodPost {
+		ss, err := s.access.Login(r.PostFormValue("password"))
+		if err == nil {
+			auditLog("logged in from %s", r.RemoteAddr)
+			http.SetCookie(w, &http.Cookie{
+				Name:     sessionCookie,
+				Value:    ss.ID,
+				Path:     "/",
+				Expires:  ss.Expires,
+				Secure:   r.TLS != nil,
+				HttpOnly: true,
+				SameSite: http.SameSiteStrictMode,
+			})
+			http.Redirect(w, r, p.Next, http.StatusFound)
+			return
+		}
+		auditLog("failed login from %s: %s", r.RemoteAddr, err)
+		p.Error = err.Error()
+		w.WriteHeader(http.StatusUnauthorized)
+	}
+
+	pages.render(w, "login.html", p)
+}
+
+// logoutHandler handles POST requests to the server for the "/logout" location, ending the session making the
+// request and redirecting to the login page.
+func (s *RealDiskplayerServer) logoutHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		errorPage(w, errors.New("logging out requires a POST request"))
+		return
+	}
+
+	if ss := requestSession(r); ss != nil {
+		s.access.Logout(ss.ID)
+	}
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Value: "", Path: "/", MaxAge: -1})
+	http.Redirect(w, r, "/login", http.StatusFound)
+}
+
+// labelHandler handles requests for a printable SVG disk label.
+// The web_url value is the complete Spotify web URL pointing to an album or playlist, whose details are looked up
+// to populate the label. If the download value is set the label is returned as an attachment.
+// An error page is returned if the label could not be created.
+func (s *RealDiskplayerServer) labelHandler(w http.ResponseWriter, r *http.Request) {
+	if s.client == nil {
+		errorPage(w, errNoClient)
+		return
+	}
+
+	u, err := createSpotifyUri(r.FormValue("web_url"))
 	if err != nil {
 		errorPage(w, err)
+		return
 	}
 
-	err = mount.Unmount(folder);
+	m, err := ResolveMetadata(s.client, u)
 	if err != nil {
 		errorPage(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/svg+xml")
+	if r.FormValue("download") != "" {
+		_, id, _ := splitSpotifyUri(u)
+		w.Header().Set("Content-Disposition", "attachment; filename=\"diskplayer-"+id+".svg\"")
+	}
+	err = WriteLabel(w, m)
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// labelsHandler handles requests for a print sheet containing many disk labels.
+// The web_urls value contains Spotify web URLs, one per line, each of which will be given a label on the sheet.
+func labelsHandler(w http.ResponseWriter, r *http.Request) {
+	p := &LabelsPage{}
+	for _, l := range strings.Split(r.FormValue("web_urls"), "\n") {
+		if l = strings.TrimSpace(l); l != "" {
+			p.WebURLs = append(p.WebURLs, l)
+		}
 	}
 
-	http.Redirect(w, r, "/static/success.html", http.StatusFound)
+	pages.render(w, "labels.html", p)
 }
 
 // indexHandler handles requests to the server for the root location "/".
-// A listing of the attached devices is obtained and applied to the index.html template response.
+// A listing of the attached removable devices is obtained and applied to the index.html template response. If the q
+// value is set, Spotify is searched for it and the results are included so that each can be recorded to the device
+// identified by the device_path value.
 // An error page is returned if an error occurred.
-func indexHandler(w http.ResponseWriter, r *http.Request) {
-	cmd := exec.Command("lsblk", "--nodeps")
-	stdout, err := cmd.Output()
+func (s *RealDiskplayerServer) indexHandler(w http.ResponseWriter, r *http.Request) {
+	ds, err := s.listDevices()
+	if err != nil {
+		errorPage(w, err)
+		return
+	}
+
+	p := &IndexPage{
+		Devices:    ds,
+		CSRFToken:  csrfToken(r),
+		Query:      r.FormValue("q"),
+		DevicePath: r.FormValue("device_path"),
+	}
+	if p.Query != "" {
+		p.Results, err = s.search(r)
+		if err != nil {
+			p.SearchError = err.Error()
+		}
+	}
+	pages.render(w, "index.html", p)
+}
+
+// errNoClient is returned for requests which need the Spotify Web API when the recorder has no Spotify client.
+var errNoClient = errors.New("no Spotify client available, run \"player -auth\" to obtain a token")
+
+// search searches Spotify for albums, playlists, artists and shows matching the q value of the request, returning up
+// to the number of each given by the limit value.
+// A recordError is returned if there is no Spotify client, the request is invalid or the search failed.
+func (s *RealDiskplayerServer) search(r *http.Request) ([]SearchItem, error) {
+	if s.client == nil {
+		return nil, &recordError{http.StatusServiceUnavailable, errNoClient}
+	}
+
+	q := strings.TrimSpace(r.FormValue("q"))
+	if q == "" {
+		return nil, &recordError{http.StatusBadRequest, errors.New("a search query is required")}
+	}
+
+	l := defaultSearchLimit
+	if v := r.FormValue("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > 50 {
+			return nil, &recordError{http.StatusBadRequest, fmt.Errorf("invalid limit: %s", v)}
+		}
+		l = n
+	}
+
+	is, err := SearchSpotify(s.client, q, l)
+	if err != nil {
+		return nil, &recordError{http.StatusBadGateway, fmt.Errorf("unable to search Spotify: %s", err)}
+	}
+	return is, nil
+}
+
+// catalogueHandler handles requests to the server for the "/catalogue" location.
+// The catalogue of recordings is searched using the q, kind, device_path and disk query values, and the matching
+// entries are applied to the catalogue.html template response along with the albums and playlists held by more than
+// one disk, and the attached removable devices so that any entry can be recorded again.
+// An error page is returned if the search is invalid.
+func (s *RealDiskplayerServer) catalogueHandler(w http.ResponseWriter, r *http.Request) {
+	f, err := catalogueFilter(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		errorPage(w, err)
+		return
+	}
+
+	ds, err := s.listDevices()
+	if err != nil {
+		log.Printf("Unable to list devices for the catalogue: %s", err)
+	}
+
+	q := url.Values{"q": {f.Query}, "kind": {f.Kind}, "device_path": {f.DevicePath}, "disk": {f.DiskID}}
+	p := &CataloguePage{
+		Entries:    s.catalogue.Search(f),
+		Devices:    ds,
+		Query:      f.Query,
+		Kind:       f.Kind,
+		DevicePath: f.DevicePath,
+		DiskID:     f.DiskID,
+		ExportCSV:  exportUrl(q, "csv"),
+		ExportJSON: exportUrl(q, "json"),
+		Duplicates: s.catalogue.Duplicates(),
+		CSRFToken:  csrfToken(r),
+	}
+	pages.render(w, "catalogue.html", p)
+}
+
+// libraryHandler handles requests to the server for the "/library" location.
+// A page of the albums, playlists or shows in the library of the authenticated Spotify user is obtained using the kind,
+// q, offset and limit values and applied to the library.html template response, so that each can be recorded to the
+// device identified by the device_path value.
+func (s *RealDiskplayerServer) libraryHandler(w http.ResponseWriter, r *http.Request) {
+	ds, err := s.listDevices()
 	if err != nil {
-		errorPage(w, err);
+		log.Printf("Unable to list devices for the library: %s", err)
 	}
 
-	p := &IndexPage{Lsblk: stdout}
-	t, _ := template.ParseFiles("./templates/index.html")
-	t.Execute(w, p)
+	p := &LibraryPage{
+		Devices:    ds,
+		Query:      r.FormValue("q"),
+		Kind:       libraryKind(r),
+		DevicePath: r.FormValue("device_path"),
+		CSRFToken:  csrfToken(r),
+	}
+	p.Results, err = s.library(r)
+	if err != nil {
+		w.WriteHeader(recordStatus(err))
+		p.Error = err.Error()
+	} else {
+		l := p.Results.Limit
+		q := url.Values{"kind": {p.Kind}, "q": {p.Query}, "device_path": {p.DevicePath}, "limit": {strconv.Itoa(l)}}
+		if o := p.Results.Offset; o > 0 {
+			if o -= l; o < 0 {
+				o = 0
+			}
+			q.Set("offset", strconv.Itoa(o))
+			p.Previous = "/library?" + q.Encode()
+		}
+		if o := p.Results.Offset + l; o < p.Results.Total {
+			q.Set("offset", strconv.Itoa(o))
+			p.Next = "/library?" + q.Encode()
+		}
+	}
+	pages.render(w, "library.html", p)
+}
+
+// defaultLibraryLimit is the number of items in a page of the user's library unless the request gives a limit.
+const defaultLibraryLimit = 20
+
+// libraryKind returns the kind of library item requested by the kind value of the request, which defaults to albums.
+func libraryKind(r *http.Request) string {
+	if k := r.FormValue("kind"); k != "" {
+		return k
+	}
+	return "album"
+}
+
+// library returns the page of the library of the authenticated Spotify user given by the kind, q, offset and limit
+// values of the request.
+// A recordError is returned if there is no Spotify client, the request is invalid or the library could not be read.
+func (s *RealDiskplayerServer) library(r *http.Request) (*LibraryResults, error) {
+	if s.client == nil {
+		return nil, &recordError{http.StatusServiceUnavailable, errNoClient}
+	}
+
+	k := libraryKind(r)
+	if k != "album" && k != "playlist" && k != "show" {
+		return nil, &recordError{http.StatusBadRequest, fmt.Errorf("invalid library kind: %s", k)}
+	}
+
+	o := 0
+	if v := r.FormValue("offset"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return nil, &recordError{http.StatusBadRequest, fmt.Errorf("invalid offset: %s", v)}
+		}
+		o = n
+	}
+
+	l := defaultLibraryLimit
+	if v := r.FormValue("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > libraryPageLimit {
+			return nil, &recordError{http.StatusBadRequest, fmt.Errorf("invalid limit: %s", v)}
+		}
+		l = n
+	}
+
+	lr, err := Library(s.client, k, r.FormValue("q"), o, l)
+	if err != nil {
+		return nil, &recordError{http.StatusBadGateway, fmt.Errorf("unable to read the Spotify library: %s", err)}
+	}
+	return lr, nil
+}
+
+// exportUrl returns the location from which the catalogue entries matching the filter values are downloaded in the
+// given format.
+func exportUrl(q url.Values, format string) string {
+	v := url.Values{"format": {format}}
+	for k, vs := range q {
+		v[k] = vs
+	}
+	return "/api/v1/history/export?" + v.Encode()
+}
+
+// DeviceStatus describes a removable device which the recorder is permitted to use, along with the operation using it,
+// if any.
+type DeviceStatus struct {
+	BlockDevice
+	Description string     `json:"description"`
+	Busy        *Operation `json:"busy,omitempty"`
+}
+
+// listDevices returns the attached removable devices permitted by the device policy defined in the diskplayer.yaml
+// configuration file, along with the operation using each of them.
+// An error is returned if the devices could not be listed.
+func (s *RealDiskplayerServer) listDevices() ([]DeviceStatus, error) {
+	ds, err := s.devices()
+	if err != nil {
+		return nil, err
+	}
+
+	ds = NewDevicePolicy().Filter(ds)
+	o := make([]DeviceStatus, len(ds))
+	for i, d := range ds {
+		o[i] = DeviceStatus{BlockDevice: d, Description: d.Description(), Busy: s.locks.Busy(d.Path)}
+	}
+	return o, nil
+}
+
+// formatDevice formats the device with a FAT filesystem labelled with the name of the album or playlist identified by
+// the Spotify URI. Formatting must be confirmed as it erases the existing contents of the device.
+// Returns an error if one is encountered.
+func (s *RealDiskplayerServer) formatDevice(d *BlockDevice, u string, confirmed bool) error {
+	if !confirmed {
+		return errors.New("formatting erases everything on the disk and must be confirmed")
+	}
+	if d.MountPoint != "" {
+		return fmt.Errorf("device %s is mounted at %s and cannot be formatted", d.Path, d.MountPoint)
+	}
+
+	l := "DISKPLAYER"
+	if s.client != nil {
+		m, err := ResolveMetadata(s.client, u)
+		if err != nil {
+			log.Printf("Unable to look up volume label for %s: %s", u, err)
+		} else {
+			l = m.Title
+		}
+	}
+
+	return FormatDevice(d.Path, l)
+}
+
+// lock holds the device, and the mount point it will be mounted to unless the recorder.write_mode field is set to
+// "direct", for the duration of the operation with the given description.
+// A function which releases them is returned, or a BusyError if another operation is using either.
+func (s *RealDiskplayerServer) lock(d *BlockDevice, description string) (func(), error) {
+	var mp string
+	if ConfigValue(RECORD_WRITE_MODE) != "direct" {
+		mp = s.mounter.MountPoint(d.Path)
+	}
+	return s.locks.Acquire(d.Path, mp, description)
+}
+
+// checkDevice returns the device identified by the path, or an error if it is not permitted by the device policy
+// defined in the diskplayer.yaml configuration file.
+func (s *RealDiskplayerServer) checkDevice(path string) (*BlockDevice, error) {
+	ds, err := s.devices()
+	if err != nil {
+		return nil, err
+	}
+	return NewDevicePolicy().Check(path, ds)
 }
 
 // errorPage returns an HTML error page, inserting error details into the error.html template.
 func errorPage(w http.ResponseWriter, err error) {
 	p := &ErrorPage{Body: []byte(err.Error())}
-	t, _ := template.ParseFiles("./templates/error.html")
-	t.Execute(w, p)
+	pages.render(w, "error.html", p)
 }
 
 type CallbackHandler struct {
@@ -3,12 +3,21 @@ package diskplayer
 import (
 	"context"
 	"errors"
+	"github.com/dinofizz/diskplayer/mocks"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
 	"github.com/zmb3/spotify"
 	"golang.org/x/oauth2"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -35,6 +44,25 @@ func TestRealDiskplayerServer_RunCallbackServer(t *testing.T) {
 	assert.NoError(t,err)
 }
 
+func TestRealDiskplayerServer_RunCallbackServerTLS(t *testing.T) {
+	viper.Set("spotify.callback_url", "https://localhost:8733/callback")
+	defer viper.Set("spotify.callback_url", "http://localhost:8732/callback")
+	ds := NewDiskplayerServer(&spotify.Authenticator{}, make(chan *oauth2.Token, 1))
+
+	_, err := ds.RunCallbackServer()
+	assert.EqualError(t, err, "callback URL https://localhost:8733/callback uses https but tls.mode is off")
+
+	_, _, cleanup := tlsFiles(t, "self_signed")
+	defer cleanup()
+	s, err := ds.RunCallbackServer()
+	assert.NoError(t, err)
+	assert.Equal(t, ":8733", s.Addr)
+	assert.NotNil(t, s.TLSConfig)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, s.Shutdown(ctx))
+}
+
 func TestErrorHandler(t *testing.T) {
 	rr := httptest.NewRecorder()
 	err := errors.New("New error")
@@ -48,7 +76,7 @@ func TestIndexHandler(t *testing.T) {
 	}
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(indexHandler)
+	handler := http.HandlerFunc(NewRecordServer(nil, nil).indexHandler)
 
 	handler.ServeHTTP(rr, req)
 
@@ -57,3 +85,1058 @@ func TestIndexHandler(t *testing.T) {
 			status, http.StatusOK)
 	}
 }
+
+func TestIndexHandlerSearch(t *testing.T) {
+	s := NewRecordServer(searchClient(), nil)
+	s.devices = func() ([]BlockDevice, error) {
+		return []BlockDevice{{Path: "/dev/sdb", Size: 1474560, Type: "disk", Removable: true}}, nil
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(s.indexHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/?q=test&device_path=/dev/sdb", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	b := rr.Body.String()
+	assert.Contains(t, b, `<img src="https://i.scdn.co/image/album"`)
+	assert.Contains(t, b, `<button type="submit" form="record_spotify:album:1S7mumn7D4riEX2gVWYgPO">Record</button>`)
+	assert.Contains(t, b, `<input type="hidden" name="web_url" value="https://open.spotify.com/show/4rOoJ6Egrf8K2IrywzwOMk">`)
+	assert.Contains(t, b, `<input type="hidden" name="device_path" value="/dev/sdb">`)
+
+	s.client = nil
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(s.indexHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/?q=test", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Unable to search: no Spotify client available")
+}
+
+func TestLibraryHandler(t *testing.T) {
+	s := NewRecordServer(libraryClient(45), nil)
+	s.devices = func() ([]BlockDevice, error) {
+		return []BlockDevice{{Path: "/dev/sdb", Size: 1474560, Type: "disk", Removable: true}}, nil
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(s.libraryHandler).ServeHTTP(rr,
+		httptest.NewRequest("GET", "/library?device_path=/dev/sdb&offset=20", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	b := rr.Body.String()
+	assert.Contains(t, b, "<strong>Album 20</strong>")
+	assert.Contains(t, b, `<input type="hidden" name="web_url" value="https://open.spotify.com/album/album39">`)
+	assert.Contains(t, b, `<input type="hidden" name="device_path" value="/dev/sdb">`)
+	assert.Contains(t, b, "Showing 20 of 45")
+	assert.Contains(t, b, `<a href="/library?device_path=%2Fdev%2Fsdb&amp;kind=album&amp;limit=20&amp;offset=0&amp;q=">Previous</a>`)
+	assert.Contains(t, b, `<a href="/library?device_path=%2Fdev%2Fsdb&amp;kind=album&amp;limit=20&amp;offset=40&amp;q=">Next</a>`)
+
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(s.libraryHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/library?kind=florble", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Unable to read the library: invalid library kind: florble")
+}
+
+func TestSuccessHandler(t *testing.T) {
+	req, err := http.NewRequest("GET", "/success?web_url=https://open.spotify.com/album/1S7mumn7D4riEX2gVWYgPO", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(successHandler).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "/label?web_url=https%3a%2f%2fopen.spotify.com%2falbum%2f1S7mumn7D4riEX2gVWYgPO")
+}
+
+func TestSuccessHandlerUndo(t *testing.T) {
+	req, err := http.NewRequest("GET", "/success?device_path=/dev/sda", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(successHandler).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "<input type=\"hidden\" name=\"device_path\" value=\"/dev/sda\">")
+	assert.Contains(t, rr.Body.String(), "Undo recording")
+}
+
+func TestLabelHandler(t *testing.T) {
+	m := new(mocks.Client)
+	a := &spotify.FullAlbum{}
+	a.Name = "Test Album"
+	m.On("GetAlbum", spotify.ID("1S7mumn7D4riEX2gVWYgPO")).Return(a, nil)
+
+	req, err := http.NewRequest("GET", "/label?download=1&web_url=https://open.spotify.com/album/1S7mumn7D4riEX2gVWYgPO", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	s := NewRecordServer(m, nil)
+	http.HandlerFunc(s.labelHandler).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "image/svg+xml", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "attachment; filename=\"diskplayer-1S7mumn7D4riEX2gVWYgPO.svg\"", rr.Header().Get("Content-Disposition"))
+	assert.Contains(t, rr.Body.String(), "Test Album")
+}
+
+func TestLabelHandlerNoClient(t *testing.T) {
+	req, err := http.NewRequest("GET", "/label?web_url=https://open.spotify.com/album/1S7mumn7D4riEX2gVWYgPO", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	s := NewRecordServer(nil, nil)
+	http.HandlerFunc(s.labelHandler).ServeHTTP(rr, req)
+
+	assert.Contains(t, rr.Body.String(), "no Spotify client available")
+}
+
+func TestLabelsHandler(t *testing.T) {
+	req, err := http.NewRequest("GET", "/labels?web_urls=https://open.spotify.com/album/a%0A%0Ahttps://open.spotify.com/playlist/b", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(labelsHandler).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, 2, strings.Count(rr.Body.String(), "<img src=\"/label?web_url="))
+}
+
+func TestRecordHandlerDeviceDenied(t *testing.T) {
+	req, err := http.NewRequest("POST", "/record", strings.NewReader("device_path=/dev/not_a_real_device&web_url=x"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(NewRecordServer(nil, nil).recordHandler).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Contains(t, rr.Body.String(), "device /dev/not_a_real_device is not an attached removable device")
+}
+
+func TestFormatDeviceNotConfirmed(t *testing.T) {
+	s := NewRecordServer(nil, nil)
+	d := &BlockDevice{Path: "/dev/sda"}
+	err := s.formatDevice(d, "spotify:album:1S7mumn7D4riEX2gVWYgPO", false)
+	assert.EqualError(t, err, "formatting erases everything on the disk and must be confirmed")
+}
+
+func TestFormatDeviceMounted(t *testing.T) {
+	s := NewRecordServer(nil, nil)
+	d := &BlockDevice{Path: "/dev/sda", MountPoint: "/media/floppy"}
+	err := s.formatDevice(d, "spotify:album:1S7mumn7D4riEX2gVWYgPO", true)
+	assert.EqualError(t, err, "device /dev/sda is mounted at /media/floppy and cannot be formatted")
+}
+
+// recordServer returns a record server using a fake mounter rooted in a temporary folder, which lists the FAT
+// formatted image as its only attached removable device.
+func recordServer(t *testing.T) (*RealDiskplayerServer, *FakeMounter, string, func()) {
+	viper.Set("recorder.filename", "diskplayer.contents")
+	viper.Set("recorder.write_mode", "mount")
+	viper.Set("recorder.policy.deny", []string{})
+	viper.Set("recorder.catalogue", "")
+
+	img := formattedImage(t)
+	root, err := ioutil.TempDir("", "diskplayer_record")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := NewFakeMounter(root)
+	s := NewRecordServer(nil, m)
+	s.devices = func() ([]BlockDevice, error) {
+		return []BlockDevice{{Path: img, Size: 64 * 1024, Type: "disk", Removable: true}}, nil
+	}
+	return s, m, img, func() {
+		os.Remove(img)
+		os.RemoveAll(root)
+	}
+}
+
+func postRecord(s *RealDiskplayerServer, webUrl, devPath string, confirm bool) *httptest.ResponseRecorder {
+	v := url.Values{"web_url": {webUrl}, "device_path": {devPath}}
+	if confirm {
+		v.Set("confirm_overwrite", "1")
+	}
+	req := httptest.NewRequest("POST", "/record", strings.NewReader(v.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(s.recordHandler).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestRecordHandler(t *testing.T) {
+	s, m, img, cleanup := recordServer(t)
+	defer cleanup()
+
+	rr := postRecord(s, recordTests[0].in, img, false)
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, "/success?web_url="+url.QueryEscape(recordTests[0].in)+"&device_path="+url.QueryEscape(img),
+		rr.Header().Get("Location"))
+
+	b, err := ioutil.ReadFile(filepath.Join(m.Folder(img), "diskplayer.contents"))
+	assert.NoError(t, err)
+	assert.Equal(t, recordTests[0].out, string(b))
+	assert.False(t, m.Mounted(img))
+	mounts, unmounts := m.Counts()
+	assert.Equal(t, 1, mounts)
+	assert.Equal(t, 1, unmounts)
+}
+
+func TestRecordHandlerUdisks(t *testing.T) {
+	s, _, _, cleanup := recordServer(t)
+	defer cleanup()
+	folder, err := ioutil.TempDir("", "diskplayer_udisks")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(folder)
+
+	// The device cannot be read by the recorder, so the filesystem on it must be found by udisks2.
+	devPath := filepath.Join(folder, "sdz")
+	s.devices = func() ([]BlockDevice, error) {
+		return []BlockDevice{{Path: devPath, Size: 64 * 1024, Type: "disk", Removable: true}}, nil
+	}
+	s.mounter = &UdisksMounter{}
+	d := &udisksDevice{folder: folder, props: map[string]string{udisksBlock + ".IdType": "vfat",
+		udisksBlock + ".IdUUID": "ABCD-1234", udisksBlock + ".IdLabel": "DISKPLAYER"}}
+	defer useUdisksDevice(d)()
+
+	rr := postRecord(s, recordTests[0].in, devPath, false)
+	assert.Equal(t, http.StatusFound, rr.Code)
+
+	b, err := ioutil.ReadFile(filepath.Join(folder, "diskplayer.contents"))
+	assert.NoError(t, err)
+	assert.Equal(t, recordTests[0].out, string(b))
+	assert.Equal(t, []string{udisksFilesystem + ".Mount", udisksFilesystem + ".Unmount"}, d.calls)
+
+	es := s.catalogue.Search(CatalogueFilter{})
+	if assert.Len(t, es, 1) {
+		assert.Equal(t, "ABCD-1234", es[0].DiskUUID)
+		assert.Equal(t, "DISKPLAYER", es[0].DiskLabel)
+	}
+
+	// A disk which was already mounted, e.g. by the automounter of a desktop, is recorded but left mounted.
+	d.automounted = true
+	d.calls = nil
+	rr = postRecord(s, recordTests[1].in, devPath, true)
+	assert.Equal(t, http.StatusFound, rr.Code)
+	b, err = ioutil.ReadFile(filepath.Join(folder, "diskplayer.contents"))
+	assert.NoError(t, err)
+	assert.Equal(t, recordTests[1].out, string(b))
+	assert.Equal(t, []string{udisksFilesystem + ".Mount"}, d.calls)
+}
+
+func TestRecordHandlerCurrent(t *testing.T) {
+	s, m, img, cleanup := recordServer(t)
+	defer cleanup()
+	c := currentlyPlaying("spotify:album:1S7mumn7D4riEX2gVWYgPO", "4uLU6hMCjMI75M1A2tKUQC")
+	c.On("GetAlbum", spotify.ID("1S7mumn7D4riEX2gVWYgPO")).Return(&spotify.FullAlbum{}, nil)
+	s.client = c
+
+	v := url.Values{"current": {"1"}, "web_url": {""}, "device_path": {img}}
+	req := httptest.NewRequest("POST", "/record", strings.NewReader(v.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(s.recordHandler).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, "/success?web_url="+url.QueryEscape(recordTests[0].in)+"&device_path="+url.QueryEscape(img),
+		rr.Header().Get("Location"))
+	b, err := ioutil.ReadFile(filepath.Join(m.Folder(img), "diskplayer.contents"))
+	assert.NoError(t, err)
+	assert.Equal(t, recordTests[0].out, string(b))
+
+	s.client = currentlyPlaying("", "")
+	rr = apiPost(s.apiRecordHandler, "/api/v1/record", `{"device_path":"`+img+`","current":true}`)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.JSONEq(t, `{"error":{"status":404,"message":"nothing is currently playing on Spotify"}}`, rr.Body.String())
+
+	s.client = nil
+	rr = apiPost(s.apiRecordHandler, "/api/v1/record", `{"device_path":"`+img+`","current":true}`)
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+}
+
+func TestRecordHandlerSnapshot(t *testing.T) {
+	s, m, img, cleanup := recordServer(t)
+	defer cleanup()
+	s.client = playlistClient(3)
+
+	rr := apiPost(s.apiRecordHandler, "/api/v1/record", `{"device_path":"`+img+`","uri":"`+recordTests[1].out+
+		`","snapshot":true}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"uri":"`+recordTests[1].out+`","kind":"snapshot"`)
+
+	b, err := ioutil.ReadFile(filepath.Join(m.Folder(img), "diskplayer.contents"))
+	assert.NoError(t, err)
+	c, err := ParseContents(b)
+	assert.NoError(t, err)
+	assert.Equal(t, &Contents{Kind: CONTENTS_SNAPSHOT, URI: recordTests[1].out, Title: "Test Playlist",
+		Tracks: []string{"spotify:track:0", "spotify:track:1", "spotify:track:2"}}, c)
+
+	rr = apiGet(s.apiDiskHandler, "/api/v1/disk?device_path="+url.QueryEscape(img))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"uri":"`+recordTests[1].out+`","kind":"snapshot"`)
+
+	// Recording the same playlist without freezing it does not need confirming, and the frozen contents are kept
+	// as the backup.
+	rr = postRecord(s, recordTests[1].in, img, false)
+	assert.Equal(t, http.StatusFound, rr.Code)
+	b, err = ioutil.ReadFile(filepath.Join(m.Folder(img), "diskplayer.contents.bak"))
+	assert.NoError(t, err)
+	assert.Equal(t, CONTENTS_SNAPSHOT, contentsKind(b))
+
+	rr = apiPost(s.apiUndoHandler, "/api/v1/undo", `{"device_path":"`+img+`"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"uri":"`+recordTests[1].out+`","kind":"snapshot"`)
+
+	rr = apiPost(s.apiRecordHandler, "/api/v1/record", `{"device_path":"`+img+`","uri":"`+recordTests[0].out+
+		`","snapshot":true}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.JSONEq(t, `{"error":{"status":400,"message":"only playlists can be frozen: `+recordTests[0].out+`"}}`,
+		rr.Body.String())
+}
+
+// postContents posts the encoded contents to the record handler to be recorded to the disk in the device, as the
+// "Re-record this" button of the catalogue does.
+func postContents(s *RealDiskplayerServer, data, devPath string, confirm bool) *httptest.ResponseRecorder {
+	v := url.Values{"contents": {data}, "device_path": {devPath}}
+	if confirm {
+		v.Set("confirm_overwrite", "1")
+	}
+	req := httptest.NewRequest("POST", "/record", strings.NewReader(v.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(s.recordHandler).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestRecordHandlerReRecordSnapshot(t *testing.T) {
+	s, m, img, cleanup := recordServer(t)
+	defer cleanup()
+	s.client = playlistClient(3)
+
+	rr := apiPost(s.apiRecordHandler, "/api/v1/record", `{"device_path":"`+img+`","uri":"`+recordTests[1].out+
+		`","snapshot":true}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	es := s.catalogue.Search(CatalogueFilter{})
+	if !assert.Len(t, es, 1) {
+		return
+	}
+	ct := &Contents{Kind: CONTENTS_SNAPSHOT, URI: recordTests[1].out, Title: "Test Playlist",
+		Tracks: []string{"spotify:track:0", "spotify:track:1", "spotify:track:2"}}
+	assert.Equal(t, ct, es[0].Contents)
+
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(s.catalogueHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/catalogue", nil))
+	assert.Contains(t, rr.Body.String(), `<input type="hidden" name="contents" value="{`)
+	assert.NotContains(t, rr.Body.String(), `<input type="hidden" name="web_url" value="`+recordTests[1].in+`">`)
+
+	// The frozen tracks are recorded again without looking up the playlist, which has since changed.
+	s.client = nil
+	assert.Equal(t, http.StatusFound, postRecord(s, recordTests[0].in, img, true).Code)
+	rr = postContents(s, es[0].EncodedContents(), img, false)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Contains(t, rr.Body.String(), `<input type="hidden" name="contents" value="{`)
+	assert.Equal(t, http.StatusFound, postContents(s, es[0].EncodedContents(), img, true).Code)
+
+	b, err := ioutil.ReadFile(filepath.Join(m.Folder(img), "diskplayer.contents"))
+	assert.NoError(t, err)
+	c, err := ParseContents(b)
+	assert.NoError(t, err)
+	assert.Equal(t, ct, c)
+
+	rr = postContents(s, `{"kind":"snapshot","uri":"`+recordTests[1].out+`"}`, img, true)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "snapshot of "+recordTests[1].out+" holds no tracks")
+	rr = postContents(s, `{"kind":`, img, true)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "invalid contents")
+}
+
+func TestRecordHandlerSmart(t *testing.T) {
+	s, m, img, cleanup := recordServer(t)
+	defer cleanup()
+
+	rr := apiPost(s.apiRecordHandler, "/api/v1/record", `{"device_path":"`+img+`","smart":"liked_songs"}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"uri":"diskplayer:liked_songs","kind":"smart"`)
+
+	b, err := ioutil.ReadFile(filepath.Join(m.Folder(img), "diskplayer.contents"))
+	assert.NoError(t, err)
+	c, err := ParseContents(b)
+	assert.NoError(t, err)
+	assert.Equal(t, &Contents{Kind: CONTENTS_SMART, Source: SMART_LIKED_SONGS, URI: "diskplayer:liked_songs",
+		Title: "Liked Songs"}, c)
+
+	// Recording the latest episode of a show over the Liked Songs needs confirming like any other recording.
+	rr = apiPost(s.apiRecordHandler, "/api/v1/record", `{"device_path":"`+img+`","web_url":"`+recordTests[3].in+
+		`","smart":"latest_episode"}`)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+
+	rr = apiPost(s.apiRecordHandler, "/api/v1/record", `{"device_path":"`+img+`","web_url":"`+recordTests[3].in+
+		`","smart":"latest_episode","confirm_overwrite":true}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"uri":"`+recordTests[3].out+`","kind":"smart"`)
+
+	rr = apiPost(s.apiRecordHandler, "/api/v1/record", `{"device_path":"`+img+`","web_url":"`+recordTests[0].in+
+		`","smart":"latest_episode"}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.JSONEq(t, `{"error":{"status":400,"message":"the latest episode can only be recorded for a show: `+
+		recordTests[0].out+`"}}`, rr.Body.String())
+
+	rr = apiPost(s.apiRecordHandler, "/api/v1/record", `{"device_path":"`+img+`","smart":"florble"}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.JSONEq(t, `{"error":{"status":400,"message":"unknown smart contents source: florble"}}`,
+		rr.Body.String())
+}
+
+func TestRecordHandlerRadio(t *testing.T) {
+	s, m, img, cleanup := recordServer(t)
+	defer cleanup()
+
+	v := url.Values{"web_url": {recordTests[2].in}, "device_path": {img}, "radio": {"1"},
+		"radio_genres": {"jazz, soul,"}, "radio_energy": {"0.8"}, "radio_tempo": {""}}
+	req := httptest.NewRequest("POST", "/record", strings.NewReader(v.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(s.recordHandler).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusFound, rr.Code)
+
+	b, err := ioutil.ReadFile(filepath.Join(m.Folder(img), "diskplayer.contents"))
+	assert.NoError(t, err)
+	c, err := ParseContents(b)
+	assert.NoError(t, err)
+	assert.Equal(t, &Contents{Kind: CONTENTS_RADIO, URI: recordTests[2].out, Title: "Radio", Radio: &Radio{
+		Seeds: []string{recordTests[2].out}, Genres: []string{"jazz", "soul"},
+		Tuning: map[string]float64{"target_energy": 0.8}}}, c)
+
+	v.Set("radio_tempo", "fast")
+	req = httptest.NewRequest("POST", "/record", strings.NewReader(v.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(s.recordHandler).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "invalid tempo: fast")
+
+	rr = apiPost(s.apiRecordHandler, "/api/v1/record", `{"device_path":"`+img+`","web_url":"`+recordTests[0].in+
+		`","radio":{"genres":["jazz"]}}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.JSONEq(t, `{"error":{"status":400,"message":"a radio can only be seeded by artists, tracks and genres: `+
+		recordTests[0].out+`"}}`, rr.Body.String())
+}
+
+func TestRecordHandlerSurprise(t *testing.T) {
+	s, m, img, cleanup := recordServer(t)
+	defer cleanup()
+
+	v := url.Values{"device_path": {img}, "surprise": {"1"},
+		"surprise_pool": {recordTests[0].in + "\n\n spotify:playlist:b \n" + recordTests[3].in}}
+	req := httptest.NewRequest("POST", "/record", strings.NewReader(v.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(s.recordHandler).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusFound, rr.Code)
+
+	b, err := ioutil.ReadFile(filepath.Join(m.Folder(img), "diskplayer.contents"))
+	assert.NoError(t, err)
+	c, err := ParseContents(b)
+	assert.NoError(t, err)
+	assert.Equal(t, CONTENTS_SURPRISE, c.Kind)
+	assert.Equal(t, []string{recordTests[0].out, "spotify:playlist:b", recordTests[3].out}, c.Pool)
+
+	rr = apiPost(s.apiRecordHandler, "/api/v1/record", `{"device_path":"`+img+`","web_url":"`+recordTests[1].in+
+		`","surprise":true,"confirm_overwrite":true}`)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `"uri":"`+recordTests[1].out+`","kind":"surprise"`)
+
+	rr = apiPost(s.apiRecordHandler, "/api/v1/record", `{"device_path":"`+img+`","surprise":true,"pool":["florble"]}`)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.JSONEq(t, `{"error":{"status":400,"message":"URL represents neither album, playlist, artist, show nor `+
+		`track: florble"}}`, rr.Body.String())
+}
+
+var reRecordTests = []struct {
+	name string
+	body string
+}{
+	{"smart", `"smart":"liked_songs"`},
+	{"latest episode", `"web_url":"` + recordTests[3].in + `","smart":"latest_episode"`},
+	{"radio", `"radio":{"genres":["jazz","soul"],"tuning":{"target_energy":0.8}}`},
+	{"surprise pool", `"surprise":true,"pool":["` + recordTests[0].out + `","` + recordTests[3].out + `"]`},
+	{"surprise playlist", `"web_url":"` + recordTests[1].in + `","surprise":true`},
+}
+
+func TestRecordHandlerReRecord(t *testing.T) {
+	for _, tt := range reRecordTests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, m, img, cleanup := recordServer(t)
+			defer cleanup()
+
+			rr := apiPost(s.apiRecordHandler, "/api/v1/record", `{"device_path":"`+img+`",`+tt.body+`}`)
+			assert.Equal(t, http.StatusOK, rr.Code)
+			b, err := ioutil.ReadFile(filepath.Join(m.Folder(img), "diskplayer.contents"))
+			assert.NoError(t, err)
+			ct, err := ParseContents(b)
+			assert.NoError(t, err)
+
+			es := s.catalogue.Search(CatalogueFilter{})
+			if !assert.Len(t, es, 1) {
+				return
+			}
+			assert.Equal(t, ct, es[0].Contents)
+
+			rr = httptest.NewRecorder()
+			http.HandlerFunc(s.catalogueHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/catalogue", nil))
+			assert.Contains(t, rr.Body.String(), `<input type="hidden" name="contents" value="{`)
+			assert.NotContains(t, rr.Body.String(), `<a href="">`)
+
+			assert.Equal(t, http.StatusFound, postRecord(s, recordTests[2].in, img, true).Code)
+			assert.Equal(t, http.StatusFound, postContents(s, es[0].EncodedContents(), img, true).Code)
+
+			b, err = ioutil.ReadFile(filepath.Join(m.Folder(img), "diskplayer.contents"))
+			assert.NoError(t, err)
+			c, err := ParseContents(b)
+			assert.NoError(t, err)
+			assert.Equal(t, ct, c)
+		})
+	}
+
+	s, _, img, cleanup := recordServer(t)
+	defer cleanup()
+	rr := postContents(s, `{"kind":"smart","source":"florble"}`, img, true)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "unknown smart contents source: florble")
+	rr = postContents(s, `{"kind":"florble"}`, img, true)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "unknown contents kind: florble")
+}
+
+func TestRecordHandlerInvalidURL(t *testing.T) {
+	s, m, img, cleanup := recordServer(t)
+	defer cleanup()
+
+	rr := postRecord(s, "florble", img, false)
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "URL represents neither album, playlist, artist, show nor track: florble")
+	mounts, _ := m.Counts()
+	assert.Equal(t, 0, mounts)
+}
+
+func TestRecordHandlerUnformatted(t *testing.T) {
+	s, m, img, cleanup := recordServer(t)
+	defer cleanup()
+	err := ioutil.WriteFile(img, make([]byte, 64*1024), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := postRecord(s, recordTests[0].in, img, false)
+	assert.Equal(t, http.StatusUnsupportedMediaType, rr.Code)
+	assert.Contains(t, rr.Body.String(), "no filesystem found on")
+	mounts, _ := m.Counts()
+	assert.Equal(t, 0, mounts)
+}
+
+func TestRecordHandlerMountError(t *testing.T) {
+	s, m, img, cleanup := recordServer(t)
+	defer cleanup()
+	m.MountErr = errors.New("device busy")
+
+	rr := postRecord(s, recordTests[0].in, img, false)
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Contains(t, rr.Body.String(), "unable to mount "+img+": device busy")
+	_, unmounts := m.Counts()
+	assert.Equal(t, 0, unmounts)
+}
+
+func TestRecordHandlerWriteError(t *testing.T) {
+	s, m, img, cleanup := recordServer(t)
+	defer cleanup()
+	err := os.MkdirAll(filepath.Join(m.Folder(img), "diskplayer.contents"), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := postRecord(s, recordTests[0].in, img, false)
+	assert.Equal(t, http.StatusInsufficientStorage, rr.Code)
+	assert.Contains(t, rr.Body.String(), "unable to write to "+img)
+	assert.False(t, m.Mounted(img))
+}
+
+func TestRecordHandlerVerifyError(t *testing.T) {
+	s, m, img, cleanup := recordServer(t)
+	defer cleanup()
+	err := os.MkdirAll(m.Folder(img), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A stale temporary file linked to the null device discards the contents written to it, and is then renamed over
+	// the contents file.
+	err = os.Symlink(os.DevNull, filepath.Join(m.Folder(img), "diskplayer.contents.tmp"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rr := postRecord(s, recordTests[0].in, img, false)
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Contains(t, rr.Body.String(), "verification of")
+	assert.False(t, m.Mounted(img))
+}
+
+func TestRecordHandlerUnmountError(t *testing.T) {
+	s, m, img, cleanup := recordServer(t)
+	defer cleanup()
+	m.UnmountErr = errors.New("target is busy")
+
+	rr := postRecord(s, recordTests[0].in, img, false)
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+	assert.Contains(t, rr.Body.String(), "unable to unmount "+img+": target is busy")
+}
+
+func TestRecordHandlerDirect(t *testing.T) {
+	s, m, img, cleanup := recordServer(t)
+	defer cleanup()
+	viper.Set("recorder.write_mode", "direct")
+	defer viper.Set("recorder.write_mode", "mount")
+
+	rr := postRecord(s, recordTests[1].in, img, false)
+	assert.Equal(t, http.StatusFound, rr.Code)
+
+	b, err := ReadDeviceFile(img, "diskplayer.contents")
+	assert.NoError(t, err)
+	assert.Equal(t, recordTests[1].out, string(b))
+	mounts, _ := m.Counts()
+	assert.Equal(t, 0, mounts)
+}
+
+func TestRecordHandlerDirectWriteError(t *testing.T) {
+	s, _, img, cleanup := recordServer(t)
+	defer cleanup()
+	viper.Set("recorder.write_mode", "direct")
+	defer viper.Set("recorder.write_mode", "mount")
+	viper.Set("recorder.filename", "a:b")
+
+	rr := postRecord(s, recordTests[0].in, img, false)
+	assert.Equal(t, http.StatusInsufficientStorage, rr.Code)
+	assert.Contains(t, rr.Body.String(), "invalid file name: a:b")
+}
+
+func postUndo(s *RealDiskplayerServer, devPath string) *httptest.ResponseRecorder {
+	v := url.Values{"device_path": {devPath}}
+	req := httptest.NewRequest("POST", "/undo", strings.NewReader(v.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(s.undoHandler).ServeHTTP(rr, req)
+	return rr
+}
+
+func TestUndoHandler(t *testing.T) {
+	s, m, img, cleanup := recordServer(t)
+	defer cleanup()
+
+	assert.Equal(t, http.StatusFound, postRecord(s, recordTests[0].in, img, false).Code)
+	assert.Equal(t, http.StatusFound, postRecord(s, recordTests[1].in, img, true).Code)
+
+	rr := postUndo(s, img)
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, "/success?undone=1&web_url="+url.QueryEscape(recordTests[0].in)+"&device_path="+url.QueryEscape(img),
+		rr.Header().Get("Location"))
+
+	b, err := ioutil.ReadFile(filepath.Join(m.Folder(img), "diskplayer.contents"))
+	assert.NoError(t, err)
+	assert.Equal(t, recordTests[0].out, string(b))
+	assert.False(t, m.Mounted(img))
+}
+
+func TestUndoHandlerNoBackup(t *testing.T) {
+	s, m, img, cleanup := recordServer(t)
+	defer cleanup()
+
+	assert.Equal(t, http.StatusFound, postRecord(s, recordTests[0].in, img, false).Code)
+
+	rr := postUndo(s, img)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Contains(t, rr.Body.String(), "no previous recording found on "+img)
+	assert.False(t, m.Mounted(img))
+}
+
+func TestUndoHandlerMethodNotAllowed(t *testing.T) {
+	s, _, img, cleanup := recordServer(t)
+	defer cleanup()
+
+	req := httptest.NewRequest("GET", "/undo?device_path="+url.QueryEscape(img), nil)
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(s.undoHandler).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+}
+
+func TestUndoHandlerDirect(t *testing.T) {
+	s, _, img, cleanup := recordServer(t)
+	defer cleanup()
+	viper.Set("recorder.write_mode", "direct")
+	defer viper.Set("recorder.write_mode", "mount")
+
+	assert.Equal(t, http.StatusNotFound, postUndo(s, img).Code)
+	assert.Equal(t, http.StatusFound, postRecord(s, recordTests[0].in, img, false).Code)
+	assert.Equal(t, http.StatusFound, postRecord(s, recordTests[1].in, img, true).Code)
+	assert.Equal(t, http.StatusFound, postUndo(s, img).Code)
+
+	b, err := ReadDeviceFile(img, "diskplayer.contents")
+	assert.NoError(t, err)
+	assert.Equal(t, recordTests[0].out, string(b))
+	b, err = ReadDeviceFile(img, "diskplayer.contents.bak")
+	assert.NoError(t, err)
+	assert.Equal(t, recordTests[1].out, string(b))
+}
+
+func TestRecordHandlerConfirmOverwrite(t *testing.T) {
+	s, m, img, cleanup := recordServer(t)
+	defer cleanup()
+	c := new(mocks.Client)
+	a := &spotify.FullAlbum{}
+	a.Name = "Test Album"
+	c.On("GetAlbum", spotify.ID("1S7mumn7D4riEX2gVWYgPO")).Return(a, nil)
+	p := &spotify.FullPlaylist{}
+	p.Name = "Test Playlist"
+	c.On("GetPlaylist", spotify.ID("5XsXwH5uWdhpAWsigjWMTA")).Return(p, nil)
+	s.client = c
+
+	assert.Equal(t, http.StatusFound, postRecord(s, recordTests[0].in, img, false).Code)
+	assert.Equal(t, http.StatusFound, postRecord(s, recordTests[0].in, img, false).Code)
+
+	rr := postRecord(s, recordTests[1].in, img, false)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Contains(t, rr.Body.String(), "<strong>Test Album</strong>")
+	assert.Contains(t, rr.Body.String(), "<input type=\"hidden\" name=\"confirm_overwrite\" value=\"1\">")
+	assert.False(t, m.Mounted(img))
+
+	b, err := ioutil.ReadFile(filepath.Join(m.Folder(img), "diskplayer.contents"))
+	assert.NoError(t, err)
+	assert.Equal(t, recordTests[0].out, string(b))
+
+	assert.Equal(t, http.StatusFound, postRecord(s, recordTests[1].in, img, true).Code)
+	b, err = ioutil.ReadFile(filepath.Join(m.Folder(img), "diskplayer.contents"))
+	assert.NoError(t, err)
+	assert.Equal(t, recordTests[1].out, string(b))
+
+	es := s.catalogue.Search(CatalogueFilter{})
+	assert.Len(t, es, 3)
+	assert.Equal(t, "Test Playlist", es[0].Title)
+	assert.Equal(t, "Test Album", es[1].Title)
+}
+
+func TestRecordHandlerConfirmOverwriteDirect(t *testing.T) {
+	s, _, img, cleanup := recordServer(t)
+	defer cleanup()
+	viper.Set("recorder.write_mode", "direct")
+	defer viper.Set("recorder.write_mode", "mount")
+
+	assert.Equal(t, http.StatusFound, postRecord(s, recordTests[0].in, img, false).Code)
+
+	rr := postRecord(s, recordTests[1].in, img, false)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Contains(t, rr.Body.String(), "<strong>"+recordTests[0].out+"</strong>")
+
+	b, err := ReadDeviceFile(img, "diskplayer.contents")
+	assert.NoError(t, err)
+	assert.Equal(t, recordTests[0].out, string(b))
+}
+
+// sharedMountServer returns a record server listing two FAT formatted images, using a mock mounter which mounts both
+// to the same folder in the same way as the syscall mounter.
+func sharedMountServer(t *testing.T) (*RealDiskplayerServer, *mocks.Mounter, string, string, string, func()) {
+	viper.Set("recorder.filename", "diskplayer.contents")
+	viper.Set("recorder.write_mode", "mount")
+	viper.Set("recorder.policy.deny", []string{})
+	viper.Set("recorder.catalogue", "")
+
+	img1, img2 := formattedImage(t), formattedImage(t)
+	folder, err := ioutil.TempDir("", "diskplayer_record")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := new(mocks.Mounter)
+	m.On("MountPoint", mock.Anything).Return(folder)
+	s := NewRecordServer(nil, m)
+	s.devices = func() ([]BlockDevice, error) {
+		return []BlockDevice{
+			{Path: img1, Size: 64 * 1024, Type: "disk", Removable: true},
+			{Path: img2, Size: 64 * 1024, Type: "disk", Removable: true},
+		}, nil
+	}
+	return s, m, img1, img2, folder, func() {
+		os.Remove(img1)
+		os.Remove(img2)
+		os.RemoveAll(folder)
+	}
+}
+
+func TestCatalogueHandler(t *testing.T) {
+	s, m, img, cleanup := recordServer(t)
+	defer cleanup()
+
+	assert.Equal(t, http.StatusFound, postRecord(s, recordTests[0].in, img, false).Code)
+	assert.Equal(t, http.StatusFound, postRecord(s, recordTests[1].in, img, true).Code)
+
+	rr := httptest.NewRecorder()
+	http.HandlerFunc(s.catalogueHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/catalogue?kind=playlist", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), recordTests[1].out)
+	assert.NotContains(t, rr.Body.String(), recordTests[0].out)
+	assert.Contains(t, rr.Body.String(), `<input type="hidden" name="web_url" value="`+recordTests[1].in+`">`)
+	assert.Contains(t, rr.Body.String(), `<option value="`+img+`">`)
+	assert.Contains(t, rr.Body.String(),
+		`<a href="/api/v1/history/export?device_path=&amp;disk=&amp;format=csv&amp;kind=playlist&amp;q=">CSV</a>`)
+
+	id, err := ReadDiskId(m.Folder(img))
+	assert.NoError(t, err)
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(s.catalogueHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/catalogue?disk="+id, nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Showing the history of disk "+id)
+	assert.Contains(t, rr.Body.String(), recordTests[0].out)
+
+	rr = httptest.NewRecorder()
+	http.HandlerFunc(s.catalogueHandler).ServeHTTP(rr, httptest.NewRequest("GET", "/catalogue?kind=florble", nil))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+	assert.Contains(t, rr.Body.String(), "invalid kind: florble")
+}
+
+func TestRecordHandlerDiskId(t *testing.T) {
+	s, m, img, cleanup := recordServer(t)
+	defer cleanup()
+
+	assert.Equal(t, http.StatusFound, postRecord(s, recordTests[0].in, img, false).Code)
+	id, err := ReadDiskId(m.Folder(img))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, id)
+
+	assert.Equal(t, http.StatusFound, postRecord(s, recordTests[1].in, img, true).Code)
+	s2, err := ReadDiskId(m.Folder(img))
+	assert.NoError(t, err)
+	assert.Equal(t, id, s2)
+
+	// A disk with another filesystem holding the same identity means the identity was copied with the contents.
+	assert.NoError(t, s.catalogue.Add(CatalogueEntry{URI: recordTests[0].out, DiskID: id, DiskUUID: "0000-0000"}))
+	assert.Equal(t, http.StatusFound, postRecord(s, recordTests[0].in, img, true).Code)
+	s2, err = ReadDiskId(m.Folder(img))
+	assert.NoError(t, err)
+	assert.NotEqual(t, id, s2)
+
+	es := s.catalogue.Search(CatalogueFilter{DiskID: s2})
+	assert.Len(t, es, 1)
+	assert.Equal(t, recordTests[0].out, es[0].URI)
+	assert.Contains(t, s.catalogue.Duplicates(), recordTests[0].out)
+}
+
+func TestRecordHandlerDiskIdDirect(t *testing.T) {
+	s, _, img, cleanup := recordServer(t)
+	defer cleanup()
+	viper.Set("recorder.write_mode", "direct")
+	defer viper.Set("recorder.write_mode", "mount")
+
+	assert.Equal(t, http.StatusFound, postRecord(s, recordTests[0].in, img, false).Code)
+	id, err := ReadDeviceDiskId(img)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, id)
+
+	rr := postUndo(s, img)
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+	assert.Equal(t, http.StatusFound, postRecord(s, recordTests[1].in, img, true).Code)
+	assert.Equal(t, http.StatusFound, postUndo(s, img).Code)
+	s2, err := ReadDeviceDiskId(img)
+	assert.NoError(t, err)
+	assert.Equal(t, id, s2)
+
+	es := s.catalogue.Search(CatalogueFilter{DiskID: id})
+	assert.Len(t, es, 3)
+	assert.Equal(t, "undo", es[0].Action)
+}
+
+func TestLoginHandler(t *testing.T) {
+	s, _, _, cleanup := recordServer(t)
+	defer cleanup()
+	s.access = testAccessControl(t)
+	h := s.access.Protect(http.HandlerFunc(s.loginHandler))
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest("GET", "/login?next=%2Fcatalogue", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `<input type="hidden" name="next" value="/catalogue">`)
+
+	login := func(password, next string) *httptest.ResponseRecorder {
+		v := url.Values{"password": {password}, "next": {next}}
+		req := httptest.NewRequest("POST", "/login", strings.NewReader(v.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr = login("hunter3", "/catalogue")
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Contains(t, rr.Body.String(), "Unable to log in: invalid password")
+	assert.Empty(t, rr.Result().Cookies())
+
+	rr = login("hunter2", "//example.com/")
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, "/", rr.Header().Get("Location"))
+	cs := rr.Result().Cookies()
+	assert.Len(t, cs, 1)
+	assert.Equal(t, sessionCookie, cs[0].Name)
+	assert.True(t, cs[0].HttpOnly)
+	assert.Equal(t, http.SameSiteStrictMode, cs[0].SameSite)
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.AddCookie(cs[0])
+	ss := s.access.Session(req)
+	assert.NotNil(t, ss)
+
+	rr = httptest.NewRecorder()
+	s.access.Protect(http.HandlerFunc(s.indexHandler)).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Contains(t, rr.Body.String(), `<input type="hidden" name="csrf_token" value="`+ss.CSRFToken+`">`)
+	assert.Contains(t, rr.Body.String(), `<form action="/logout" method="post">`)
+
+	v := url.Values{"csrf_token": {ss.CSRFToken}}
+	req = httptest.NewRequest("POST", "/logout", strings.NewReader(v.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.AddCookie(cs[0])
+	rr = httptest.NewRecorder()
+	s.access.Protect(http.HandlerFunc(s.logoutHandler)).ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, "/login", rr.Header().Get("Location"))
+	assert.Nil(t, s.access.Session(req))
+}
+
+func TestRecordHandlerCSRF(t *testing.T) {
+	s, _, img, cleanup := recordServer(t)
+	defer cleanup()
+	s.access = testAccessControl(t)
+	ss, err := s.access.Login("hunter2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h := s.access.Protect(http.HandlerFunc(s.recordHandler))
+
+	record := func(csrf string) *httptest.ResponseRecorder {
+		v := url.Values{"web_url": {recordTests[0].in}, "device_path": {img}, "csrf_token": {csrf}}
+		req := httptest.NewRequest("POST", "/record", strings.NewReader(v.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.AddCookie(&http.Cookie{Name: sessionCookie, Value: ss.ID})
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr
+	}
+
+	assert.Equal(t, http.StatusForbidden, record("").Code)
+	assert.Empty(t, s.catalogue.Search(CatalogueFilter{}))
+
+	// A GET request is not checked for a CSRF token, so must not record anything either.
+	v := url.Values{"web_url": {recordTests[0].in}, "device_path": {img}}
+	req := httptest.NewRequest("GET", "/record?"+v.Encode(), nil)
+	req.AddCookie(&http.Cookie{Name: sessionCookie, Value: ss.ID})
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusMethodNotAllowed, rr.Code)
+	assert.Equal(t, http.MethodPost, rr.Header().Get("Allow"))
+	assert.Empty(t, s.catalogue.Search(CatalogueFilter{}))
+	assert.Equal(t, http.StatusFound, record(ss.CSRFToken).Code)
+	assert.Len(t, s.catalogue.Search(CatalogueFilter{}), 1)
+}
+
+func TestRecordHandlerBusy(t *testing.T) {
+	s, m, img1, img2, folder, cleanup := sharedMountServer(t)
+	defer cleanup()
+	ch := make(chan time.Time)
+	m.On("Mount", img1, "vfat", "flush").WaitUntil(ch).Return(folder, nil)
+	m.On("Mount", img2, "vfat", "flush").Return(folder, nil)
+	m.On("Unmount", mock.Anything).Return(nil)
+
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- postRecord(s, recordTests[0].in, img1, false)
+	}()
+	for s.locks.Busy(img1) == nil {
+		time.Sleep(time.Millisecond)
+	}
+
+	rr := postRecord(s, recordTests[0].in, img1, false)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Contains(t, rr.Body.String(), img1+" is busy recording "+recordTests[0].out)
+
+	rr = postRecord(s, recordTests[1].in, img2, false)
+	assert.Equal(t, http.StatusConflict, rr.Code)
+	assert.Contains(t, rr.Body.String(), "mount point "+folder+" is busy recording "+recordTests[0].out)
+
+	assert.Equal(t, http.StatusConflict, apiGet(s.apiDiskHandler, "/api/v1/disk?device_path="+img2).Code)
+	assert.Equal(t, http.StatusConflict, postUndo(s, img1).Code)
+
+	rr = apiGet(s.apiDevicesHandler, "/api/v1/devices")
+	assert.Contains(t, rr.Body.String(), `"description":"recording `+recordTests[0].out+`"`)
+
+	close(ch)
+	assert.Equal(t, http.StatusFound, (<-done).Code)
+	assert.Nil(t, s.locks.Busy(img1))
+
+	assert.Equal(t, http.StatusFound, postRecord(s, recordTests[1].in, img2, true).Code)
+	m.AssertNumberOfCalls(t, "Mount", 2)
+	m.AssertNumberOfCalls(t, "Unmount", 2)
+}
+
+func TestRecordHandlerConcurrent(t *testing.T) {
+	s, m, img1, img2, folder, cleanup := sharedMountServer(t)
+	defer cleanup()
+
+	var active, overlaps int32
+	m.On("Mount", mock.Anything, "vfat", "flush").Run(func(mock.Arguments) {
+		if atomic.AddInt32(&active, 1) > 1 {
+			atomic.AddInt32(&overlaps, 1)
+		}
+	}).Return(folder, nil)
+	m.On("Unmount", mock.Anything).After(5 * time.Millisecond).Run(func(mock.Arguments) {
+		atomic.AddInt32(&active, -1)
+	}).Return(nil)
+
+	var start, wg sync.WaitGroup
+	start.Add(1)
+	codes := make([]int, 20)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			img := img1
+			if i%2 == 1 {
+				img = img2
+			}
+			start.Wait()
+			codes[i] = postRecord(s, recordTests[0].in, img, true).Code
+		}(i)
+	}
+	start.Done()
+	wg.Wait()
+
+	var ok int
+	for _, c := range codes {
+		assert.Contains(t, []int{http.StatusFound, http.StatusConflict}, c)
+		if c == http.StatusFound {
+			ok++
+		}
+	}
+	assert.NotZero(t, ok)
+	assert.Zero(t, atomic.LoadInt32(&overlaps))
+	m.AssertNumberOfCalls(t, "Mount", ok)
+}
@@ -0,0 +1,181 @@
+package diskplayer
+
+import (
+	"fmt"
+	"github.com/zmb3/spotify"
+	"strings"
+)
+
+const (
+	// SMART_LIKED_SONGS is the source of smart contents which play the tracks most recently saved to the Liked Songs
+	// of the user.
+	SMART_LIKED_SONGS = "liked_songs"
+	// SMART_TOP_TRACKS is the source of smart contents which play the tracks the user has played most over the last
+	// month or so.
+	SMART_TOP_TRACKS = "top_tracks"
+	// SMART_RECENTLY_PLAYED is the source of smart contents which play the tracks the user has played most recently.
+	SMART_RECENTLY_PLAYED = "recently_played"
+	// SMART_LATEST_EPISODE is the source of smart contents which play the newest episode of a show.
+	SMART_LATEST_EPISODE = "latest_episode"
+)
+
+// smartPageLimit is the number of tracks requested from Spotify at a time when resolving smart contents, which is the
+// most Spotify allows.
+const smartPageLimit = 50
+
+// smartTrackMax is the greatest number of Liked Songs played by a disk.
+const smartTrackMax = 200
+
+// smartUriPrefix begins the URI identifying smart contents which are not drawn from a Spotify item.
+const smartUriPrefix = "diskplayer:"
+
+// smartTitles holds the title of the smart contents of each source which is not drawn from a Spotify item.
+var smartTitles = map[string]string{
+	SMART_LIKED_SONGS:     "Liked Songs",
+	SMART_TOP_TRACKS:      "Top tracks this month",
+	SMART_RECENTLY_PLAYED: "Recently played",
+}
+
+// SmartContents returns smart contents of the source, which are resolved into the tracks to play each time the disk is
+// inserted. Contents of the SMART_LATEST_EPISODE source play the newest episode of the show identified by the Spotify
+// URI. The URI is ignored for any other source, and the contents are identified by a URI such as
+// diskplayer:liked_songs instead, so that the disk can be described and catalogued like any other.
+// An error is returned if the source is unknown, or the latest episode is requested of anything but a show.
+func SmartContents(source, uri string) (*Contents, error) {
+	if source == SMART_LATEST_EPISODE {
+		if k, _, _ := splitSpotifyUri(uri); k != "show" {
+			return nil, fmt.Errorf("the latest episode can only be recorded for a show: %s", uri)
+		}
+		return &Contents{Kind: CONTENTS_SMART, Source: source, URI: uri}, nil
+	}
+
+	t, ok := smartTitles[source]
+	if !ok {
+		return nil, fmt.Errorf("unknown smart contents source: %s", source)
+	}
+	return &Contents{Kind: CONTENTS_SMART, Source: source, URI: smartUriPrefix + source, Title: t}, nil
+}
+
+// ResolveSmart will look up the Spotify URIs of the tracks, or the episode, which the smart contents currently refer
+// to.
+// An error is returned if the source is unknown, there is nothing to play, or one is encountered.
+func ResolveSmart(c Client, ct *Contents) ([]string, error) {
+	var ts []string
+	var err error
+	switch ct.Source {
+	case SMART_LIKED_SONGS:
+		ts, err = likedSongs(c)
+	case SMART_TOP_TRACKS:
+		ts, err = topTracks(c)
+	case SMART_RECENTLY_PLAYED:
+		ts, err = recentlyPlayed(c)
+	case SMART_LATEST_EPISODE:
+		ts, err = latestEpisode(c, ct.URI)
+	default:
+		return nil, fmt.Errorf("unknown smart contents source: %s", ct.Source)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if len(ts) == 0 {
+		return nil, fmt.Errorf("nothing found to play for %s", ct.Source)
+	}
+	return ts, nil
+}
+
+// smartTitle returns the title of the smart, radio or surprise contents identified by the URI, or an empty string if
+// the URI does not identify contents which are not drawn from a Spotify item.
+func smartTitle(uri string) string {
+	if uri == radioUri {
+		return "Radio"
+	}
+	if strings.HasPrefix(uri, surpriseUriPrefix) {
+		return "Surprise"
+	}
+	if !strings.HasPrefix(uri, smartUriPrefix) {
+		return ""
+	}
+	return smartTitles[strings.TrimPrefix(uri, smartUriPrefix)]
+}
+
+// likedSongs returns the Spotify URIs of the tracks most recently saved to the Liked Songs of the user, up to
+// smartTrackMax of them.
+// An error is returned if one is encountered.
+func likedSongs(c Client) ([]string, error) {
+	var ts []string
+	for o := 0; o < smartTrackMax; o += smartPageLimit {
+		l := smartPageLimit
+		p, err := c.CurrentUsersTracksOpt(&spotify.Options{Limit: &l, Offset: &o})
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range p.Tracks {
+			ts = append(ts, string(t.URI))
+		}
+		if len(p.Tracks) == 0 || o+len(p.Tracks) >= p.Total {
+			break
+		}
+	}
+	return ts, nil
+}
+
+// topTracks returns the Spotify URIs of the tracks the user has played most over the short term time range, which
+// Spotify describes as approximately the last four weeks.
+// An error is returned if one is encountered.
+func topTracks(c Client) ([]string, error) {
+	l := smartPageLimit
+	r := "short"
+	p, err := c.CurrentUsersTopTracksOpt(&spotify.Options{Limit: &l, Timerange: &r})
+	if err != nil {
+		return nil, err
+	}
+
+	var ts []string
+	for _, t := range p.Tracks {
+		ts = append(ts, string(t.URI))
+	}
+	return ts, nil
+}
+
+// recentlyPlayed returns the Spotify URIs of the tracks the user has played most recently, most recent first. Tracks
+// played more than once are only included once.
+// An error is returned if one is encountered.
+func recentlyPlayed(c Client) ([]string, error) {
+	is, err := c.PlayerRecentlyPlayedOpt(&spotify.RecentlyPlayedOptions{Limit: smartPageLimit})
+	if err != nil {
+		return nil, err
+	}
+
+	var ts []string
+	seen := map[string]bool{}
+	for _, i := range is {
+		u := string(i.Track.URI)
+		if !seen[u] {
+			seen[u] = true
+			ts = append(ts, u)
+		}
+	}
+	return ts, nil
+}
+
+// latestEpisode returns the Spotify URI of the newest episode of the show identified by the Spotify URI.
+// An error is returned if one is encountered.
+func latestEpisode(c Client, uri string) ([]string, error) {
+	_, id, err := splitSpotifyUri(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	l := 1
+	p, err := c.GetShowEpisodesOpt(&spotify.Options{Limit: &l}, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var ts []string
+	for _, e := range p.Episodes {
+		ts = append(ts, string(e.URI))
+	}
+	return ts, nil
+}
@@ -0,0 +1,145 @@
+package diskplayer
+
+import (
+	"errors"
+	"github.com/dinofizz/diskplayer/mocks"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/zmb3/spotify"
+	"strconv"
+	"testing"
+)
+
+var smartContentsTests = []struct {
+	source string
+	uri    string
+	out    *Contents
+	e      string
+}{
+	{SMART_LIKED_SONGS, "", &Contents{Kind: CONTENTS_SMART, Source: SMART_LIKED_SONGS, URI: "diskplayer:liked_songs",
+		Title: "Liked Songs"}, ""},
+	{SMART_TOP_TRACKS, "spotify:album:1S7mumn7D4riEX2gVWYgPO", &Contents{Kind: CONTENTS_SMART,
+		Source: SMART_TOP_TRACKS, URI: "diskplayer:top_tracks", Title: "Top tracks this month"}, ""},
+	{SMART_RECENTLY_PLAYED, "", &Contents{Kind: CONTENTS_SMART, Source: SMART_RECENTLY_PLAYED,
+		URI: "diskplayer:recently_played", Title: "Recently played"}, ""},
+	{SMART_LATEST_EPISODE, "spotify:show:4rOoJ6Egrf8K2IrywzwOMk", &Contents{Kind: CONTENTS_SMART,
+		Source: SMART_LATEST_EPISODE, URI: "spotify:show:4rOoJ6Egrf8K2IrywzwOMk"}, ""},
+	{SMART_LATEST_EPISODE, "spotify:album:1S7mumn7D4riEX2gVWYgPO", nil,
+		"the latest episode can only be recorded for a show: spotify:album:1S7mumn7D4riEX2gVWYgPO"},
+	{"florble", "", nil, "unknown smart contents source: florble"},
+}
+
+func TestSmartContents(t *testing.T) {
+	for _, tt := range smartContentsTests {
+		t.Run(tt.source, func(t *testing.T) {
+			c, err := SmartContents(tt.source, tt.uri)
+			if tt.e != "" {
+				assert.EqualError(t, err, tt.e)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.out, c)
+		})
+	}
+}
+
+// likedClient returns a mock client holding n Liked Songs, served in pages as the options request them.
+func likedClient(n int) *mocks.Client {
+	m := new(mocks.Client)
+	m.On("CurrentUsersTracksOpt", mock.Anything).Return(func(o *spotify.Options) *spotify.SavedTrackPage {
+		p := &spotify.SavedTrackPage{}
+		p.Total = n
+		for i := *o.Offset; i < *o.Offset+*o.Limit && i < n; i++ {
+			t := spotify.SavedTrack{}
+			t.URI = spotify.URI("spotify:track:" + strconv.Itoa(i))
+			p.Tracks = append(p.Tracks, t)
+		}
+		return p
+	}, nil)
+	return m
+}
+
+func TestResolveSmartLikedSongs(t *testing.T) {
+	m := likedClient(75)
+	ts, err := ResolveSmart(m, &Contents{Kind: CONTENTS_SMART, Source: SMART_LIKED_SONGS})
+	assert.NoError(t, err)
+	assert.Len(t, ts, 75)
+	assert.Equal(t, "spotify:track:74", ts[74])
+	m.AssertNumberOfCalls(t, "CurrentUsersTracksOpt", 2)
+
+	m = likedClient(1000)
+	ts, err = ResolveSmart(m, &Contents{Kind: CONTENTS_SMART, Source: SMART_LIKED_SONGS})
+	assert.NoError(t, err)
+	assert.Len(t, ts, smartTrackMax)
+	m.AssertNumberOfCalls(t, "CurrentUsersTracksOpt", 4)
+
+	_, err = ResolveSmart(likedClient(0), &Contents{Kind: CONTENTS_SMART, Source: SMART_LIKED_SONGS})
+	assert.EqualError(t, err, "nothing found to play for liked_songs")
+}
+
+func TestResolveSmartTopTracks(t *testing.T) {
+	p := &spotify.FullTrackPage{Tracks: make([]spotify.FullTrack, 2)}
+	p.Tracks[0].URI = "spotify:track:a"
+	p.Tracks[1].URI = "spotify:track:b"
+
+	m := new(mocks.Client)
+	m.On("CurrentUsersTopTracksOpt", mock.MatchedBy(func(o *spotify.Options) bool {
+		return *o.Timerange == "short" && *o.Limit == smartPageLimit
+	})).Return(p, nil)
+
+	ts, err := ResolveSmart(m, &Contents{Kind: CONTENTS_SMART, Source: SMART_TOP_TRACKS})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"spotify:track:a", "spotify:track:b"}, ts)
+}
+
+func TestResolveSmartRecentlyPlayed(t *testing.T) {
+	is := make([]spotify.RecentlyPlayedItem, 3)
+	is[0].Track.URI = "spotify:track:a"
+	is[1].Track.URI = "spotify:track:b"
+	is[2].Track.URI = "spotify:track:a"
+
+	m := new(mocks.Client)
+	m.On("PlayerRecentlyPlayedOpt", &spotify.RecentlyPlayedOptions{Limit: smartPageLimit}).Return(is, nil)
+
+	ts, err := ResolveSmart(m, &Contents{Kind: CONTENTS_SMART, Source: SMART_RECENTLY_PLAYED})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"spotify:track:a", "spotify:track:b"}, ts)
+}
+
+func TestResolveSmartLatestEpisode(t *testing.T) {
+	p := &spotify.SimpleEpisodePage{Episodes: make([]spotify.EpisodePage, 1)}
+	p.Episodes[0].URI = "spotify:episode:512ojhOuo1ktJprKbVcKyQ"
+
+	m := new(mocks.Client)
+	m.On("GetShowEpisodesOpt", mock.Anything, "4rOoJ6Egrf8K2IrywzwOMk").Return(p, nil)
+
+	ts, err := ResolveSmart(m, &Contents{Kind: CONTENTS_SMART, Source: SMART_LATEST_EPISODE,
+		URI: "spotify:show:4rOoJ6Egrf8K2IrywzwOMk"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"spotify:episode:512ojhOuo1ktJprKbVcKyQ"}, ts)
+}
+
+func TestResolveSmartErrors(t *testing.T) {
+	_, err := ResolveSmart(new(mocks.Client), &Contents{Kind: CONTENTS_SMART, Source: "florble"})
+	assert.EqualError(t, err, "unknown smart contents source: florble")
+
+	m := new(mocks.Client)
+	m.On("CurrentUsersTopTracksOpt", mock.Anything).Return(nil, errors.New("CurrentUsersTopTracksOpt error"))
+	_, err = ResolveSmart(m, &Contents{Kind: CONTENTS_SMART, Source: SMART_TOP_TRACKS})
+	assert.EqualError(t, err, "CurrentUsersTopTracksOpt error")
+}
+
+func TestPlayContentsSmart(t *testing.T) {
+	viper.Set("spotify.device_name", "test_device_name")
+
+	m := likedClient(3)
+	m.On("PlayerDevices").Return([]spotify.PlayerDevice{{ID: "TEST_ID", Name: "test_device_name"}}, nil)
+	m.On("PlayOpt", mock.MatchedBy(func(o *spotify.PlayOptions) bool {
+		return o.PlaybackContext == nil && len(o.URIs) == 3 && o.URIs[2] == "spotify:track:2"
+	})).Return(nil)
+
+	err := PlayPath(m, "./test-fixtures/smart.contents")
+	assert.NoError(t, err)
+	m.AssertExpectations(t)
+}
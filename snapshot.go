@@ -0,0 +1,65 @@
+package diskplayer
+
+import (
+	"fmt"
+	"github.com/zmb3/spotify"
+	"strings"
+)
+
+// snapshotPageLimit is the number of playlist tracks requested from Spotify at a time when taking a snapshot.
+const snapshotPageLimit = 100
+
+// Snapshot will look up the tracks currently in the playlist identified by the Spotify URI and return contents which
+// play that fixed list of tracks, however the playlist changes afterwards. Local files, which cannot be played through
+// the Spotify Web API, are left out.
+// An error is returned if the URI does not identify a playlist, it holds no playable tracks, or one is encountered.
+func Snapshot(c Client, uri string) (*Contents, error) {
+	k, id, err := splitSpotifyUri(uri)
+	if err != nil {
+		return nil, err
+	}
+	if k != "playlist" {
+		return nil, fmt.Errorf("only playlists can be frozen: %s", uri)
+	}
+
+	p, err := c.GetPlaylist(spotify.ID(id))
+	if err != nil {
+		return nil, err
+	}
+
+	ts, err := playlistTracks(c, id)
+	if err != nil {
+		return nil, err
+	}
+
+	ct := &Contents{Kind: CONTENTS_SNAPSHOT, URI: uri, Title: p.Name}
+	for _, t := range ts {
+		u := string(t.Track.URI)
+		if u != "" && !strings.HasPrefix(u, "spotify:local:") {
+			ct.Tracks = append(ct.Tracks, u)
+		}
+	}
+
+	if len(ct.Tracks) == 0 {
+		return nil, fmt.Errorf("playlist %s holds no tracks which can be played", uri)
+	}
+	return ct, nil
+}
+
+// playlistTracks returns all of the tracks in the playlist with the given ID, requesting snapshotPageLimit of them at
+// a time.
+// An error is returned if one is encountered.
+func playlistTracks(c Client, id string) ([]spotify.PlaylistTrack, error) {
+	var ts []spotify.PlaylistTrack
+	for o := 0; ; o += snapshotPageLimit {
+		l := snapshotPageLimit
+		tp, err := c.GetPlaylistTracksOpt(spotify.ID(id), &spotify.Options{Limit: &l, Offset: &o}, "")
+		if err != nil {
+			return nil, err
+		}
+		ts = append(ts, tp.Tracks...)
+		if len(tp.Tracks) == 0 || o+len(tp.Tracks) >= tp.Total {
+			return ts, nil
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package diskplayer
+
+import (
+	"errors"
+	"github.com/dinofizz/diskplayer/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/zmb3/spotify"
+	"strconv"
+	"testing"
+)
+
+// playlistClient returns a mock client holding the playlist 5XsXwH5uWdhpAWsigjWMTA with n tracks, served in pages
+// as the options request them. Every tenth track is a local file.
+func playlistClient(n int) *mocks.Client {
+	p := &spotify.FullPlaylist{}
+	p.Name = "Test Playlist"
+
+	m := new(mocks.Client)
+	m.On("GetPlaylist", spotify.ID("5XsXwH5uWdhpAWsigjWMTA")).Return(p, nil)
+	m.On("GetPlaylistTracksOpt", spotify.ID("5XsXwH5uWdhpAWsigjWMTA"), mock.Anything, "").Return(
+		func(id spotify.ID, o *spotify.Options, f string) *spotify.PlaylistTrackPage {
+			tp := &spotify.PlaylistTrackPage{}
+			tp.Total = n
+			for i := *o.Offset; i < *o.Offset+*o.Limit && i < n; i++ {
+				t := spotify.PlaylistTrack{}
+				t.Track.URI = spotify.URI("spotify:track:" + strconv.Itoa(i))
+				if i%10 == 9 {
+					t.Track.URI = spotify.URI("spotify:local:::Local+Track:" + strconv.Itoa(i))
+				}
+				tp.Tracks = append(tp.Tracks, t)
+			}
+			return tp
+		}, nil)
+	return m
+}
+
+func TestSnapshot(t *testing.T) {
+	m := playlistClient(250)
+	c, err := Snapshot(m, "spotify:playlist:5XsXwH5uWdhpAWsigjWMTA")
+	assert.NoError(t, err)
+	assert.Equal(t, CONTENTS_SNAPSHOT, c.Kind)
+	assert.Equal(t, "spotify:playlist:5XsXwH5uWdhpAWsigjWMTA", c.URI)
+	assert.Equal(t, "Test Playlist", c.Title)
+	assert.Len(t, c.Tracks, 225)
+	assert.Equal(t, "spotify:track:0", c.Tracks[0])
+	assert.Equal(t, "spotify:track:248", c.Tracks[224])
+	m.AssertNumberOfCalls(t, "GetPlaylistTracksOpt", 3)
+}
+
+func TestSnapshotErrors(t *testing.T) {
+	_, err := Snapshot(new(mocks.Client), "spotify:album:1S7mumn7D4riEX2gVWYgPO")
+	assert.EqualError(t, err, "only playlists can be frozen: spotify:album:1S7mumn7D4riEX2gVWYgPO")
+
+	_, err = Snapshot(playlistClient(0), "spotify:playlist:5XsXwH5uWdhpAWsigjWMTA")
+	assert.EqualError(t, err, "playlist spotify:playlist:5XsXwH5uWdhpAWsigjWMTA holds no tracks which can be played")
+
+	m := new(mocks.Client)
+	m.On("GetPlaylist", spotify.ID("5XsXwH5uWdhpAWsigjWMTA")).Return(nil, errors.New("GetPlaylist error"))
+	_, err = Snapshot(m, "spotify:playlist:5XsXwH5uWdhpAWsigjWMTA")
+	assert.EqualError(t, err, "GetPlaylist error")
+}
@@ -0,0 +1,49 @@
+package diskplayer
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+)
+
+// PlayerState holds what the player remembers from one disk being inserted to the next. The surprise history holds
+// the Spotify URIs played so far by each surprise disk, keyed by the URI identifying its contents.
+type PlayerState struct {
+	Surprise map[string][]string `json:"surprise,omitempty"`
+}
+
+// ReadPlayerState will read the player state from the file whose path is defined in the diskplayer.yaml configuration
+// file under the player.state_path field, resolved as described for ConfigPath. An empty state is returned if the file
+// does not exist yet.
+// An error is returned if one is encountered, along with an empty state.
+func ReadPlayerState() (*PlayerState, error) {
+	s := &PlayerState{Surprise: map[string][]string{}}
+
+	b, err := ioutil.ReadFile(ConfigPath(PLAYER_STATE_PATH))
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return s, err
+	}
+
+	err = json.Unmarshal(b, s)
+	if err != nil {
+		return &PlayerState{Surprise: map[string][]string{}}, err
+	}
+	if s.Surprise == nil {
+		s.Surprise = map[string][]string{}
+	}
+	return s, nil
+}
+
+// SavePlayerState will save the player state to the file whose path is defined in the diskplayer.yaml configuration
+// file under the player.state_path field, resolved as described for ConfigPath, replacing the file atomically.
+// Returns an error if one is encountered.
+func SavePlayerState(s *PlayerState) error {
+	b, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return replaceFile(ConfigPath(PLAYER_STATE_PATH), append(b, '\n'))
+}
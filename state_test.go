@@ -0,0 +1,46 @@
+package diskplayer
+
+import (
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// stateFile sets the player state path to a file in a new temporary directory, returning a function which removes
+// the directory again.
+func stateFile(t *testing.T) (string, func()) {
+	d, err := ioutil.TempDir("", "diskplayer_state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := filepath.Join(d, "player_state.json")
+	viper.Set("player.state_path", p)
+	return p, func() { os.RemoveAll(d) }
+}
+
+func TestPlayerState(t *testing.T) {
+	p, cleanup := stateFile(t)
+	defer cleanup()
+
+	s, err := ReadPlayerState()
+	assert.NoError(t, err)
+	assert.Empty(t, s.Surprise)
+
+	s.Surprise["spotify:playlist:5XsXwH5uWdhpAWsigjWMTA"] = []string{"spotify:album:1S7mumn7D4riEX2gVWYgPO"}
+	assert.NoError(t, SavePlayerState(s))
+
+	r, err := ReadPlayerState()
+	assert.NoError(t, err)
+	assert.Equal(t, s, r)
+
+	err = ioutil.WriteFile(p, []byte("{florble"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err = ReadPlayerState()
+	assert.EqualError(t, err, "invalid character 'f' looking for beginning of object key string")
+	assert.Empty(t, r.Surprise)
+}
@@ -0,0 +1,51 @@
+// Refreshes the device picker on the recorder page as removable drives are plugged in and removed, and shows which
+// devices are busy with another recording.
+(function () {
+    var select = document.getElementById("device_path");
+    if (!select) {
+        return;
+    }
+
+    function update(devices) {
+        var selected = select.value;
+        while (select.firstChild) {
+            select.removeChild(select.firstChild);
+        }
+
+        if (devices.length === 0) {
+            var none = document.createElement("option");
+            none.value = "";
+            none.disabled = true;
+            none.selected = true;
+            none.textContent = "No removable devices found, insert a disk";
+            select.appendChild(none);
+            return;
+        }
+
+        devices.forEach(function (d) {
+            var o = document.createElement("option");
+            o.value = d.path;
+            o.textContent = d.description;
+            if (d.busy) {
+                o.textContent += " - busy " + d.busy.description;
+            }
+            o.selected = d.path === selected;
+            select.appendChild(o);
+        });
+    }
+
+    function refresh() {
+        fetch("/api/v1/devices")
+            .then(function (r) {
+                return r.ok ? r.json() : Promise.reject(r.statusText);
+            })
+            .then(function (o) {
+                update(o.devices);
+            })
+            .catch(function (e) {
+                console.log("Unable to refresh devices:", e);
+            });
+    }
+
+    setInterval(refresh, 3000);
+})();
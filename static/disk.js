@@ -0,0 +1,81 @@
+// Shows the contents of the disk in the chosen device on the recorder page, so that it can be checked before it is
+// recorded over.
+(function () {
+    var select = document.getElementById("device_path");
+    var button = document.getElementById("show_disk");
+    var panel = document.getElementById("disk");
+    if (!select || !button || !panel) {
+        return;
+    }
+
+    function clear() {
+        while (panel.firstChild) {
+            panel.removeChild(panel.firstChild);
+        }
+    }
+
+    function message(text) {
+        clear();
+        var p = document.createElement("p");
+        p.textContent = text;
+        panel.appendChild(p);
+    }
+
+    function show(c) {
+        if (!c.uri) {
+            message("The disk is empty.");
+            return;
+        }
+
+        clear();
+        var m = c.metadata;
+        if (m && m.image_url) {
+            var img = document.createElement("img");
+            img.src = m.image_url;
+            img.alt = "Cover art";
+            img.width = 160;
+            img.height = 160;
+            panel.appendChild(img);
+        }
+
+        var p = document.createElement("p");
+        var s = document.createElement("strong");
+        if (m && m.title) {
+            s.textContent = m.title;
+            p.appendChild(document.createTextNode("The disk holds "));
+            p.appendChild(s);
+            if (m.artist) {
+                p.appendChild(document.createTextNode(" by " + m.artist));
+            }
+        } else {
+            s.textContent = c.uri;
+            p.appendChild(document.createTextNode("The disk holds "));
+            p.appendChild(s);
+        }
+        if (c.kind) {
+            p.appendChild(document.createTextNode(" (" + c.kind + ")"));
+        }
+        panel.appendChild(p);
+    }
+
+    button.addEventListener("click", function () {
+        if (!select.value) {
+            message("No device chosen.");
+            return;
+        }
+
+        message("Reading disk…");
+        fetch("/api/v1/disk?device_path=" + encodeURIComponent(select.value))
+            .then(function (r) {
+                return r.json().then(function (o) {
+                    return r.ok ? o : Promise.reject(o.error.message);
+                });
+            })
+            .then(show)
+            .catch(function (e) {
+                message("Unable to read disk: " + e);
+            });
+    });
+
+    select.addEventListener("change", clear);
+})();
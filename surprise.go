@@ -0,0 +1,153 @@
+package diskplayer
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math/rand"
+	"sort"
+	"strings"
+	"time"
+)
+
+// surpriseUriPrefix begins the URI identifying surprise contents which hold a pool of Spotify URIs.
+const surpriseUriPrefix = smartUriPrefix + "surprise:"
+
+// surpriseIntn returns a random number from zero up to, but not including, n, and is used to choose what a surprise
+// disk plays.
+var surpriseIntn = rand.New(rand.NewSource(time.Now().UnixNano())).Intn
+
+// SurpriseContents returns contents which play one album, playlist, artist, show or track chosen at random each time
+// the disk is inserted. The choice is made either from the pool of Spotify URIs, or from the albums of the tracks in
+// the playlist identified by the Spotify URI, which are looked up each time. Contents holding a pool are identified by
+// a URI such as diskplayer:surprise:1a2b3c4d5e6f, derived from the URIs in the pool. URIs given more than once are
+// only held in the pool once.
+// An error is returned unless exactly one of a playlist or a pool is given, or if the pool holds an invalid URI.
+func SurpriseContents(uri string, pool []string) (*Contents, error) {
+	if (uri == "") == (len(pool) == 0) {
+		return nil, fmt.Errorf("a surprise needs either a playlist or a pool to choose from")
+	}
+
+	if uri != "" {
+		if k, _, _ := splitSpotifyUri(uri); k != "playlist" {
+			return nil, fmt.Errorf("a surprise can only choose from the albums of a playlist: %s", uri)
+		}
+		return &Contents{Kind: CONTENTS_SURPRISE, URI: uri, Title: "Surprise"}, nil
+	}
+
+	var us []string
+	for _, p := range pool {
+		_, err := createSpotifyUri(webUrl(p))
+		if err != nil {
+			return nil, fmt.Errorf("invalid Spotify URI in surprise pool: %s", p)
+		}
+		if indexOf(us, p) < 0 {
+			us = append(us, p)
+		}
+	}
+
+	ps := append([]string{}, us...)
+	sort.Strings(ps)
+	h := sha256.Sum256([]byte(strings.Join(ps, "\n")))
+	return &Contents{Kind: CONTENTS_SURPRISE, URI: surpriseUriPrefix + hex.EncodeToString(h[:6]), Title: "Surprise",
+		Pool: us}, nil
+}
+
+// PlaySurprise will play one of the Spotify URIs the surprise contents choose from, picked at random from those which
+// have not been played by the disk yet. Once every one of them has been played the choice starts afresh, though the
+// URI played last is not played again straight away. The URIs played so far are kept in the player state, as
+// described for PlayerState.
+// An error is returned if there is nothing to choose from, or one is encountered.
+func PlaySurprise(c Client, ct *Contents) error {
+	pool := ct.Pool
+	if len(pool) == 0 {
+		var err error
+		pool, err = playlistAlbums(c, ct.URI)
+		if err != nil {
+			return err
+		}
+	}
+
+	s, err := ReadPlayerState()
+	if err != nil {
+		log.Printf("Unable to read player state, starting afresh: %s", err)
+	}
+
+	u, h := surprisePick(pool, s.Surprise[ct.URI])
+	log.Printf("Surprise! Playing %s", u)
+	err = PlayUri(c, u)
+	if err != nil {
+		return err
+	}
+
+	s.Surprise[ct.URI] = h
+	err = SavePlayerState(s)
+	if err != nil {
+		return fmt.Errorf("unable to save player state: %s", err)
+	}
+	return nil
+}
+
+// surprisePick picks a Spotify URI at random from those in the pool which have not been played according to the
+// history, starting afresh if every one of them has been played. The URI picked is returned along with the new
+// history, which only holds URIs from the pool. The pool must not be empty.
+func surprisePick(pool, history []string) (string, []string) {
+	played := map[string]bool{}
+	var h []string
+	for _, u := range history {
+		if indexOf(pool, u) >= 0 && !played[u] {
+			played[u] = true
+			h = append(h, u)
+		}
+	}
+
+	var cs []string
+	for _, u := range pool {
+		if !played[u] {
+			cs = append(cs, u)
+		}
+	}
+	if len(cs) == 0 {
+		for _, u := range pool {
+			if len(pool) == 1 || u != h[len(h)-1] {
+				cs = append(cs, u)
+			}
+		}
+		h = nil
+	}
+	if len(cs) == 0 {
+		cs = pool
+	}
+
+	u := cs[surpriseIntn(len(cs))]
+	return u, append(h, u)
+}
+
+// playlistAlbums returns the Spotify URIs of the albums of the tracks in the playlist identified by the Spotify URI,
+// in the order in which they first appear.
+// An error is returned if the playlist holds no albums, or one is encountered.
+func playlistAlbums(c Client, uri string) ([]string, error) {
+	_, id, err := splitSpotifyUri(uri)
+	if err != nil {
+		return nil, err
+	}
+
+	ts, err := playlistTracks(c, id)
+	if err != nil {
+		return nil, err
+	}
+
+	var as []string
+	for _, t := range ts {
+		u := string(t.Track.Album.URI)
+		if u != "" && indexOf(as, u) < 0 {
+			as = append(as, u)
+		}
+	}
+
+	if len(as) == 0 {
+		return nil, fmt.Errorf("playlist %s holds no albums to choose from", uri)
+	}
+	return as, nil
+}
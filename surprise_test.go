@@ -0,0 +1,177 @@
+package diskplayer
+
+import (
+	"errors"
+	"github.com/dinofizz/diskplayer/mocks"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/zmb3/spotify"
+	"io/ioutil"
+	"testing"
+)
+
+var surprisePool = []string{"spotify:album:a", "spotify:playlist:b", "spotify:show:c"}
+
+var surpriseContentsTests = []struct {
+	name string
+	uri  string
+	pool []string
+	out  *Contents
+	e    string
+}{
+	{"playlist", "spotify:playlist:5XsXwH5uWdhpAWsigjWMTA", nil, &Contents{Kind: CONTENTS_SURPRISE,
+		URI: "spotify:playlist:5XsXwH5uWdhpAWsigjWMTA", Title: "Surprise"}, ""},
+	{"pool", "", surprisePool, &Contents{Kind: CONTENTS_SURPRISE, URI: "diskplayer:surprise:e89fd9970447",
+		Title: "Surprise", Pool: surprisePool}, ""},
+	{"neither", "", nil, nil, "a surprise needs either a playlist or a pool to choose from"},
+	{"both", "spotify:playlist:5XsXwH5uWdhpAWsigjWMTA", surprisePool, nil,
+		"a surprise needs either a playlist or a pool to choose from"},
+	{"album", "spotify:album:1S7mumn7D4riEX2gVWYgPO", nil, nil,
+		"a surprise can only choose from the albums of a playlist: spotify:album:1S7mumn7D4riEX2gVWYgPO"},
+	{"invalid", "", []string{"spotify:album:a", "florble"}, nil, "invalid Spotify URI in surprise pool: florble"},
+	{"duplicates", "", append(surprisePool, "spotify:album:a"), &Contents{Kind: CONTENTS_SURPRISE,
+		URI: "diskplayer:surprise:e89fd9970447", Title: "Surprise", Pool: surprisePool}, ""},
+}
+
+func TestSurpriseContents(t *testing.T) {
+	for _, tt := range surpriseContentsTests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := SurpriseContents(tt.uri, tt.pool)
+			if tt.e != "" {
+				assert.EqualError(t, err, tt.e)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.out, c)
+		})
+	}
+
+	// The URI identifying a pool does not depend on the order of the pool.
+	c, err := SurpriseContents("", []string{"spotify:show:c", "spotify:album:a", "spotify:playlist:b"})
+	assert.NoError(t, err)
+	assert.Equal(t, "diskplayer:surprise:e89fd9970447", c.URI)
+}
+
+var surprisePickTests = []struct {
+	name    string
+	history []string
+	pick    string
+	out     []string
+}{
+	{"first", nil, "spotify:album:a", []string{"spotify:album:a"}},
+	{"unplayed", []string{"spotify:album:a"}, "spotify:playlist:b", []string{"spotify:album:a", "spotify:playlist:b"}},
+	{"removed from pool", []string{"spotify:album:x", "spotify:show:c"}, "spotify:album:a",
+		[]string{"spotify:show:c", "spotify:album:a"}},
+	{"exhausted", []string{"spotify:show:c", "spotify:playlist:b", "spotify:album:a"}, "spotify:playlist:b",
+		[]string{"spotify:playlist:b"}},
+}
+
+func TestSurprisePick(t *testing.T) {
+	defer func(f func(int) int) { surpriseIntn = f }(surpriseIntn)
+	surpriseIntn = func(n int) int { return 0 }
+
+	for _, tt := range surprisePickTests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, h := surprisePick(surprisePool, tt.history)
+			assert.Equal(t, tt.pick, u)
+			assert.Equal(t, tt.out, h)
+		})
+	}
+
+	u, h := surprisePick([]string{"spotify:album:a"}, []string{"spotify:album:a"})
+	assert.Equal(t, "spotify:album:a", u)
+	assert.Equal(t, []string{"spotify:album:a"}, h)
+
+	// A pool holding the same URI more than once, as recorded before pools were deduplicated, starts afresh with the
+	// URI played last rather than finding nothing to choose from.
+	u, h = surprisePick([]string{"spotify:album:a", "spotify:album:a"}, []string{"spotify:album:a"})
+	assert.Equal(t, "spotify:album:a", u)
+	assert.Equal(t, []string{"spotify:album:a"}, h)
+}
+
+func TestPlaySurprise(t *testing.T) {
+	_, cleanup := stateFile(t)
+	defer cleanup()
+	defer func(f func(int) int) { surpriseIntn = f }(surpriseIntn)
+	surpriseIntn = func(n int) int { return n - 1 }
+	viper.Set("spotify.device_name", "test_device_name")
+
+	var played []string
+	m := new(mocks.Client)
+	m.On("PlayerDevices").Return([]spotify.PlayerDevice{{ID: "TEST_ID", Name: "test_device_name"}}, nil)
+	m.On("PlayOpt", mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		played = append(played, string(*args.Get(0).(*spotify.PlayOptions).PlaybackContext))
+	})
+
+	c, err := SurpriseContents("", surprisePool)
+	assert.NoError(t, err)
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, PlaySurprise(m, c))
+	}
+	assert.Equal(t, []string{"spotify:show:c", "spotify:playlist:b", "spotify:album:a", "spotify:show:c"}, played)
+
+	s, err := ReadPlayerState()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"spotify:show:c"}, s.Surprise[c.URI])
+}
+
+func TestPlaySurprisePlaylist(t *testing.T) {
+	_, cleanup := stateFile(t)
+	defer cleanup()
+	defer func(f func(int) int) { surpriseIntn = f }(surpriseIntn)
+	surpriseIntn = func(n int) int { return 1 }
+	viper.Set("spotify.device_name", "test_device_name")
+
+	tp := &spotify.PlaylistTrackPage{Tracks: make([]spotify.PlaylistTrack, 4)}
+	tp.Total = 4
+	tp.Tracks[0].Track.Album.URI = "spotify:album:a"
+	tp.Tracks[1].Track.Album.URI = "spotify:album:a"
+	tp.Tracks[3].Track.Album.URI = "spotify:album:b"
+
+	m := new(mocks.Client)
+	m.On("GetPlaylistTracksOpt", spotify.ID("5XsXwH5uWdhpAWsigjWMTA"), mock.Anything, "").Return(tp, nil)
+	m.On("PlayerDevices").Return([]spotify.PlayerDevice{{ID: "TEST_ID", Name: "test_device_name"}}, nil)
+	m.On("PlayOpt", mock.MatchedBy(func(o *spotify.PlayOptions) bool {
+		return *o.PlaybackContext == "spotify:album:b"
+	})).Return(nil)
+
+	err := PlayContents(m, &Contents{Kind: CONTENTS_SURPRISE, URI: "spotify:playlist:5XsXwH5uWdhpAWsigjWMTA"})
+	assert.NoError(t, err)
+	m.AssertExpectations(t)
+}
+
+func TestPlaySurpriseErrors(t *testing.T) {
+	p, cleanup := stateFile(t)
+	defer cleanup()
+	viper.Set("spotify.device_name", "test_device_name")
+
+	m := new(mocks.Client)
+	m.On("GetPlaylistTracksOpt", spotify.ID("5XsXwH5uWdhpAWsigjWMTA"), mock.Anything, "").Return(
+		&spotify.PlaylistTrackPage{}, nil)
+	err := PlaySurprise(m, &Contents{Kind: CONTENTS_SURPRISE, URI: "spotify:playlist:5XsXwH5uWdhpAWsigjWMTA"})
+	assert.EqualError(t, err, "playlist spotify:playlist:5XsXwH5uWdhpAWsigjWMTA holds no albums to choose from")
+
+	// A player state which cannot be read is started afresh, and nothing is saved if playback fails.
+	err = ioutil.WriteFile(p, []byte("{florble"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m = new(mocks.Client)
+	m.On("PlayerDevices").Return(nil, errors.New("PlayerDevices error"))
+	err = PlaySurprise(m, &Contents{Kind: CONTENTS_SURPRISE, URI: "diskplayer:surprise:e89fd9970447",
+		Pool: surprisePool})
+	assert.EqualError(t, err, "PlayerDevices error")
+	b, err := ioutil.ReadFile(p)
+	assert.NoError(t, err)
+	assert.Equal(t, "{florble", string(b))
+
+	viper.Set("player.state_path", "/florble/player_state.json")
+	m = new(mocks.Client)
+	m.On("PlayerDevices").Return([]spotify.PlayerDevice{{ID: "TEST_ID", Name: "test_device_name"}}, nil)
+	m.On("PlayOpt", mock.Anything).Return(nil)
+	err = PlaySurprise(m, &Contents{Kind: CONTENTS_SURPRISE, URI: "diskplayer:surprise:e89fd9970447",
+		Pool: surprisePool})
+	assert.EqualError(t, err,
+		"unable to save player state: open /florble/player_state.json.tmp: no such file or directory")
+}
@@ -0,0 +1,154 @@
+package diskplayer
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"github.com/spf13/viper"
+	"io/ioutil"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+// selfSignedValidity is how long a generated self-signed certificate is valid for. A new one is generated once it
+// has expired.
+const selfSignedValidity = 5 * 365 * 24 * time.Hour
+
+// TLSConfig returns the TLS configuration defined in the diskplayer.yaml configuration file under the tls fields, or
+// nil if the tls.mode field is "off" or empty. If the mode is "files" the certificate and key are read from the files
+// given by the tls.cert_file and tls.key_file fields. If the mode is "self_signed" a self-signed certificate for the
+// host names and addresses given by the tls.hosts field is generated and persisted to those files the first time it is
+// needed, and read from them afterwards. Relative paths to the files are resolved as described for ConfigPath.
+// An error is returned if one is encountered.
+func TLSConfig() (*tls.Config, error) {
+	certFile := ConfigPath(TLS_CERT_FILE)
+	keyFile := ConfigPath(TLS_KEY_FILE)
+
+	var c tls.Certificate
+	var err error
+	switch m := viper.GetString(TLS_MODE); m {
+	case "", "off":
+		return nil, nil
+	case "files":
+		c, err = tls.LoadX509KeyPair(certFile, keyFile)
+	case "self_signed":
+		c, err = selfSignedCertificate(certFile, keyFile, tlsHosts())
+	default:
+		return nil, fmt.Errorf("unknown TLS mode: %s", m)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to load TLS certificate: %s", err)
+	}
+
+	return &tls.Config{Certificates: []tls.Certificate{c}, MinVersion: tls.VersionTLS12}, nil
+}
+
+// HTTPSRedirect returns a handler which redirects every request to the same location using HTTPS on the port.
+func HTTPSRedirect(port string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h, _, err := net.SplitHostPort(r.Host)
+		if err != nil {
+			h = r.Host
+		}
+		if port != "443" {
+			h = net.JoinHostPort(h, port)
+		}
+		http.Redirect(w, r, "https://"+h+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
+
+// tlsHosts returns the host names and addresses to include in a self-signed certificate, defined in the
+// diskplayer.yaml configuration file under the tls.hosts field. If none are given the host name of the machine and the
+// loopback addresses are used.
+func tlsHosts() []string {
+	hs := ConfigValues(TLS_HOSTS)
+	if len(hs) > 0 {
+		return hs
+	}
+
+	hs = []string{"localhost", "127.0.0.1", "::1"}
+	if n, err := os.Hostname(); err == nil {
+		hs = append([]string{n}, hs...)
+	}
+	return hs
+}
+
+// selfSignedCertificate returns the certificate persisted to the files, generating and persisting a new self-signed
+// certificate for the hosts if there is none or it has expired.
+// An error is returned if one is encountered.
+func selfSignedCertificate(certFile, keyFile string, hosts []string) (tls.Certificate, error) {
+	c, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err == nil {
+		x, err := x509.ParseCertificate(c.Certificate[0])
+		if err == nil && time.Now().Before(x.NotAfter) {
+			return c, nil
+		}
+		log.Printf("Self-signed certificate %s has expired, generating a new one", certFile)
+	} else if !os.IsNotExist(err) {
+		return c, err
+	}
+
+	err = createCertificate(certFile, keyFile, hosts)
+	if err != nil {
+		return c, err
+	}
+	log.Printf("Generated self-signed certificate %s for %v", certFile, hosts)
+	return tls.LoadX509KeyPair(certFile, keyFile)
+}
+
+// createCertificate generates a self-signed certificate for the hosts, writing the certificate and its private key to
+// the files in PEM format. The private key file may only be read by its owner.
+// An error is returned if one is encountered.
+func createCertificate(certFile, keyFile string, hosts []string) error {
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	sn, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	t := &x509.Certificate{
+		SerialNumber:          sn,
+		Subject:               pkix.Name{Organization: []string{"diskplayer"}, CommonName: hosts[0]},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(selfSignedValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			t.IPAddresses = append(t.IPAddresses, ip)
+		} else {
+			t.DNSNames = append(t.DNSNames, h)
+		}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, t, t, &k.PublicKey, k)
+	if err != nil {
+		return err
+	}
+	kb, err := x509.MarshalECPrivateKey(k)
+	if err != nil {
+		return err
+	}
+
+	err = ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: kb}), 0600)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644)
+}
@@ -0,0 +1,106 @@
+package diskplayer
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// tlsFiles sets the TLS configuration to use a certificate and key in a temporary folder, returning their paths.
+func tlsFiles(t *testing.T, mode string) (string, string, func()) {
+	d, err := ioutil.TempDir("", "diskplayer_tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c, k := filepath.Join(d, "diskplayer.crt"), filepath.Join(d, "diskplayer.key")
+	viper.Set("tls.mode", mode)
+	viper.Set("tls.cert_file", c)
+	viper.Set("tls.key_file", k)
+	viper.Set("tls.hosts", []string{"diskplayer.local", "192.168.1.10"})
+	return c, k, func() {
+		viper.Set("tls.mode", "off")
+		viper.Set("tls.hosts", []string{})
+		os.RemoveAll(d)
+	}
+}
+
+func TestTLSConfigOff(t *testing.T) {
+	viper.Set("tls.mode", "off")
+	c, err := TLSConfig()
+	assert.NoError(t, err)
+	assert.Nil(t, c)
+
+	viper.Set("tls.mode", "florble")
+	defer viper.Set("tls.mode", "off")
+	_, err = TLSConfig()
+	assert.EqualError(t, err, "unknown TLS mode: florble")
+}
+
+func TestTLSConfigSelfSigned(t *testing.T) {
+	cf, kf, cleanup := tlsFiles(t, "self_signed")
+	defer cleanup()
+
+	c, err := TLSConfig()
+	assert.NoError(t, err)
+	assert.Len(t, c.Certificates, 1)
+	x, err := x509.ParseCertificate(c.Certificates[0].Certificate[0])
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"diskplayer.local"}, x.DNSNames)
+	assert.Len(t, x.IPAddresses, 1)
+	assert.True(t, x.IPAddresses[0].Equal(net.ParseIP("192.168.1.10")))
+	assert.NoError(t, x.VerifyHostname("diskplayer.local"))
+
+	fi, err := os.Stat(kf)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), fi.Mode().Perm())
+	_, err = os.Stat(cf)
+	assert.NoError(t, err)
+
+	c2, err := TLSConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, c.Certificates[0].Certificate, c2.Certificates[0].Certificate)
+}
+
+func TestTLSConfigFiles(t *testing.T) {
+	cf, kf, cleanup := tlsFiles(t, "files")
+	defer cleanup()
+
+	_, err := TLSConfig()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "unable to load TLS certificate")
+
+	assert.NoError(t, createCertificate(cf, kf, []string{"localhost"}))
+	c, err := TLSConfig()
+	assert.NoError(t, err)
+	assert.Len(t, c.Certificates, 1)
+	assert.Equal(t, uint16(tls.VersionTLS12), c.MinVersion)
+}
+
+func TestHTTPSRedirect(t *testing.T) {
+	var tests = []struct {
+		port   string
+		host   string
+		target string
+		out    string
+	}{
+		{"3443", "raspberrypi:3000", "/catalogue?q=a", "https://raspberrypi:3443/catalogue?q=a"},
+		{"443", "raspberrypi", "/", "https://raspberrypi/"},
+		{"3443", "[::1]:3000", "/", "https://[::1]:3443/"},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", tt.target, nil)
+		r.Host = tt.host
+		rr := httptest.NewRecorder()
+		HTTPSRedirect(tt.port).ServeHTTP(rr, r)
+		assert.Equal(t, http.StatusMovedPermanently, rr.Code)
+		assert.Equal(t, tt.out, rr.Header().Get("Location"))
+	}
+}